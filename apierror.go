@@ -0,0 +1,262 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// APIError is the JSON body of every error response. Code is stable and
+// meant for programs; Message is for people and may change. Validation
+// errors list the offending fields, each with its own code.
+type APIError struct {
+	Status  int          `json:"-"`
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Fields  []FieldError `json:"fields,omitempty"`
+}
+
+// FieldError is a problem with one field of a request: a body field, a query
+// parameter or a path variable.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error codes
+const (
+	ErrCodeInvalidJSON          = "invalid_json"
+	ErrCodeValidation           = "validation_failed"
+	ErrCodeNotFound             = "not_found"
+	ErrCodeMethodNotAllowed     = "method_not_allowed"
+	ErrCodeEmailTaken           = "email_taken"
+	ErrCodeInUse                = "in_use"
+	ErrCodeScheduleArchived     = "schedule_archived"
+	ErrCodeStatusTransition     = "invalid_status_transition"
+	ErrCodeVersionConflict      = "version_conflict"
+	ErrCodeAlreadyRostered      = "already_participant"
+	ErrCodeTeamDeleted          = "team_deleted"
+	ErrCodeRequestInProgress    = "request_in_progress"
+	ErrCodeIdempotencyKeyReused = "idempotency_key_reused"
+	ErrCodePreconditionFailed   = "precondition_failed"
+	ErrCodePreconditionRequired = "precondition_required"
+	ErrCodeInternal             = "internal_error"
+)
+
+// Field error codes
+const (
+	FieldRequired      = "required"
+	FieldInvalidFormat = "invalid_format"
+	FieldInvalidType   = "invalid_type"
+	FieldInvalidChoice = "invalid_choice"
+	FieldOutOfRange    = "out_of_range"
+	FieldNotFound      = "not_found"
+	FieldDuplicate     = "duplicate"
+	FieldNotTeamMember = "not_team_member"
+	FieldInvalid       = "invalid"
+)
+
+func (e *APIError) Error() string {
+	if len(e.Fields) == 0 {
+		return e.Message
+	}
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Field + ": " + field.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+func notFoundError(message string) *APIError {
+	return &APIError{Status: http.StatusNotFound, Code: ErrCodeNotFound, Message: message}
+}
+
+func conflictError(code, message string) *APIError {
+	return &APIError{Status: http.StatusConflict, Code: code, Message: message}
+}
+
+func validationError(fields ...FieldError) *APIError {
+	return &APIError{Status: http.StatusBadRequest, Code: ErrCodeValidation, Message: "The request has invalid fields", Fields: fields}
+}
+
+// fieldError is a validation error for a single field.
+func fieldError(field, code, message string) *APIError {
+	return validationError(FieldError{Field: field, Code: code, Message: message})
+}
+
+// writeError answers with err as JSON. Errors other than *APIError are
+// logged and answered with a generic 500, so database errors never reach
+// clients.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		log.Printf("Error handling %s %s: %v", r.Method, r.URL.Path, err)
+		apiErr = &APIError{Status: http.StatusInternalServerError, Code: ErrCodeInternal, Message: "Internal server error"}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(apiErr.Status)
+	json.NewEncoder(w).Encode(apiErr)
+}
+
+// notFoundHandler and methodNotAllowedHandler answer requests the router
+// has no route for.
+func notFoundHandler(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, notFoundError("No such endpoint"))
+}
+
+func methodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, &APIError{Status: http.StatusMethodNotAllowed, Code: ErrCodeMethodNotAllowed,
+		Message: fmt.Sprintf("%s is not allowed here", r.Method)})
+}
+
+// decodeJSON decodes the request body into v. A body of the wrong shape is
+// an invalid_json error, naming the field when a value has the wrong type.
+func decodeJSON(r *http.Request, v interface{}) error {
+	return jsonError(json.NewDecoder(r.Body).Decode(v))
+}
+
+// jsonError turns an error from decoding a request body into an
+// invalid_json error.
+func jsonError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	invalid := &APIError{Status: http.StatusBadRequest, Code: ErrCodeInvalidJSON, Message: "The request body is not valid JSON"}
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, io.EOF):
+		invalid.Message = "The request body is empty"
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		invalid.Message = "The request body has a value of the wrong type"
+		invalid.Fields = []FieldError{{Field: typeErr.Field, Code: FieldInvalidType,
+			Message: fmt.Sprintf("Must be a %s, not a %s", jsonTypeName(typeErr.Type.Kind().String()), typeErr.Value)}}
+	}
+	return invalid
+}
+
+// jsonTypeName names a Go kind the way a JSON client would.
+func jsonTypeName(kind string) string {
+	switch {
+	case strings.HasPrefix(kind, "int"), strings.HasPrefix(kind, "uint"), strings.HasPrefix(kind, "float"):
+		return "number"
+	case kind == "bool":
+		return "boolean"
+	case kind == "slice":
+		return "list"
+	case kind == "map", kind == "struct":
+		return "object"
+	}
+	return kind
+}
+
+// pathID parses a numeric path variable.
+func pathID(r *http.Request, name string) (int, error) {
+	id, err := strconv.Atoi(mux.Vars(r)[name])
+	if err != nil || id <= 0 {
+		return 0, fieldError(name, FieldInvalidFormat, "Must be a positive whole number")
+	}
+	return id, nil
+}
+
+// queryID parses an optional numeric query parameter, returning 0 when it is
+// absent.
+func queryID(r *http.Request, name string) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	id, err := strconv.Atoi(v)
+	if err != nil || id <= 0 {
+		return 0, fieldError(name, FieldInvalidFormat, "Must be a positive whole number")
+	}
+	return id, nil
+}
+
+// validator collects the problems with a request's fields, so a client
+// hears about all of them at once.
+type validator struct {
+	fields []FieldError
+}
+
+func (v *validator) add(field, code, message string) {
+	v.fields = append(v.fields, FieldError{Field: field, Code: code, Message: message})
+}
+
+// check adds the error unless ok.
+func (v *validator) check(ok bool, field, code, message string) {
+	if !ok {
+		v.add(field, code, message)
+	}
+}
+
+// required checks that a string field is not blank.
+func (v *validator) required(field, value string) bool {
+	if strings.TrimSpace(value) == "" {
+		v.add(field, FieldRequired, "Is required")
+		return false
+	}
+	return true
+}
+
+// requiredID checks that an ID field is set.
+func (v *validator) requiredID(field string, id int) bool {
+	if id <= 0 {
+		v.add(field, FieldRequired, "Is required")
+		return false
+	}
+	return true
+}
+
+// timestamp parses a required YYYY-MM-DDTHH:MM field.
+func (v *validator) timestamp(field, value string) time.Time {
+	if !v.required(field, value) {
+		return time.Time{}
+	}
+	t, err := time.Parse("2006-01-02T15:04", value)
+	if err != nil {
+		v.add(field, FieldInvalidFormat, "Must be formatted as YYYY-MM-DDTHH:MM")
+	}
+	return t
+}
+
+// date parses a required YYYY-MM-DD field.
+func (v *validator) date(field, value string) time.Time {
+	if !v.required(field, value) {
+		return time.Time{}
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		v.add(field, FieldInvalidFormat, "Must be formatted as YYYY-MM-DD")
+	}
+	return t
+}
+
+// merge adds the fields of a validation error and returns any other error.
+func (v *validator) merge(err error) error {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.Code == ErrCodeValidation {
+		v.fields = append(v.fields, apiErr.Fields...)
+		return nil
+	}
+	return err
+}
+
+// err returns the collected problems as a validation error, or nil.
+func (v *validator) err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return validationError(v.fields...)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestErrorResponses(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		want   APIError
+	}{
+		{
+			name:   "every invalid field",
+			body:   `{"email": "not an address"}`,
+			status: http.StatusBadRequest,
+			want: APIError{Code: ErrCodeValidation, Message: "The request has invalid fields", Fields: []FieldError{
+				{Field: "email", Code: FieldInvalidFormat, Message: "Must be an email address"},
+				{Field: "slack_handle", Code: FieldRequired, Message: "Is required"},
+			}},
+		},
+		{
+			name:   "wrong type",
+			body:   `{"email": "a@example.com", "slack_handle": "a", "team_id": "ops"}`,
+			status: http.StatusBadRequest,
+			want: APIError{Code: ErrCodeInvalidJSON, Message: "The request body has a value of the wrong type", Fields: []FieldError{
+				{Field: "team_id", Code: FieldInvalidType, Message: "Must be a number, not a string"},
+			}},
+		},
+		{
+			name:   "malformed",
+			body:   `{"email": `,
+			status: http.StatusBadRequest,
+			want:   APIError{Code: ErrCodeInvalidJSON},
+		},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		createUserHandler(w, httptest.NewRequest("POST", "/api/v1/users", strings.NewReader(test.body)))
+
+		if w.Code != test.status || w.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("%s: status %d, Content-Type %q", test.name, w.Code, w.Header().Get("Content-Type"))
+		}
+		var got APIError
+		if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+			t.Fatalf("%s: decoding the body: %v", test.name, err)
+		}
+		if test.want.Message == "" {
+			test.want.Message = got.Message
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: body = %+v, want %+v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestWriteErrorHidesInternalErrors(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeError(w, httptest.NewRequest("GET", "/api/v1/users", nil), errors.New(`pq: relation "users" does not exist`))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", w.Code)
+	}
+	if body := w.Body.String(); strings.Contains(body, "pq:") || !strings.Contains(body, ErrCodeInternal) {
+		t.Errorf("body = %s", body)
+	}
+}
@@ -0,0 +1,219 @@
+// Package client is a typed Go client for the go-oncall API, as described by
+// the OpenAPI document the server publishes at /api/v1/openapi.json. Each
+// method is named after the operationId of its endpoint.
+//
+//	c := client.New("http://oncall.internal:8080")
+//	users, next, err := c.ListUsers(ctx, &client.ListUsersOptions{TeamID: 3})
+//
+// Errors from the API are returned as *Error, whose Code is stable. Wrap the
+// context with WithIdempotencyKey to make retrying a POST safe.
+//
+// Edits take the Revision of the user, team or schedule they are based on,
+// and fail with ErrCodePreconditionFailed if it has changed since:
+//
+//	user, err := c.GetUser(ctx, id)
+//	...
+//	user, err = c.UpdateUser(ctx, id, user.Revision, client.UserUpdate{Email: &email})
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiPrefix is the version of the API the client speaks.
+const apiPrefix = "/api/v1"
+
+// Client calls the API of the server at BaseURL.
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// New returns a client for the server at baseURL, such as
+// "http://localhost:8080".
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), HTTPClient: http.DefaultClient}
+}
+
+// Error is an error response from the API.
+type Error struct {
+	StatusCode int          `json:"-"`
+	Code       string       `json:"code"`
+	Message    string       `json:"message"`
+	Fields     []FieldError `json:"fields,omitempty"`
+}
+
+// FieldError is a problem with one field of a request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	message := fmt.Sprintf("go-oncall: %d %s: %s", e.StatusCode, e.Code, e.Message)
+	for _, field := range e.Fields {
+		message += fmt.Sprintf("; %s: %s", field.Field, field.Message)
+	}
+	return message
+}
+
+// Error codes
+const (
+	ErrCodeInvalidJSON          = "invalid_json"
+	ErrCodeValidation           = "validation_failed"
+	ErrCodeNotFound             = "not_found"
+	ErrCodeMethodNotAllowed     = "method_not_allowed"
+	ErrCodeEmailTaken           = "email_taken"
+	ErrCodeInUse                = "in_use"
+	ErrCodeScheduleArchived     = "schedule_archived"
+	ErrCodeStatusTransition     = "invalid_status_transition"
+	ErrCodeVersionConflict      = "version_conflict"
+	ErrCodeAlreadyRostered      = "already_participant"
+	ErrCodeTeamDeleted          = "team_deleted"
+	ErrCodeRequestInProgress    = "request_in_progress"
+	ErrCodeIdempotencyKeyReused = "idempotency_key_reused"
+	ErrCodePreconditionFailed   = "precondition_failed"
+	ErrCodePreconditionRequired = "precondition_required"
+	ErrCodeInternal             = "internal_error"
+)
+
+// TimeFormat is the format of the times in requests, such as start_time and
+// effective_from. They carry no offset and the server reads them as UTC.
+const TimeFormat = "2006-01-02T15:04"
+
+// FormatTime formats t in UTC for a request.
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(TimeFormat)
+}
+
+// ListOptions are the paging options of list methods. Cursor is the next
+// cursor returned with the previous page.
+type ListOptions struct {
+	Limit  int
+	Sort   string // a field name, prefixed with - for descending order
+	Cursor string
+}
+
+func (o ListOptions) query() url.Values {
+	query := url.Values{}
+	if o.Limit != 0 {
+		query.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Sort != "" {
+		query.Set("sort", o.Sort)
+	}
+	if o.Cursor != "" {
+		query.Set("cursor", o.Cursor)
+	}
+	return query
+}
+
+type idempotencyKeyContext struct{}
+
+// WithIdempotencyKey returns a context whose POST requests carry key as
+// their Idempotency-Key. Retrying a call with it returns the response of the
+// first attempt instead of, say, creating a second schedule.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyContext{}, key)
+}
+
+// do sends a request and decodes the JSON response into out. body is sent
+// as JSON unless it is an io.Reader.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) (http.Header, error) {
+	return c.send(ctx, method, path, query, nil, body, out)
+}
+
+// doIfMatch sends an edit of a resource at revision.
+func (c *Client) doIfMatch(ctx context.Context, method, path string, revision int, query url.Values, body, out interface{}) error {
+	header := http.Header{"If-Match": {strconv.Quote(strconv.Itoa(revision))}}
+	_, err := c.send(ctx, method, path, query, header, body, out)
+	return err
+}
+
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, header http.Header, body, out interface{}) (http.Header, error) {
+	endpoint := c.BaseURL + apiPrefix + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	contentType := ""
+	switch body := body.(type) {
+	case nil:
+	case io.Reader:
+		reader = body
+		contentType = "text/calendar"
+	default:
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	if key, ok := ctx.Value(idempotencyKeyContext{}).(string); ok && method == http.MethodPost {
+		req.Header.Set("Idempotency-Key", key)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		apiErr := &Error{StatusCode: resp.StatusCode}
+		data, _ := io.ReadAll(resp.Body)
+		if json.Unmarshal(data, apiErr) != nil || apiErr.Code == "" {
+			apiErr.Code = "http_" + strconv.Itoa(resp.StatusCode)
+			apiErr.Message = strings.TrimSpace(string(data))
+		}
+		return resp.Header, apiErr
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.Header, fmt.Errorf("go-oncall: decoding %s %s: %w", method, path, err)
+		}
+	}
+	return resp.Header, nil
+}
+
+func (c *Client) get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	_, err := c.do(ctx, http.MethodGet, path, query, nil, out)
+	return err
+}
+
+// list gets a page and returns the cursor of the next one, or "".
+func (c *Client) list(ctx context.Context, path string, query url.Values, out interface{}) (string, error) {
+	header, err := c.do(ctx, http.MethodGet, path, query, nil, out)
+	if err != nil {
+		return "", err
+	}
+	return header.Get("X-Next-Cursor"), nil
+}
@@ -0,0 +1,220 @@
+package client
+
+import "time"
+
+type User struct {
+	ID          int        `json:"id"`
+	Email       string     `json:"email"`
+	SlackHandle string     `json:"slack_handle"`
+	TeamIDs     []int      `json:"team_ids"`
+	Revision    int        `json:"revision"`
+	CreatedAt   time.Time  `json:"created_at"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+}
+
+type Team struct {
+	ID           int          `json:"id"`
+	Name         string       `json:"name"`
+	SlackChannel string       `json:"slack_channel"`
+	Members      []TeamMember `json:"members"`
+	Revision     int          `json:"revision"`
+	CreatedAt    time.Time    `json:"created_at"`
+	DeletedAt    *time.Time   `json:"deleted_at,omitempty"`
+}
+
+// TeamMember is a user's membership of a team.
+type TeamMember struct {
+	User
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// Team roles
+const (
+	TeamRoleMember  = "member"
+	TeamRoleManager = "manager"
+)
+
+type Schedule struct {
+	ID              int       `json:"id"`
+	TeamID          int       `json:"team_id"`
+	Name            string    `json:"name"`
+	StartTime       time.Time `json:"start_time"`
+	EndTime         time.Time `json:"end_time"`
+	Status          string    `json:"status"`
+	FallbackUserID  int       `json:"fallback_user_id,omitempty"`
+	StatusChangedAt time.Time `json:"status_changed_at"`
+
+	// The definition in effect
+	ScheduleDefinition
+	Version       int       `json:"version"`
+	EffectiveFrom time.Time `json:"effective_from"`
+
+	Revision  int        `json:"revision"`
+	CreatedAt time.Time  `json:"created_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Schedule states
+const (
+	ScheduleStatusActive   = "active"
+	ScheduleStatusPaused   = "paused"
+	ScheduleStatusArchived = "archived"
+)
+
+// ScheduleDefinition is the part of a schedule that is versioned. In
+// requests, give either Roster or Participants.
+type ScheduleDefinition struct {
+	RotationPeriod     int                   `json:"rotation_period"` // in seconds
+	Roster             []ScheduleParticipant `json:"roster,omitempty"`
+	Participants       []int                 `json:"participants,omitempty"`
+	RotationStrategy   string                `json:"rotation_strategy,omitempty"`
+	ParticipantWeights map[int]float64       `json:"participant_weights,omitempty"`
+	Sequence           []int                 `json:"sequence,omitempty"`
+	HolidayMode        string                `json:"holiday_mode,omitempty"`
+	HolidayScheduleID  int                   `json:"holiday_schedule_id,omitempty"`
+	Timezone           string                `json:"timezone,omitempty"`
+}
+
+// Rotation strategies
+const (
+	RotationStrategyRoundRobin    = "round_robin"
+	RotationStrategyFairness      = "fairness"
+	RotationStrategyWeighted      = "weighted"
+	RotationStrategyRandom        = "random"
+	RotationStrategyFixedSequence = "fixed_sequence"
+)
+
+// Holiday modes
+const (
+	HolidayModeNone     = "none"
+	HolidayModeSkip     = "skip"
+	HolidayModeRotation = "rotation"
+	HolidayModeFlag     = "flag"
+)
+
+// ScheduleParticipant is an entry in a schedule's roster. Only active tier
+// 1 participants take rotation shifts, so set Active for them in requests.
+type ScheduleParticipant struct {
+	UserID   int     `json:"user_id"`
+	Position int     `json:"position,omitempty"`
+	Weight   float64 `json:"weight,omitempty"`
+	Tier     int     `json:"tier,omitempty"`
+	Active   bool    `json:"active"`
+}
+
+type ScheduleVersion struct {
+	ID            int       `json:"id"`
+	ScheduleID    int       `json:"schedule_id"`
+	Version       int       `json:"version"`
+	EffectiveFrom time.Time `json:"effective_from"`
+	ScheduleDefinition
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ShadowShift struct {
+	ID         int       `json:"id"`
+	ScheduleID int       `json:"schedule_id"`
+	UserID     int       `json:"user_id"`
+	StartTime  time.Time `json:"start_time"`
+	EndTime    time.Time `json:"end_time"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type Unavailability struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Holiday struct {
+	ID        int       `json:"id"`
+	TeamID    int       `json:"team_id"`
+	Date      time.Time `json:"date"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ProjectedShift is an upcoming shift. UserID is 0 when nobody will be on
+// call.
+type ProjectedShift struct {
+	ScheduleID  int       `json:"schedule_id"`
+	UserID      int       `json:"user_id"`
+	StartTime   time.Time `json:"start_time"`
+	EndTime     time.Time `json:"end_time"`
+	Holiday     bool      `json:"holiday"`
+	HolidayName string    `json:"holiday_name,omitempty"`
+	Skipped     bool      `json:"skipped"`
+	Paused      bool      `json:"paused"`
+}
+
+type CoverageGap struct {
+	ScheduleID   int       `json:"schedule_id"`
+	ScheduleName string    `json:"schedule_name"`
+	TeamID       int       `json:"team_id"`
+	StartTime    time.Time `json:"start_time"`
+	EndTime      time.Time `json:"end_time"`
+	Reason       string    `json:"reason"`
+}
+
+type OnCallHoursReport struct {
+	UserID       int     `json:"user_id"`
+	Email        string  `json:"email"`
+	PrimaryHours float64 `json:"primary_hours"`
+	ShadowHours  float64 `json:"shadow_hours"`
+	HolidayHours float64 `json:"holiday_hours"`
+}
+
+type LeaderStatus struct {
+	InstanceID string       `json:"instance_id"`
+	IsLeader   bool         `json:"is_leader"`
+	Lease      *LeaderLease `json:"lease"`
+}
+
+type LeaderLease struct {
+	Name         string    `json:"name"`
+	Holder       string    `json:"holder"`
+	FencingToken int64     `json:"fencing_token"`
+	AcquiredAt   time.Time `json:"acquired_at"`
+	RenewedAt    time.Time `json:"renewed_at"`
+}
+
+// Result is the response to most writes: the ID of the resource written.
+type Result struct {
+	ID      int    `json:"id"`
+	Message string `json:"message"`
+}
+
+// TeamMemberResult is the response to membership changes. ID is the team's.
+type TeamMemberResult struct {
+	ID      int    `json:"id"`
+	UserID  int    `json:"user_id"`
+	Role    string `json:"role,omitempty"`
+	Message string `json:"message"`
+}
+
+// ScheduleEditResult is the response to schedule edits, with the schedule's
+// new Revision. Version and EffectiveFrom are set when the edit made a new
+// version.
+type ScheduleEditResult struct {
+	ID            int        `json:"id"`
+	Revision      int        `json:"revision"`
+	Version       int        `json:"version,omitempty"`
+	EffectiveFrom *time.Time `json:"effective_from,omitempty"`
+	Message       string     `json:"message"`
+}
+
+type ScheduleStatusResult struct {
+	ID       int    `json:"id"`
+	Revision int    `json:"revision"`
+	Status   string `json:"status"`
+	Message  string `json:"message"`
+}
+
+type ImportResult struct {
+	Imported int    `json:"imported"`
+	Message  string `json:"message"`
+}
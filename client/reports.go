@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+type UnavailabilityRequest struct {
+	UserID    int    `json:"user_id"`
+	StartTime string `json:"start_time"` // formatted with FormatTime
+	EndTime   string `json:"end_time"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+func (c *Client) CreateUnavailability(ctx context.Context, req UnavailabilityRequest) (*Result, error) {
+	var result Result
+	_, err := c.do(ctx, http.MethodPost, "/unavailability", nil, req, &result)
+	return &result, err
+}
+
+func (c *Client) ListUnavailability(ctx context.Context) ([]Unavailability, error) {
+	var entries []Unavailability
+	err := c.get(ctx, "/unavailability", nil, &entries)
+	return entries, err
+}
+
+func (c *Client) DeleteUnavailability(ctx context.Context, id int) error {
+	_, err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/unavailability/%d", id), nil, nil, nil)
+	return err
+}
+
+// GetOnCallHoursReport reports on-call hours per user between from and to,
+// as YYYY-MM-DD dates; empty dates default to the last 30 days.
+func (c *Client) GetOnCallHoursReport(ctx context.Context, from, to string) ([]OnCallHoursReport, error) {
+	query := url.Values{}
+	if from != "" {
+		query.Set("from", from)
+	}
+	if to != "" {
+		query.Set("to", to)
+	}
+	var report []OnCallHoursReport
+	err := c.get(ctx, "/reports/oncall-hours", query, &report)
+	return report, err
+}
+
+// ListCoverageGaps lists the windows over the next days in which a schedule
+// has nobody on call; days 0 uses the server's default.
+func (c *Client) ListCoverageGaps(ctx context.Context, days int) ([]CoverageGap, error) {
+	query := url.Values{}
+	if days != 0 {
+		query.Set("days", strconv.Itoa(days))
+	}
+	var gaps []CoverageGap
+	err := c.get(ctx, "/coverage/gaps", query, &gaps)
+	return gaps, err
+}
+
+func (c *Client) GetLeaderStatus(ctx context.Context) (*LeaderStatus, error) {
+	var status LeaderStatus
+	err := c.get(ctx, "/leader", nil, &status)
+	return &status, err
+}
+
+// GetOpenAPISpec returns the server's OpenAPI document.
+func (c *Client) GetOpenAPISpec(ctx context.Context) (json.RawMessage, error) {
+	var spec json.RawMessage
+	err := c.get(ctx, "/openapi.json", nil, &spec)
+	return spec, err
+}
@@ -0,0 +1,241 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Times in requests are formatted with FormatTime. Edits take the Revision
+// of the schedule they are based on.
+
+type CreateScheduleRequest struct {
+	TeamID    int    `json:"team_id"`
+	Name      string `json:"name"`
+	StartTime string `json:"start_time"`
+	EndTime   string `json:"end_time"`
+	ScheduleDefinition
+}
+
+// ScheduleUpdateRequest replaces a schedule's definition from EffectiveFrom,
+// or from the next handoff if it is empty.
+type ScheduleUpdateRequest struct {
+	EffectiveFrom string `json:"effective_from,omitempty"`
+	ScheduleDefinition
+}
+
+// SchedulePatch changes the fields that are set. Name and EndTime apply
+// straight away; the others are applied to the latest version and saved as
+// a new one from EffectiveFrom.
+type SchedulePatch struct {
+	Name          *string `json:"name,omitempty"`
+	EndTime       *string `json:"end_time,omitempty"`
+	EffectiveFrom string  `json:"effective_from,omitempty"`
+
+	RotationPeriod     *int                  `json:"rotation_period,omitempty"`
+	Roster             []ScheduleParticipant `json:"roster,omitempty"`
+	Participants       []int                 `json:"participants,omitempty"`
+	RotationStrategy   *string               `json:"rotation_strategy,omitempty"`
+	ParticipantWeights map[int]float64       `json:"participant_weights,omitempty"`
+	Sequence           []int                 `json:"sequence,omitempty"`
+	HolidayMode        *string               `json:"holiday_mode,omitempty"`
+	HolidayScheduleID  *int                  `json:"holiday_schedule_id,omitempty"`
+	Timezone           *string               `json:"timezone,omitempty"`
+}
+
+type ListSchedulesOptions struct {
+	ListOptions
+	TeamID          int
+	Name            string // contains, ignoring case
+	Active          *bool  // true for active schedules, false for paused ones
+	IncludeArchived bool
+	IncludeDeleted  bool
+}
+
+type AddParticipantRequest struct {
+	UserID        int     `json:"user_id"`
+	Position      int     `json:"position,omitempty"` // 0 for last
+	Weight        float64 `json:"weight,omitempty"`
+	Tier          int     `json:"tier,omitempty"`
+	Active        *bool   `json:"active,omitempty"` // defaults to true
+	EffectiveFrom string  `json:"effective_from,omitempty"`
+}
+
+// ParticipantUpdate changes the fields that are set.
+type ParticipantUpdate struct {
+	Position      *int     `json:"position,omitempty"`
+	Weight        *float64 `json:"weight,omitempty"`
+	Tier          *int     `json:"tier,omitempty"`
+	Active        *bool    `json:"active,omitempty"`
+	EffectiveFrom string   `json:"effective_from,omitempty"`
+}
+
+type ShadowShiftRequest struct {
+	UserID    int    `json:"user_id"`
+	StartTime string `json:"start_time"`
+	EndTime   string `json:"end_time"`
+}
+
+func (c *Client) CreateSchedule(ctx context.Context, req CreateScheduleRequest) (*Result, error) {
+	var result Result
+	_, err := c.do(ctx, http.MethodPost, "/schedules", nil, req, &result)
+	return &result, err
+}
+
+// ListSchedules returns a page of schedules and the cursor of the next
+// page, or "" on the last page.
+func (c *Client) ListSchedules(ctx context.Context, opts *ListSchedulesOptions) ([]Schedule, string, error) {
+	query := url.Values{}
+	if opts != nil {
+		query = opts.query()
+		if opts.TeamID != 0 {
+			query.Set("team_id", strconv.Itoa(opts.TeamID))
+		}
+		if opts.Name != "" {
+			query.Set("name", opts.Name)
+		}
+		if opts.Active != nil {
+			query.Set("active", strconv.FormatBool(*opts.Active))
+		}
+		if opts.IncludeArchived {
+			query.Set("include_archived", "true")
+		}
+		if opts.IncludeDeleted {
+			query.Set("include_deleted", "true")
+		}
+	}
+	var schedules []Schedule
+	next, err := c.list(ctx, "/schedules", query, &schedules)
+	return schedules, next, err
+}
+
+func (c *Client) GetSchedule(ctx context.Context, id int) (*Schedule, error) {
+	var schedule Schedule
+	err := c.get(ctx, fmt.Sprintf("/schedules/%d", id), nil, &schedule)
+	return &schedule, err
+}
+
+func (c *Client) ReplaceSchedule(ctx context.Context, id, revision int, req ScheduleUpdateRequest) (*ScheduleEditResult, error) {
+	var result ScheduleEditResult
+	err := c.doIfMatch(ctx, http.MethodPut, fmt.Sprintf("/schedules/%d", id), revision, nil, req, &result)
+	return &result, err
+}
+
+func (c *Client) UpdateSchedule(ctx context.Context, id, revision int, patch SchedulePatch) (*ScheduleEditResult, error) {
+	var result ScheduleEditResult
+	err := c.doIfMatch(ctx, http.MethodPatch, fmt.Sprintf("/schedules/%d", id), revision, nil, patch, &result)
+	return &result, err
+}
+
+// DeleteSchedule deletes a schedule, keeping its on-call history. It can be
+// restored until it is purged, which only happens to schedules that never
+// had anyone on call.
+func (c *Client) DeleteSchedule(ctx context.Context, id int) error {
+	_, err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/schedules/%d", id), nil, nil, nil)
+	return err
+}
+
+// RestoreSchedule restores a deleted schedule. It fails with
+// ErrCodeTeamDeleted while the schedule's team is deleted.
+func (c *Client) RestoreSchedule(ctx context.Context, id int) (*Schedule, error) {
+	var schedule Schedule
+	_, err := c.do(ctx, http.MethodPost, fmt.Sprintf("/schedules/%d/restore", id), nil, nil, &schedule)
+	return &schedule, err
+}
+
+func (c *Client) ListScheduleVersions(ctx context.Context, id int) ([]ScheduleVersion, error) {
+	var versions []ScheduleVersion
+	err := c.get(ctx, fmt.Sprintf("/schedules/%d/versions", id), nil, &versions)
+	return versions, err
+}
+
+// GetScheduleVersionAt returns the version in effect at, formatted with
+// FormatTime.
+func (c *Client) GetScheduleVersionAt(ctx context.Context, id int, at string) (*ScheduleVersion, error) {
+	var version ScheduleVersion
+	err := c.get(ctx, fmt.Sprintf("/schedules/%d/versions", id), url.Values{"at": {at}}, &version)
+	return &version, err
+}
+
+// PauseSchedule pauses a schedule. fallbackUserID, if not 0, covers the
+// shifts that start while it is paused.
+func (c *Client) PauseSchedule(ctx context.Context, id, revision, fallbackUserID int) (*ScheduleStatusResult, error) {
+	req := struct {
+		FallbackUserID int `json:"fallback_user_id,omitempty"`
+	}{fallbackUserID}
+	var result ScheduleStatusResult
+	err := c.doIfMatch(ctx, http.MethodPost, fmt.Sprintf("/schedules/%d/pause", id), revision, nil, req, &result)
+	return &result, err
+}
+
+func (c *Client) ResumeSchedule(ctx context.Context, id, revision int) (*ScheduleStatusResult, error) {
+	var result ScheduleStatusResult
+	err := c.doIfMatch(ctx, http.MethodPost, fmt.Sprintf("/schedules/%d/resume", id), revision, nil, nil, &result)
+	return &result, err
+}
+
+func (c *Client) ArchiveSchedule(ctx context.Context, id, revision int) (*ScheduleStatusResult, error) {
+	var result ScheduleStatusResult
+	err := c.doIfMatch(ctx, http.MethodPost, fmt.Sprintf("/schedules/%d/archive", id), revision, nil, nil, &result)
+	return &result, err
+}
+
+func (c *Client) AddScheduleParticipant(ctx context.Context, id, revision int, req AddParticipantRequest) (*ScheduleEditResult, error) {
+	var result ScheduleEditResult
+	err := c.doIfMatch(ctx, http.MethodPost, fmt.Sprintf("/schedules/%d/participants", id), revision, nil, req, &result)
+	return &result, err
+}
+
+// ReorderScheduleParticipants sets the rotation order; userIDs must list
+// every participant once.
+func (c *Client) ReorderScheduleParticipants(ctx context.Context, id, revision int, userIDs []int, effectiveFrom string) (*ScheduleEditResult, error) {
+	req := struct {
+		UserIDs       []int  `json:"user_ids"`
+		EffectiveFrom string `json:"effective_from,omitempty"`
+	}{userIDs, effectiveFrom}
+	var result ScheduleEditResult
+	err := c.doIfMatch(ctx, http.MethodPut, fmt.Sprintf("/schedules/%d/participants/order", id), revision, nil, req, &result)
+	return &result, err
+}
+
+func (c *Client) UpdateScheduleParticipant(ctx context.Context, id, revision, userID int, update ParticipantUpdate) (*ScheduleEditResult, error) {
+	var result ScheduleEditResult
+	err := c.doIfMatch(ctx, http.MethodPut, fmt.Sprintf("/schedules/%d/participants/%d", id, userID), revision, nil, update, &result)
+	return &result, err
+}
+
+func (c *Client) RemoveScheduleParticipant(ctx context.Context, id, revision, userID int, effectiveFrom string) (*ScheduleEditResult, error) {
+	query := url.Values{}
+	if effectiveFrom != "" {
+		query.Set("effective_from", effectiveFrom)
+	}
+	var result ScheduleEditResult
+	err := c.doIfMatch(ctx, http.MethodDelete, fmt.Sprintf("/schedules/%d/participants/%d", id, userID), revision, query, nil, &result)
+	return &result, err
+}
+
+// GetScheduleProjection returns the shifts expected over the next days, or
+// 14 days if days is 0.
+func (c *Client) GetScheduleProjection(ctx context.Context, id, days int) ([]ProjectedShift, error) {
+	query := url.Values{}
+	if days != 0 {
+		query.Set("days", strconv.Itoa(days))
+	}
+	var shifts []ProjectedShift
+	err := c.get(ctx, fmt.Sprintf("/schedules/%d/projection", id), query, &shifts)
+	return shifts, err
+}
+
+func (c *Client) CreateShadowShift(ctx context.Context, id int, req ShadowShiftRequest) (*Result, error) {
+	var result Result
+	_, err := c.do(ctx, http.MethodPost, fmt.Sprintf("/schedules/%d/shadows", id), nil, req, &result)
+	return &result, err
+}
+
+func (c *Client) ListShadowShifts(ctx context.Context, id int) ([]ShadowShift, error) {
+	var shifts []ShadowShift
+	err := c.get(ctx, fmt.Sprintf("/schedules/%d/shadows", id), nil, &shifts)
+	return shifts, err
+}
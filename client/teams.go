@@ -0,0 +1,128 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+type CreateTeamRequest struct {
+	Name         string `json:"name"`
+	SlackChannel string `json:"slack_channel,omitempty"`
+}
+
+// TeamUpdate changes a team. ReplaceTeam needs Name; UpdateTeam changes the
+// fields that are set. Both take the team's Revision.
+type TeamUpdate struct {
+	Name         *string `json:"name,omitempty"`
+	SlackChannel *string `json:"slack_channel,omitempty"`
+}
+
+type ListTeamsOptions struct {
+	ListOptions
+	Name           string // contains, ignoring case
+	IncludeDeleted bool
+}
+
+type HolidayRequest struct {
+	Date string `json:"date"` // YYYY-MM-DD
+	Name string `json:"name"`
+}
+
+func (c *Client) CreateTeam(ctx context.Context, req CreateTeamRequest) (*Result, error) {
+	var result Result
+	_, err := c.do(ctx, http.MethodPost, "/teams", nil, req, &result)
+	return &result, err
+}
+
+// ListTeams returns a page of teams with their members, and the cursor of
+// the next page, or "" on the last page.
+func (c *Client) ListTeams(ctx context.Context, opts *ListTeamsOptions) ([]Team, string, error) {
+	query := url.Values{}
+	if opts != nil {
+		query = opts.query()
+		if opts.Name != "" {
+			query.Set("name", opts.Name)
+		}
+		if opts.IncludeDeleted {
+			query.Set("include_deleted", "true")
+		}
+	}
+	var teams []Team
+	next, err := c.list(ctx, "/teams", query, &teams)
+	return teams, next, err
+}
+
+func (c *Client) GetTeam(ctx context.Context, id int) (*Team, error) {
+	var team Team
+	err := c.get(ctx, fmt.Sprintf("/teams/%d", id), nil, &team)
+	return &team, err
+}
+
+func (c *Client) ReplaceTeam(ctx context.Context, id, revision int, update TeamUpdate) (*Team, error) {
+	var team Team
+	err := c.doIfMatch(ctx, http.MethodPut, fmt.Sprintf("/teams/%d", id), revision, nil, update, &team)
+	return &team, err
+}
+
+func (c *Client) UpdateTeam(ctx context.Context, id, revision int, update TeamUpdate) (*Team, error) {
+	var team Team
+	err := c.doIfMatch(ctx, http.MethodPatch, fmt.Sprintf("/teams/%d", id), revision, nil, update, &team)
+	return &team, err
+}
+
+// DeleteTeam deletes a team that has no schedules. It can be restored until
+// it is purged.
+func (c *Client) DeleteTeam(ctx context.Context, id int) error {
+	_, err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/teams/%d", id), nil, nil, nil)
+	return err
+}
+
+func (c *Client) RestoreTeam(ctx context.Context, id int) (*Team, error) {
+	var team Team
+	_, err := c.do(ctx, http.MethodPost, fmt.Sprintf("/teams/%d/restore", id), nil, nil, &team)
+	return &team, err
+}
+
+// AddTeamMember adds a user to a team with role, TeamRoleMember or
+// TeamRoleManager, or changes the role of a member.
+func (c *Client) AddTeamMember(ctx context.Context, teamID, userID int, role string) (*TeamMemberResult, error) {
+	req := struct {
+		UserID int    `json:"user_id"`
+		Role   string `json:"role,omitempty"`
+	}{userID, role}
+	var result TeamMemberResult
+	_, err := c.do(ctx, http.MethodPost, fmt.Sprintf("/teams/%d/members", teamID), nil, req, &result)
+	return &result, err
+}
+
+func (c *Client) RemoveTeamMember(ctx context.Context, teamID, userID int) error {
+	_, err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/teams/%d/members/%d", teamID, userID), nil, nil, nil)
+	return err
+}
+
+func (c *Client) CreateHoliday(ctx context.Context, teamID int, req HolidayRequest) (*Result, error) {
+	var result Result
+	_, err := c.do(ctx, http.MethodPost, fmt.Sprintf("/teams/%d/holidays", teamID), nil, req, &result)
+	return &result, err
+}
+
+func (c *Client) ListHolidays(ctx context.Context, teamID int) ([]Holiday, error) {
+	var holidays []Holiday
+	err := c.get(ctx, fmt.Sprintf("/teams/%d/holidays", teamID), nil, &holidays)
+	return holidays, err
+}
+
+// ImportHolidays imports the all-day events of an iCalendar (.ics) file.
+func (c *Client) ImportHolidays(ctx context.Context, teamID int, ics io.Reader) (*ImportResult, error) {
+	var result ImportResult
+	_, err := c.do(ctx, http.MethodPost, fmt.Sprintf("/teams/%d/holidays/import", teamID), nil, ics, &result)
+	return &result, err
+}
+
+func (c *Client) DeleteHoliday(ctx context.Context, teamID, holidayID int) error {
+	_, err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/teams/%d/holidays/%d", teamID, holidayID), nil, nil, nil)
+	return err
+}
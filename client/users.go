@@ -0,0 +1,87 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+type CreateUserRequest struct {
+	Email       string `json:"email"`
+	SlackHandle string `json:"slack_handle"`
+	TeamID      int    `json:"team_id,omitempty"` // made a member of this team
+}
+
+// UserUpdate changes a user. ReplaceUser needs both fields; UpdateUser
+// changes those that are set. Both take the user's Revision.
+type UserUpdate struct {
+	Email       *string `json:"email,omitempty"`
+	SlackHandle *string `json:"slack_handle,omitempty"`
+}
+
+type ListUsersOptions struct {
+	ListOptions
+	TeamID         int
+	Email          string // contains, ignoring case
+	IncludeDeleted bool
+}
+
+func (c *Client) CreateUser(ctx context.Context, req CreateUserRequest) (*Result, error) {
+	var result Result
+	_, err := c.do(ctx, http.MethodPost, "/users", nil, req, &result)
+	return &result, err
+}
+
+// ListUsers returns a page of users and the cursor of the next page, or ""
+// on the last page.
+func (c *Client) ListUsers(ctx context.Context, opts *ListUsersOptions) ([]User, string, error) {
+	query := url.Values{}
+	if opts != nil {
+		query = opts.query()
+		if opts.TeamID != 0 {
+			query.Set("team_id", strconv.Itoa(opts.TeamID))
+		}
+		if opts.Email != "" {
+			query.Set("email", opts.Email)
+		}
+		if opts.IncludeDeleted {
+			query.Set("include_deleted", "true")
+		}
+	}
+	var users []User
+	next, err := c.list(ctx, "/users", query, &users)
+	return users, next, err
+}
+
+func (c *Client) GetUser(ctx context.Context, id int) (*User, error) {
+	var user User
+	err := c.get(ctx, fmt.Sprintf("/users/%d", id), nil, &user)
+	return &user, err
+}
+
+func (c *Client) ReplaceUser(ctx context.Context, id, revision int, update UserUpdate) (*User, error) {
+	var user User
+	err := c.doIfMatch(ctx, http.MethodPut, fmt.Sprintf("/users/%d", id), revision, nil, update, &user)
+	return &user, err
+}
+
+func (c *Client) UpdateUser(ctx context.Context, id, revision int, update UserUpdate) (*User, error) {
+	var user User
+	err := c.doIfMatch(ctx, http.MethodPatch, fmt.Sprintf("/users/%d", id), revision, nil, update, &user)
+	return &user, err
+}
+
+// DeleteUser deletes a user who is on no schedule's roster. They can be
+// restored until they are purged.
+func (c *Client) DeleteUser(ctx context.Context, id int) error {
+	_, err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/users/%d", id), nil, nil, nil)
+	return err
+}
+
+func (c *Client) RestoreUser(ctx context.Context, id int) (*User, error) {
+	var user User
+	_, err := c.do(ctx, http.MethodPost, fmt.Sprintf("/users/%d/restore", id), nil, nil, &user)
+	return &user, err
+}
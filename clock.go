@@ -0,0 +1,32 @@
+package main
+
+import "time"
+
+// Clock tells the scheduler what time it is. Production uses the wall
+// clock; simulations swap in a virtualClock so rotations can be replayed
+// over any range without waiting.
+type Clock interface {
+	Now() time.Time
+}
+
+// clock is the Clock used by the scheduler, projections and coverage checks.
+var clock Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// virtualClock only moves when set.
+type virtualClock struct {
+	now time.Time
+}
+
+func (c *virtualClock) Now() time.Time {
+	return c.now
+}
+
+func (c *virtualClock) Set(t time.Time) {
+	c.now = t
+}
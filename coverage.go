@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+)
+
+const defaultCoverageLookaheadDays = 7
+
+// coverageChecker alerts team channels about coverage gaps every hour until
+// ctx is done.
+func coverageChecker(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	
+	days := coverageLookaheadDays()
+	log.Printf("Coverage checker started (checking the next %d days every hour)", days)
+	
+	for {
+		// Only the leader alerts, so replicas don't post duplicates
+		if fencingToken, leader := elector.fencingToken(); leader {
+			alertCoverageGaps(ctx, days, fencingToken)
+		}
+		
+		select {
+		case <-ctx.Done():
+			log.Println("Coverage checker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// alertCoverageGaps reports the gaps in the next days that were not already
+// alerted about. The alerts sent are recorded (see
+// Store.ClaimCoverageGapAlert), so a gap is announced once however many
+// checks, restarts and leaders it outlasts. Shifts that PTO leaves without
+// anyone are left to the scheduler, which warns about them a rotation ahead
+// (see alertUncoveredShift). An instance that has lost leadership with
+// fencingToken stops alerting.
+func alertCoverageGaps(ctx context.Context, days int, fencingToken int64) {
+	now := clock.Now()
+	gaps, err := findCoverageGaps(ctx, now, now.AddDate(0, 0, days))
+	if err != nil {
+		log.Printf("Error finding coverage gaps: %v", err)
+	}
+	for _, gap := range gaps {
+		if gap.Reason == GapReasonUnfilledPTO {
+			continue
+		}
+		claimed, err := store.ClaimCoverageGapAlert(ctx, gap, fencingToken)
+		if err == errFencedOut {
+			log.Printf("Stopping coverage check: %v", err)
+			return
+		}
+		if err != nil {
+			log.Printf("Error recording the coverage gap alert for schedule %s: %v", gap.ScheduleName, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		log.Printf("Coverage gap on schedule %s from %v to %v: %s", gap.ScheduleName, gap.StartTime, gap.EndTime, gap.Reason)
+		notifier.CoverageGap(ctx, gap)
+	}
+}
+
+// coverageLookaheadDays reads COVERAGE_LOOKAHEAD_DAYS, defaulting to a week.
+func coverageLookaheadDays() int {
+	if v := os.Getenv("COVERAGE_LOOKAHEAD_DAYS"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err == nil && days > 0 {
+			return days
+		}
+		log.Printf("Invalid COVERAGE_LOOKAHEAD_DAYS %q, using %d", v, defaultCoverageLookaheadDays)
+	}
+	return defaultCoverageLookaheadDays
+}
+
+// findCoverageGaps scans every schedule for windows between from and to
+// with nobody on call.
+func findCoverageGaps(ctx context.Context, from, to time.Time) ([]CoverageGap, error) {
+	schedules, err := store.GetSchedules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	
+	gaps := []CoverageGap{}
+	for _, schedule := range schedules {
+		if schedule.Status == ScheduleStatusArchived {
+			continue
+		}
+		scheduleGaps, err := scheduleCoverageGaps(ctx, schedule, from, to)
+		if err != nil {
+			log.Printf("Error analyzing coverage for schedule %s: %v", schedule.Name, err)
+			continue
+		}
+		gaps = append(gaps, scheduleGaps...)
+	}
+	return gaps, nil
+}
+
+// scheduleCoverageGaps finds the uncovered windows of one schedule. Shifts
+// deliberately skipped for a holiday are not reported.
+func scheduleCoverageGaps(ctx context.Context, schedule Schedule, from, to time.Time) ([]CoverageGap, error) {
+	// Schedules that ended before the window or start after it are not gaps
+	if !schedule.EndTime.After(from) || !schedule.StartTime.Before(to) {
+		return nil, nil
+	}
+	
+	var gaps []CoverageGap
+	addGap := func(start, end time.Time, reason string) {
+		var firstStart time.Time
+		if start.Before(from) {
+			start, firstStart = from, start
+		}
+		if end.After(to) {
+			end = to
+		}
+		if !start.Before(end) {
+			return
+		}
+		// Merge back-to-back windows with the same cause
+		if n := len(gaps); n > 0 && gaps[n-1].Reason == reason && gaps[n-1].EndTime.Equal(start) {
+			gaps[n-1].EndTime = end
+			return
+		}
+		gaps = append(gaps, CoverageGap{
+			ScheduleID:   schedule.ID,
+			ScheduleName: schedule.Name,
+			TeamID:       schedule.TeamID,
+			StartTime:    start,
+			EndTime:      end,
+			Reason:       reason,
+			firstStart:   firstStart,
+		})
+	}
+	
+	windowStart := from
+	if schedule.StartTime.After(windowStart) {
+		windowStart = schedule.StartTime
+	}
+	// Gaps for the whole definition begin when it took effect
+	definitionStart := schedule.StartTime
+	if schedule.EffectiveFrom.After(definitionStart) {
+		definitionStart = schedule.EffectiveFrom
+	}
+	windowEnd := to
+	if schedule.EndTime.Before(windowEnd) {
+		windowEnd = schedule.EndTime
+	}
+	
+	if len(schedule.Participants) == 0 {
+		addGap(definitionStart, windowEnd, GapReasonNoParticipants)
+	} else if removed, err := participantsNotInTeam(ctx, schedule); err != nil {
+		// The scheduler skips schedules with missing participants entirely
+		addGap(definitionStart, windowEnd, GapReasonInvalidParticipants)
+	} else {
+		shifts, err := projectSchedule(ctx, schedule, windowStart, windowEnd)
+		if err != nil {
+			return nil, err
+		}
+		for _, shift := range shifts {
+			switch {
+			case shift.Skipped:
+			case shift.Paused && shift.UserID == 0:
+				addGap(shift.StartTime, shift.EndTime, GapReasonPaused)
+			case shift.UserID == 0:
+				addGap(shift.StartTime, shift.EndTime, GapReasonUnfilledPTO)
+			case removed[shift.UserID]:
+				addGap(shift.StartTime, shift.EndTime, GapReasonParticipantLeft)
+			}
+		}
+	}
+	
+	if schedule.EndTime.Before(to) {
+		addGap(schedule.EndTime, to, GapReasonScheduleEnds)
+	}
+	return gaps, nil
+}
+
+// participantsNotInTeam returns the participants who are no longer members of
+// the schedule's team. It fails if a participant does not exist at all.
+func participantsNotInTeam(ctx context.Context, schedule Schedule) (map[int]bool, error) {
+	removed := make(map[int]bool)
+	for _, userID := range schedule.Participants {
+		user, err := store.GetUserByID(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("participant user %d not found: %v", userID, err)
+		}
+		if !slices.Contains(user.TeamIDs, schedule.TeamID) {
+			removed[userID] = true
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// useMemoryWorld points the store, clock and notifier at an in-memory copy
+// of the fixture until the test ends.
+func useMemoryWorld(t *testing.T, fixture SimulationFixture, now time.Time) (*virtualClock, *recordingNotifier) {
+	t.Helper()
+	memory, err := newMemoryStore(fixture)
+	if err != nil {
+		t.Fatalf("newMemoryStore() error = %v", err)
+	}
+	virtual := &virtualClock{now: now}
+	recorder := &recordingNotifier{clock: virtual}
+
+	previousClock, previousStore, previousNotifier, previousCheckpoints := clock, store, notifier, replayCheckpoints
+	clock, store, notifier, replayCheckpoints = virtual, memory, recorder, newReplayCache()
+	t.Cleanup(func() {
+		clock, store, notifier, replayCheckpoints = previousClock, previousStore, previousNotifier, previousCheckpoints
+	})
+	return virtual, recorder
+}
+
+func TestAlertCoverageGapsOnce(t *testing.T) {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	fixture := SimulationFixture{
+		Users: []User{{ID: 1, TeamIDs: []int{1}}, {ID: 2, TeamIDs: []int{1}}},
+		Schedules: []SimulationSchedule{
+			// Paused without a fallback since the first shift: an ongoing gap
+			{Schedule: Schedule{ID: 1, TeamID: 1, Name: "paused", StartTime: start, EndTime: start.AddDate(1, 0, 0),
+				Status: ScheduleStatusPaused, StatusChangedAt: start.Add(12 * time.Hour),
+				ScheduleDefinition: ScheduleDefinition{RotationPeriod: 86400, Participants: []int{1, 2}}}},
+			// Both away for a day, which the scheduler warns about
+			{Schedule: Schedule{ID: 2, TeamID: 1, Name: "away", StartTime: start, EndTime: start.AddDate(1, 0, 0),
+				ScheduleDefinition: ScheduleDefinition{RotationPeriod: 86400, Participants: []int{1, 2}}}},
+		},
+		Unavailability: []Unavailability{
+			{UserID: 1, StartTime: start.AddDate(0, 0, 4), EndTime: start.AddDate(0, 0, 5)},
+			{UserID: 2, StartTime: start.AddDate(0, 0, 4), EndTime: start.AddDate(0, 0, 5)},
+		},
+	}
+	virtual, recorder := useMemoryWorld(t, fixture, start.AddDate(0, 0, 2).Add(10*time.Hour))
+
+	// Each check clips the ongoing gap to the time it runs
+	for i := 0; i < 3; i++ {
+		alertCoverageGaps(context.Background(), 7, 0)
+		virtual.Set(virtual.Now().Add(time.Hour))
+	}
+
+	if len(recorder.notifications) != 1 {
+		t.Fatalf("sent %d alerts, want 1: %+v", len(recorder.notifications), recorder.notifications)
+	}
+	if got := recorder.notifications[0]; got.ScheduleID != 1 || got.Reason != GapReasonPaused {
+		t.Errorf("alerted %+v, want the paused gap of schedule 1", got)
+	}
+}
@@ -1,11 +1,16 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 func initDB() {
@@ -14,118 +19,512 @@ func initDB() {
 }
 
 // User functions
-func createUser(email, slackHandle string, teamID int) (int, error) {
+
+// createUser adds the user, as a member of the team when teamID is set.
+func createUser(ctx context.Context, email, slackHandle string, teamID int) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	
 	var id int
-	err := db.QueryRow("INSERT INTO users (email, slack_handle, team_id) VALUES ($1, $2, $3) RETURNING id", 
-		email, slackHandle, teamID).Scan(&id)
-	return id, err
+	err = tx.QueryRowContext(ctx, "INSERT INTO users (email, slack_handle) VALUES ($1, $2) RETURNING id", 
+		email, slackHandle).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	
+	if teamID != 0 {
+		if err := addUserToTeam(ctx, tx, teamID, id, TeamRoleMember); err != nil {
+			return 0, err
+		}
+	}
+	return id, tx.Commit()
 }
 
-func getUsers() ([]User, error) {
-	rows, err := db.Query("SELECT id, email, slack_handle, team_id, created_at FROM users")
+// userColumns selects a user along with the teams they are a member of,
+// leaving out deleted teams.
+const userColumns = `users.id, users.email, users.slack_handle,
+	COALESCE((SELECT string_agg(tu.team_id::text, ',' ORDER BY tu.team_id) FROM team_users tu
+		JOIN teams t ON t.id = tu.team_id AND t.deleted_at IS NULL WHERE tu.user_id = users.id), ''),
+	users.revision, users.created_at, users.deleted_at`
+
+func scanUser(row rowScanner) (User, error) {
+	var user User
+	var teamIDs string
+	err := row.Scan(&user.ID, &user.Email, &user.SlackHandle, &teamIDs, &user.Revision, &user.CreatedAt, &user.DeletedAt)
 	if err != nil {
-		return nil, err
+		return user, err
 	}
-	defer rows.Close()
+	user.TeamIDs, err = splitIDs(teamIDs)
+	return user, err
+}
 
-	var users []User
-	for rows.Next() {
-		var user User
-		err := rows.Scan(&user.ID, &user.Email, &user.SlackHandle, &user.TeamID, &user.CreatedAt)
-		if err != nil {
-			return nil, err
-		}
-		users = append(users, user)
-	}
-	return users, nil
+// userFilter narrows a user listing. Zero values match every user that is
+// not deleted.
+type userFilter struct {
+	TeamID         int    // member of the team
+	Email          string // contains, ignoring case
+	IncludeDeleted bool
+}
+
+var userSortFields = map[string]sortField{
+	"id":         {"id", "integer"},
+	"email":      {"email", "text"},
+	"created_at": {"created_at", "timestamptz"},
 }
 
-func getUsersByTeamID(teamID int) ([]User, error) {
-	rows, err := db.Query("SELECT id, email, slack_handle, team_id, created_at FROM users WHERE team_id = $1", teamID)
+// listUsers returns a page of the users matching filter, and the cursor of
+// the next page if there is one.
+func listUsers(ctx context.Context, filter userFilter, page pageRequest) ([]User, *pageCursor, error) {
+	var q listQuery
+	if filter.TeamID != 0 {
+		q.where("EXISTS (SELECT 1 FROM team_users tu WHERE tu.user_id = users.id AND tu.team_id = " + q.arg(filter.TeamID) + ")")
+	}
+	if filter.Email != "" {
+		q.where("strpos(lower(email), lower(" + q.arg(filter.Email) + ")) > 0")
+	}
+	if !filter.IncludeDeleted {
+		q.where("deleted_at IS NULL")
+	}
+	
+	rows, err := db.QueryContext(ctx, q.page("SELECT "+userColumns+" FROM users", page, userSortFields), q.args...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer rows.Close()
 
-	var users []User
+	users := []User{}
 	for rows.Next() {
-		var user User
-		err := rows.Scan(&user.ID, &user.Email, &user.SlackHandle, &user.TeamID, &user.CreatedAt)
+		user, err := scanUser(rows)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		users = append(users, user)
 	}
-	return users, nil
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	
+	if len(users) <= page.Limit {
+		return users, nil, nil
+	}
+	users = users[:page.Limit]
+	last := users[len(users)-1]
+	value := strconv.Itoa(last.ID)
+	switch page.Sort {
+	case "email":
+		value = last.Email
+	case "created_at":
+		value = cursorTime(last.CreatedAt)
+	}
+	return users, page.cursorAfter(value, last.ID), nil
 }
 
-func getUserByID(userID int) (*User, error) {
-	var user User
-	err := db.QueryRow("SELECT id, email, slack_handle, team_id, created_at FROM users WHERE id = $1", userID).
-		Scan(&user.ID, &user.Email, &user.SlackHandle, &user.TeamID, &user.CreatedAt)
+// getUserByID returns the user, or sql.ErrNoRows if there is no such user
+// or they are deleted.
+func getUserByID(ctx context.Context, userID int) (*User, error) {
+	user, err := scanUser(db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE id = $1 AND deleted_at IS NULL", userID))
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
+// updateUser saves the user's email and Slack handle and bumps their
+// revision. It returns sql.ErrNoRows if there is no such user or their
+// revision is no longer user.Revision.
+func updateUser(ctx context.Context, user User) error {
+	result, err := db.ExecContext(ctx, `UPDATE users SET email = $2, slack_handle = $3, revision = revision + 1
+		WHERE id = $1 AND revision = $4`,
+		user.ID, user.Email, user.SlackHandle, user.Revision)
+	return requireRow(result, err)
+}
+
+// getUserScheduleIDs returns the schedules whose history depends on the
+// user: those that have had them on a roster, as their fallback user or on
+// call, or that have paired them as a shadow.
+func getUserScheduleIDs(ctx context.Context, userID int) ([]int, error) {
+	return queryIDs(ctx, db, `SELECT v.schedule_id FROM schedule_participants sp
+			JOIN schedule_versions v ON v.id = sp.schedule_version_id WHERE sp.user_id = $1
+		UNION SELECT id FROM schedules WHERE fallback_user_id = $1
+		UNION SELECT schedule_id FROM schedule_pauses WHERE fallback_user_id = $1
+		UNION SELECT schedule_id FROM oncall_assignments WHERE user_id = $1 AND schedule_id IS NOT NULL
+		UNION SELECT schedule_id FROM shadow_shifts WHERE user_id = $1 AND start_time <= now() AND schedule_id IS NOT NULL
+		ORDER BY 1`, userID)
+}
+
+// getUserRosteredScheduleIDs returns the schedules that would still put the
+// user on call: those not archived or deleted whose current or a pending
+// version has them on its roster, or that are paused with them as the
+// fallback user.
+func getUserRosteredScheduleIDs(ctx context.Context, q sqlExecutor, userID int) ([]int, error) {
+	return queryIDs(ctx, q, `SELECT s.id FROM schedules s
+			JOIN schedule_versions v ON v.schedule_id = s.id
+			JOIN schedule_participants sp ON sp.schedule_version_id = v.id
+		WHERE sp.user_id = $1 AND s.status <> $2 AND s.deleted_at IS NULL
+			AND v.effective_from >= COALESCE((SELECT MAX(effective_from) FROM schedule_versions
+				WHERE schedule_id = s.id AND effective_from <= now()), '-infinity')
+		UNION SELECT id FROM schedules WHERE fallback_user_id = $1 AND status = $3 AND deleted_at IS NULL
+		ORDER BY 1`, userID, ScheduleStatusArchived, ScheduleStatusPaused)
+}
+
+// softDeleteUser marks the user deleted and drops their shadow shifts that
+// have not started, unless a schedule would still put them on call (see
+// getUserRosteredScheduleIDs): then it deletes nothing and returns those
+// schedules. Their memberships and PTO are kept for a restore, and their
+// on-call history is untouched. It returns sql.ErrNoRows if there is no such
+// user or they are already deleted.
+func softDeleteUser(ctx context.Context, userID int) ([]int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	
+	if err := lockLiveRow(ctx, tx, "users", userID); err != nil {
+		return nil, err
+	}
+	scheduleIDs, err := getUserRosteredScheduleIDs(ctx, tx, userID)
+	if err != nil || len(scheduleIDs) > 0 {
+		return scheduleIDs, err
+	}
+	
+	if _, err := tx.ExecContext(ctx, "DELETE FROM shadow_shifts WHERE user_id = $1 AND start_time > now()", userID); err != nil {
+		return nil, err
+	}
+	if err := markDeleted(ctx, tx, "users", userID); err != nil {
+		return nil, err
+	}
+	return nil, tx.Commit()
+}
+
+// purgeUser deletes a deleted user for good together with their
+// unavailability, team memberships and shadow shifts that have not started.
+// It returns sql.ErrNoRows if there is no such user or they are not deleted,
+// and errFencedOut once fencingToken is stale.
+func purgeUser(ctx context.Context, userID int, fencingToken int64) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	
+	if err := lockFencingToken(ctx, tx, fencingToken); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM shadow_shifts WHERE user_id = $1 AND start_time > now()", userID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM user_unavailability WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	
+	result, err := tx.ExecContext(ctx, "DELETE FROM users WHERE id = $1 AND deleted_at IS NOT NULL", userID)
+	if err := requireRow(result, err); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 // Team functions
-func createTeam(name string) (int, error) {
+func createTeam(ctx context.Context, name, slackChannel string) (int, error) {
 	var id int
-	err := db.QueryRow("INSERT INTO teams (name) VALUES ($1) RETURNING id", name).Scan(&id)
+	err := db.QueryRowContext(ctx, "INSERT INTO teams (name, slack_channel) VALUES ($1, NULLIF($2, '')) RETURNING id", name, slackChannel).Scan(&id)
 	return id, err
 }
 
-func getTeams() ([]Team, error) {
-	rows, err := db.Query("SELECT id, name, created_at FROM teams")
+func getTeamSlackChannel(ctx context.Context, teamID int) (string, error) {
+	var channel string
+	err := db.QueryRowContext(ctx, "SELECT COALESCE(slack_channel, '') FROM teams WHERE id = $1", teamID).Scan(&channel)
+	return channel, err
+}
+
+// teamFilter narrows a team listing. Zero values match every team that is
+// not deleted.
+type teamFilter struct {
+	Name           string // contains, ignoring case
+	IncludeDeleted bool
+}
+
+var teamSortFields = map[string]sortField{
+	"id":         {"id", "integer"},
+	"name":       {"name", "text"},
+	"created_at": {"created_at", "timestamptz"},
+}
+
+// listTeams returns a page of the teams matching filter with their members,
+// and the cursor of the next page if there is one.
+func listTeams(ctx context.Context, filter teamFilter, page pageRequest) ([]Team, *pageCursor, error) {
+	var q listQuery
+	if filter.Name != "" {
+		q.where("strpos(lower(name), lower(" + q.arg(filter.Name) + ")) > 0")
+	}
+	if !filter.IncludeDeleted {
+		q.where("deleted_at IS NULL")
+	}
+	
+	rows, err := db.QueryContext(ctx, q.page("SELECT id, name, COALESCE(slack_channel, ''), revision, created_at, deleted_at FROM teams", page, teamSortFields),
+		q.args...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer rows.Close()
 
-	var teams []Team
+	teams := []Team{}
 	for rows.Next() {
 		var team Team
-		err := rows.Scan(&team.ID, &team.Name, &team.CreatedAt)
+		err := rows.Scan(&team.ID, &team.Name, &team.SlackChannel, &team.Revision, &team.CreatedAt, &team.DeletedAt)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+		teams = append(teams, team)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	
+	var next *pageCursor
+	if len(teams) > page.Limit {
+		teams = teams[:page.Limit]
+		last := teams[len(teams)-1]
+		value := strconv.Itoa(last.ID)
+		switch page.Sort {
+		case "name":
+			value = last.Name
+		case "created_at":
+			value = cursorTime(last.CreatedAt)
 		}
-		
-		// Get users for this team
-		users, err := getUsersByTeamID(team.ID)
+		next = page.cursorAfter(value, last.ID)
+	}
+	
+	if err := attachTeamMembers(ctx, teams); err != nil {
+		return nil, nil, err
+	}
+	return teams, next, nil
+}
+
+// teamExists reports whether the team exists and is not deleted.
+func teamExists(ctx context.Context, teamID int) (bool, error) {
+	var exists bool
+	err := db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM teams WHERE id = $1 AND deleted_at IS NULL)", teamID).Scan(&exists)
+	return exists, err
+}
+
+// scheduleExists reports whether the schedule exists and is not deleted.
+func scheduleExists(ctx context.Context, scheduleID int) (bool, error) {
+	var exists bool
+	err := db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM schedules WHERE id = $1 AND deleted_at IS NULL)", scheduleID).Scan(&exists)
+	return exists, err
+}
+
+// getTeamByID returns the team with its members, or sql.ErrNoRows if there
+// is no such team or it is deleted.
+func getTeamByID(ctx context.Context, teamID int) (*Team, error) {
+	var team Team
+	err := db.QueryRowContext(ctx, `SELECT id, name, COALESCE(slack_channel, ''), revision, created_at, deleted_at FROM teams
+		WHERE id = $1 AND deleted_at IS NULL`, teamID).
+		Scan(&team.ID, &team.Name, &team.SlackChannel, &team.Revision, &team.CreatedAt, &team.DeletedAt)
+	if err != nil {
+		return nil, err
+	}
+	
+	teams := []Team{team}
+	if err := attachTeamMembers(ctx, teams); err != nil {
+		return nil, err
+	}
+	return &teams[0], nil
+}
+
+// attachTeamMembers loads the members of all the teams in one query and sets
+// each team's members, in the order they joined. Deleted users are left out.
+func attachTeamMembers(ctx context.Context, teams []Team) error {
+	if len(teams) == 0 {
+		return nil
+	}
+	ids := make([]int, len(teams))
+	for i, team := range teams {
+		ids[i] = team.ID
+	}
+	
+	rows, err := db.QueryContext(ctx, `SELECT tu.team_id, tu.role, tu.created_at, `+userColumns+`
+		FROM team_users tu JOIN users ON users.id = tu.user_id
+		WHERE tu.team_id = ANY($1) AND users.deleted_at IS NULL ORDER BY tu.created_at, users.id`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	members := make(map[int][]TeamMember)
+	for rows.Next() {
+		var teamID int
+		var member TeamMember
+		var teamIDs string
+		err := rows.Scan(&teamID, &member.Role, &member.JoinedAt, &member.ID, &member.Email, &member.SlackHandle, &teamIDs, &member.Revision, &member.CreatedAt,
+			&member.DeletedAt)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		team.Users = users
-		
-		teams = append(teams, team)
+		if member.TeamIDs, err = splitIDs(teamIDs); err != nil {
+			return err
+		}
+		members[teamID] = append(members[teamID], member)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	
+	for i := range teams {
+		teams[i].Members = members[teams[i].ID]
+		if teams[i].Members == nil {
+			teams[i].Members = []TeamMember{}
+		}
+	}
+	return nil
+}
+
+// updateTeam saves the team's name and Slack channel and bumps its
+// revision. It returns sql.ErrNoRows if there is no such team or its
+// revision is no longer team.Revision.
+func updateTeam(ctx context.Context, team Team) error {
+	result, err := db.ExecContext(ctx, `UPDATE teams SET name = $2, slack_channel = NULLIF($3, ''), revision = revision + 1
+		WHERE id = $1 AND revision = $4`,
+		team.ID, team.Name, team.SlackChannel, team.Revision)
+	return requireRow(result, err)
+}
+
+// getTeamScheduleIDs returns the team's schedules that are not deleted,
+// archived ones included.
+func getTeamScheduleIDs(ctx context.Context, q sqlExecutor, teamID int) ([]int, error) {
+	return queryIDs(ctx, q, "SELECT id FROM schedules WHERE team_id = $1 AND deleted_at IS NULL ORDER BY id", teamID)
+}
+
+// softDeleteTeam marks the team deleted unless it has schedules that are not
+// deleted: then it deletes nothing and returns those schedules. It returns
+// sql.ErrNoRows if there is no such team or it is already deleted.
+func softDeleteTeam(ctx context.Context, teamID int) ([]int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	
+	if err := lockLiveRow(ctx, tx, "teams", teamID); err != nil {
+		return nil, err
+	}
+	scheduleIDs, err := getTeamScheduleIDs(ctx, tx, teamID)
+	if err != nil || len(scheduleIDs) > 0 {
+		return scheduleIDs, err
+	}
+	
+	if err := markDeleted(ctx, tx, "teams", teamID); err != nil {
+		return nil, err
+	}
+	return nil, tx.Commit()
+}
+
+// purgeTeam deletes a deleted team for good together with its holidays and
+// memberships. It returns sql.ErrNoRows if there is no such team or it is
+// not deleted, and errFencedOut once fencingToken is stale.
+func purgeTeam(ctx context.Context, teamID int, fencingToken int64) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	
+	if err := lockFencingToken(ctx, tx, fencingToken); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM holidays WHERE team_id = $1", teamID); err != nil {
+		return err
 	}
-	return teams, nil
+	
+	result, err := tx.ExecContext(ctx, "DELETE FROM teams WHERE id = $1 AND deleted_at IS NOT NULL", teamID)
+	if err := requireRow(result, err); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func addUserToTeam(userID, teamID int) error {
-	_, err := db.Exec("INSERT INTO team_users (team_id, user_id) VALUES ($1, $2) ON CONFLICT (team_id, user_id) DO NOTHING", 
-		teamID, userID)
+// bumpMembershipRevisions follows a statement named member that returns the
+// team_id and user_id of the memberships it changed, and bumps the revisions
+// of those teams and users, as each lists the other. It updates one row per
+// user.
+const bumpMembershipRevisions = `, teams_changed AS (
+			UPDATE teams SET revision = revision + 1 WHERE id IN (SELECT team_id FROM member))
+		UPDATE users SET revision = revision + 1 WHERE id IN (SELECT user_id FROM member)`
+
+// addUserToTeam makes the user a member of the team with the given role, or
+// changes their role if they already are one, in the same statement as it
+// bumps the revisions of both.
+func addUserToTeam(ctx context.Context, exec sqlExecutor, teamID, userID int, role string) error {
+	_, err := exec.ExecContext(ctx, `WITH member AS (
+			INSERT INTO team_users (team_id, user_id, role) VALUES ($1, $2, $3)
+			ON CONFLICT (team_id, user_id) DO UPDATE SET role = EXCLUDED.role
+			RETURNING team_id, user_id)
+		`+bumpMembershipRevisions, 
+		teamID, userID, role)
 	return err
 }
 
+// removeUserFromTeam ends the user's membership of the team. It returns
+// false if they were not a member.
+func removeUserFromTeam(ctx context.Context, teamID, userID int) (bool, error) {
+	result, err := db.ExecContext(ctx, `WITH member AS (
+			DELETE FROM team_users WHERE team_id = $1 AND user_id = $2
+			RETURNING team_id, user_id)
+		`+bumpMembershipRevisions, teamID, userID)
+	if err != nil {
+		return false, err
+	}
+	removed, err := result.RowsAffected()
+	return removed > 0, err
+}
+
 // Schedule functions
-func createSchedule(teamID int, name string, startTime, endTime time.Time, rotationPeriod int, participants []int) (int, error) {
-	// Convert participant IDs to string for storage
-	participantStrings := make([]string, len(participants))
-	for i, id := range participants {
-		participantStrings[i] = strconv.Itoa(id)
+
+// createSchedule records the schedule and its definition as version 1, in
+// effect from the schedule's start.
+func createSchedule(ctx context.Context, schedule Schedule) (int, error) {
+	if schedule.RotationStrategy == "" {
+		schedule.RotationStrategy = RotationStrategyRoundRobin
 	}
-	participantList := strings.Join(participantStrings, ",")
+	if schedule.HolidayMode == "" {
+		schedule.HolidayMode = HolidayModeNone
+	}
+	if schedule.Timezone == "" {
+		schedule.Timezone = "UTC"
+	}
+	
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
 	
 	var id int
-	err := db.QueryRow("INSERT INTO schedules (team_id, name, start_time, end_time, rotation_period, participant_ids) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id", 
-		teamID, name, startTime, endTime, rotationPeriod, participantList).Scan(&id)
-	return id, err
+	err = tx.QueryRowContext(ctx, `INSERT INTO schedules (team_id, name, start_time, end_time, rotation_period,
+		rotation_strategy, sequence_ids, holiday_mode, holiday_schedule_id)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9) RETURNING id`, 
+		schedule.TeamID, schedule.Name, schedule.StartTime, schedule.EndTime, schedule.RotationPeriod,
+		schedule.RotationStrategy, joinIDs(schedule.Sequence), schedule.HolidayMode, nullableID(schedule.HolidayScheduleID)).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	
+	if _, err := insertScheduleVersion(ctx, tx, id, schedule.StartTime, schedule.ScheduleDefinition); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
 }
 
-func getSchedules() ([]Schedule, error) {
-	rows, err := db.Query("SELECT id, team_id, name, start_time, end_time, rotation_period, COALESCE(participant_ids, '') as participant_ids, created_at FROM schedules")
+const scheduleColumns = "id, team_id, name, start_time, end_time, rotation_period, rotation_strategy, COALESCE(sequence_ids, ''), holiday_mode, COALESCE(holiday_schedule_id, 0), created_at, status, COALESCE(fallback_user_id, 0), COALESCE(status_changed_at, created_at), revision, deleted_at"
+
+// getSchedules returns every schedule that is not deleted.
+func getSchedules(ctx context.Context) ([]Schedule, error) {
+	rows, err := db.QueryContext(ctx, "SELECT " + scheduleColumns + " FROM schedules WHERE deleted_at IS NULL")
 	if err != nil {
 		return nil, err
 	}
@@ -133,69 +532,1037 @@ func getSchedules() ([]Schedule, error) {
 
 	var schedules []Schedule
 	for rows.Next() {
-		var schedule Schedule
-		var participantList string
-		err := rows.Scan(&schedule.ID, &schedule.TeamID, &schedule.Name, &schedule.StartTime, 
-			&schedule.EndTime, &schedule.RotationPeriod, &participantList, &schedule.CreatedAt)
+		schedule, err := scanSchedule(rows)
 		if err != nil {
 			return nil, err
 		}
-		
-		// Convert participant string back to int slice
-		if participantList != "" {
-			participantStrings := strings.Split(participantList, ",")
-			schedule.Participants = make([]int, len(participantStrings))
-			for i, idStr := range participantStrings {
-				id, err := strconv.Atoi(idStr)
-				if err != nil {
-					return nil, fmt.Errorf("error converting participant ID: %v", err)
-				}
-				schedule.Participants[i] = id
-			}
-		}
-		
-		schedules = append(schedules, schedule)
+		schedules = append(schedules, *schedule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	
+	if err := attachScheduleVersions(ctx, schedules); err != nil {
+		return nil, err
 	}
 	return schedules, nil
 }
 
-// OnCall Assignment functions
-func getCurrentOnCallAssignments() ([]OnCallAssignment, error) {
-	query := `
-		SELECT a.id, a.schedule_id, a.user_id, a.start_time, a.end_time, a.active 
-		FROM oncall_assignments a
-		INNER JOIN (
-			SELECT schedule_id, MAX(start_time) as max_start_time
-			FROM oncall_assignments 
-			GROUP BY schedule_id
-		) latest ON a.schedule_id = latest.schedule_id AND a.start_time = latest.max_start_time`
-	fmt.Printf("Executing query: %s\n", query)
-	rows, err := db.Query(query)
+// scheduleFilter narrows a schedule listing. Zero values match every
+// schedule that is neither archived nor deleted.
+type scheduleFilter struct {
+	TeamID          int
+	Name            string // contains, ignoring case
+	Active          *bool  // status is active, or is not
+	IncludeArchived bool
+	IncludeDeleted  bool
+}
+
+var scheduleSortFields = map[string]sortField{
+	"id":         {"id", "integer"},
+	"name":       {"name", "text"},
+	"start_time": {"start_time", "timestamptz"},
+	"end_time":   {"end_time", "timestamptz"},
+	"created_at": {"created_at", "timestamptz"},
+}
+
+// listSchedules returns a page of the schedules matching filter with their
+// versions, and the cursor of the next page if there is one.
+func listSchedules(ctx context.Context, filter scheduleFilter, page pageRequest) ([]Schedule, *pageCursor, error) {
+	var q listQuery
+	if filter.TeamID != 0 {
+		q.where("team_id = " + q.arg(filter.TeamID))
+	}
+	if filter.Name != "" {
+		q.where("strpos(lower(name), lower(" + q.arg(filter.Name) + ")) > 0")
+	}
+	if filter.Active != nil && *filter.Active {
+		q.where("status = " + q.arg(ScheduleStatusActive))
+	} else if filter.Active != nil {
+		q.where("status <> " + q.arg(ScheduleStatusActive))
+	}
+	if !filter.IncludeArchived {
+		q.where("status <> " + q.arg(ScheduleStatusArchived))
+	}
+	if !filter.IncludeDeleted {
+		q.where("deleted_at IS NULL")
+	}
+	
+	rows, err := db.QueryContext(ctx, q.page("SELECT "+scheduleColumns+" FROM schedules", page, scheduleSortFields), q.args...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer rows.Close()
 
-	var assignments []OnCallAssignment
+	schedules := []Schedule{}
 	for rows.Next() {
-		var assignment OnCallAssignment
-		err := rows.Scan(&assignment.ID, &assignment.ScheduleID, &assignment.UserID, 
-			&assignment.StartTime, &assignment.EndTime, &assignment.Active)
+		schedule, err := scanSchedule(rows)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
-		assignments = append(assignments, assignment)
+		schedules = append(schedules, *schedule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
 	}
-	return assignments, nil
+	
+	var next *pageCursor
+	if len(schedules) > page.Limit {
+		schedules = schedules[:page.Limit]
+		last := schedules[len(schedules)-1]
+		value := strconv.Itoa(last.ID)
+		switch page.Sort {
+		case "name":
+			value = last.Name
+		case "start_time":
+			value = cursorTime(last.StartTime)
+		case "end_time":
+			value = cursorTime(last.EndTime)
+		case "created_at":
+			value = cursorTime(last.CreatedAt)
+		}
+		next = page.cursorAfter(value, last.ID)
+	}
+	
+	if err := attachScheduleVersions(ctx, schedules); err != nil {
+		return nil, nil, err
+	}
+	return schedules, next, nil
 }
 
-func createOnCallAssignment(scheduleID int, userID int, startTime, endTime time.Time) error {
-	_, err := db.Exec("INSERT INTO oncall_assignments (schedule_id, user_id, start_time, end_time, active) VALUES ($1, $2, $3, $4, $5)", 
-		scheduleID, userID, startTime, endTime, true)
-	return err
+// getScheduleByID returns the schedule with its versions, or sql.ErrNoRows
+// if there is no such schedule or it is deleted.
+func getScheduleByID(ctx context.Context, scheduleID int) (*Schedule, error) {
+	schedule, err := scanSchedule(db.QueryRowContext(ctx, "SELECT "+scheduleColumns+" FROM schedules WHERE id = $1 AND deleted_at IS NULL",
+		scheduleID))
+	if err != nil {
+		return nil, err
+	}
+	
+	schedules := []Schedule{*schedule}
+	if err := attachScheduleVersions(ctx, schedules); err != nil {
+		return nil, err
+	}
+	return &schedules[0], nil
 }
 
-func deactivateAssignment(assignmentID int) error {
-	_, err := db.Exec("UPDATE oncall_assignments SET active = false WHERE id = $1", assignmentID)
-	return err
+// attachScheduleVersions loads every version and pause of the schedules and
+// sets each schedule's definition to the version in effect now.
+func attachScheduleVersions(ctx context.Context, schedules []Schedule) error {
+	if len(schedules) == 0 {
+		return nil
+	}
+	ids := make([]int, len(schedules))
+	for i, schedule := range schedules {
+		ids[i] = schedule.ID
+	}
+	
+	versions, err := getScheduleVersions(ctx, ids...)
+	if err != nil {
+		return err
+	}
+	pauses, err := getSchedulePauses(ctx, ids...)
+	if err != nil {
+		return err
+	}
+	
+	now := clock.Now()
+	for i := range schedules {
+		schedules[i].Versions = versions[schedules[i].ID]
+		schedules[i].Pauses = pauses[schedules[i].ID]
+		schedules[i] = versionAt(schedules[i], now)
+	}
+	return nil
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanSchedule reads a schedule row. Its definition is completed by
+// attachScheduleVersions, which loads the rosters.
+func scanSchedule(row rowScanner) (*Schedule, error) {
+	var schedule Schedule
+	var sequenceList string
+	err := row.Scan(&schedule.ID, &schedule.TeamID, &schedule.Name, &schedule.StartTime, 
+		&schedule.EndTime, &schedule.RotationPeriod, &schedule.RotationStrategy, &sequenceList, &schedule.HolidayMode, &schedule.HolidayScheduleID, &schedule.CreatedAt,
+		&schedule.Status, &schedule.FallbackUserID, &schedule.StatusChangedAt, &schedule.Revision, &schedule.DeletedAt)
+	if err != nil {
+		return nil, err
+	}
+	
+	schedule.Sequence, err = splitIDs(sequenceList)
+	if err != nil {
+		return nil, fmt.Errorf("error converting sequence ID: %v", err)
+	}
+	return &schedule, nil
+}
+
+// updateScheduleStatus moves the schedule to a new lifecycle state, with
+// the user who covers it while paused, and bumps its revision. The pause in
+// progress, if any, ends, and pausing starts a new one, so pausing again
+// with another fallback user leaves the shifts already covered to the
+// first. It returns sql.ErrNoRows if the schedule's revision is no longer
+// revision.
+func updateScheduleStatus(ctx context.Context, scheduleID, revision int, status string, fallbackUserID int) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	
+	// now() is the same for the whole transaction
+	result, err := tx.ExecContext(ctx, `UPDATE schedules SET status = $2, fallback_user_id = $3, status_changed_at = now(), revision = revision + 1
+		WHERE id = $1 AND revision = $4`,
+		scheduleID, status, nullableID(fallbackUserID), revision)
+	if err := requireRow(result, err); err != nil {
+		return err
+	}
+	
+	if _, err := tx.ExecContext(ctx, "UPDATE schedule_pauses SET resumed_at = now() WHERE schedule_id = $1 AND resumed_at IS NULL", scheduleID); err != nil {
+		return err
+	}
+	if status == ScheduleStatusPaused {
+		_, err := tx.ExecContext(ctx, "INSERT INTO schedule_pauses (schedule_id, paused_at, fallback_user_id) VALUES ($1, now(), $2)",
+			scheduleID, nullableID(fallbackUserID))
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// updateSchedule saves the schedule's name and end time and, when definition
+// is set, records it as a new version in effect from effectiveFrom, all in
+// one transaction. It returns the new version number, or 0 without one, and
+// sql.ErrNoRows if the schedule's revision is no longer schedule.Revision.
+func updateSchedule(ctx context.Context, schedule Schedule, definition *ScheduleDefinition, effectiveFrom time.Time) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	
+	result, err := tx.ExecContext(ctx, `UPDATE schedules SET name = $2, end_time = $3, revision = revision + 1
+		WHERE id = $1 AND revision = $4`,
+		schedule.ID, schedule.Name, schedule.EndTime, schedule.Revision)
+	if err := requireRow(result, err); err != nil {
+		return 0, err
+	}
+	
+	version := 0
+	if definition != nil {
+		if version, err = insertScheduleVersion(ctx, tx, schedule.ID, effectiveFrom, *definition); err != nil {
+			return 0, err
+		}
+	}
+	return version, tx.Commit()
+}
+
+// scheduleHasAssignments reports whether anyone has been on call for the
+// schedule.
+func scheduleHasAssignments(ctx context.Context, scheduleID int) (bool, error) {
+	var exists bool
+	err := db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM oncall_assignments WHERE schedule_id = $1)", scheduleID).Scan(&exists)
+	return exists, err
+}
+
+// getHolidayScheduleUsers returns the other schedules, not deleted, that use
+// the schedule to cover their holiday shifts, in any version.
+func getHolidayScheduleUsers(ctx context.Context, q sqlExecutor, scheduleID int) ([]int, error) {
+	return queryIDs(ctx, q, `SELECT id FROM schedules WHERE holiday_schedule_id = $1 AND id <> $1 AND deleted_at IS NULL
+		UNION SELECT v.schedule_id FROM schedule_versions v JOIN schedules s ON s.id = v.schedule_id
+		WHERE v.holiday_schedule_id = $1 AND v.schedule_id <> $1 AND s.deleted_at IS NULL
+		ORDER BY 1`, scheduleID)
+}
+
+// softDeleteSchedule marks the schedule deleted unless other schedules use
+// it to cover their holidays: then it deletes nothing and returns those
+// schedules. It returns sql.ErrNoRows if there is no such schedule or it is
+// already deleted.
+func softDeleteSchedule(ctx context.Context, scheduleID int) ([]int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	
+	if err := lockLiveRow(ctx, tx, "schedules", scheduleID); err != nil {
+		return nil, err
+	}
+	dependents, err := getHolidayScheduleUsers(ctx, tx, scheduleID)
+	if err != nil || len(dependents) > 0 {
+		return dependents, err
+	}
+	
+	if err := markDeleted(ctx, tx, "schedules", scheduleID); err != nil {
+		return nil, err
+	}
+	return nil, tx.Commit()
+}
+
+// scheduleTeamDeleted reports whether the schedule's team is deleted, whether
+// or not the schedule is. It returns sql.ErrNoRows if there is no such
+// schedule.
+func scheduleTeamDeleted(ctx context.Context, scheduleID int) (bool, error) {
+	var deleted bool
+	err := db.QueryRowContext(ctx, `SELECT t.deleted_at IS NOT NULL FROM schedules s JOIN teams t ON t.id = s.team_id
+		WHERE s.id = $1`, scheduleID).Scan(&deleted)
+	return deleted, err
+}
+
+// purgeSchedule deletes a deleted schedule for good together with its
+// versions, pauses and shadow shifts. It returns sql.ErrNoRows if there is no
+// such schedule or it is not deleted, and errFencedOut once fencingToken is
+// stale.
+func purgeSchedule(ctx context.Context, scheduleID int, fencingToken int64) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	
+	if err := lockFencingToken(ctx, tx, fencingToken); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM shadow_shifts WHERE schedule_id = $1", scheduleID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM schedule_versions WHERE schedule_id = $1", scheduleID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM schedule_pauses WHERE schedule_id = $1", scheduleID); err != nil {
+		return err
+	}
+	
+	result, err := tx.ExecContext(ctx, "DELETE FROM schedules WHERE id = $1 AND deleted_at IS NOT NULL", scheduleID)
+	if err := requireRow(result, err); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Schedule version functions
+
+// createScheduleVersion records a new version of the schedule's definition
+// in effect from effectiveFrom, bumps the schedule's revision and returns
+// the version number. It returns sql.ErrNoRows if the schedule's revision is
+// no longer revision.
+func createScheduleVersion(ctx context.Context, scheduleID, revision int, effectiveFrom time.Time, definition ScheduleDefinition) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	
+	result, err := tx.ExecContext(ctx, "UPDATE schedules SET revision = revision + 1 WHERE id = $1 AND revision = $2",
+		scheduleID, revision)
+	if err := requireRow(result, err); err != nil {
+		return 0, err
+	}
+	
+	version, err := insertScheduleVersion(ctx, tx, scheduleID, effectiveFrom, definition)
+	if err != nil {
+		return 0, err
+	}
+	return version, tx.Commit()
+}
+
+// insertScheduleVersion records the version with its roster. q should be a
+// transaction, so a version is never seen without its participants.
+func insertScheduleVersion(ctx context.Context, q sqlExecutor, scheduleID int, effectiveFrom time.Time, definition ScheduleDefinition) (int, error) {
+	// Concurrent edits race for the same version number and all but one
+	// fail on the unique constraint
+	var id, version int
+	err := q.QueryRowContext(ctx, `
+		INSERT INTO schedule_versions (schedule_id, version, effective_from, rotation_period,
+			rotation_strategy, sequence_ids, holiday_mode, holiday_schedule_id, timezone)
+		SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8
+		FROM schedule_versions WHERE schedule_id = $1
+		RETURNING id, version`,
+		scheduleID, effectiveFrom, definition.RotationPeriod, definition.RotationStrategy, joinIDs(definition.Sequence),
+		definition.HolidayMode, nullableID(definition.HolidayScheduleID), definition.Timezone).Scan(&id, &version)
+	if err != nil {
+		return 0, err
+	}
+	
+	for _, participant := range definition.Roster {
+		_, err := q.ExecContext(ctx, `
+			INSERT INTO schedule_participants (schedule_version_id, user_id, position, weight, tier, active)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			id, participant.UserID, participant.Position, participant.Weight, participant.Tier, participant.Active)
+		if err != nil {
+			return 0, fmt.Errorf("error adding participant %d: %v", participant.UserID, err)
+		}
+	}
+	return version, nil
+}
+
+// getSchedulePauses returns every pause of the given schedules, oldest
+// first, keyed by schedule ID.
+func getSchedulePauses(ctx context.Context, scheduleIDs ...int) (map[int][]SchedulePause, error) {
+	rows, err := db.QueryContext(ctx, `SELECT schedule_id, paused_at, resumed_at, COALESCE(fallback_user_id, 0)
+		FROM schedule_pauses WHERE schedule_id = ANY($1) ORDER BY schedule_id, paused_at, id`, pq.Array(scheduleIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	
+	pauses := make(map[int][]SchedulePause)
+	for rows.Next() {
+		var scheduleID int
+		var pause SchedulePause
+		if err := rows.Scan(&scheduleID, &pause.PausedAt, &pause.ResumedAt, &pause.FallbackUserID); err != nil {
+			return nil, err
+		}
+		pauses[scheduleID] = append(pauses[scheduleID], pause)
+	}
+	return pauses, rows.Err()
+}
+
+// getScheduleVersions returns every version of the given schedules, oldest
+// first, keyed by schedule ID.
+func getScheduleVersions(ctx context.Context, scheduleIDs ...int) (map[int][]ScheduleVersion, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, schedule_id, version, effective_from, rotation_period, rotation_strategy,
+			COALESCE(sequence_ids, ''), holiday_mode, COALESCE(holiday_schedule_id, 0), timezone, created_at
+		FROM schedule_versions
+		WHERE schedule_id = ANY($1)
+		ORDER BY schedule_id, version`, pq.Array(scheduleIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var all []ScheduleVersion
+	var versionIDs []int
+	for rows.Next() {
+		var version ScheduleVersion
+		var sequenceList string
+		err := rows.Scan(&version.ID, &version.ScheduleID, &version.Version, &version.EffectiveFrom, &version.RotationPeriod,
+			&version.RotationStrategy, &sequenceList, &version.HolidayMode, &version.HolidayScheduleID, &version.Timezone,
+			&version.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		version.Sequence, err = splitIDs(sequenceList)
+		if err != nil {
+			return nil, fmt.Errorf("error converting sequence ID: %v", err)
+		}
+		all = append(all, version)
+		versionIDs = append(versionIDs, version.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	
+	rosters, err := getScheduleRosters(ctx, versionIDs)
+	if err != nil {
+		return nil, err
+	}
+	
+	versions := make(map[int][]ScheduleVersion)
+	for _, version := range all {
+		version.Roster = rosters[version.ID]
+		applyRoster(&version.ScheduleDefinition)
+		versions[version.ScheduleID] = append(versions[version.ScheduleID], version)
+	}
+	return versions, nil
+}
+
+// getScheduleRosters returns the participants of the given schedule
+// versions in position order, keyed by version ID.
+func getScheduleRosters(ctx context.Context, versionIDs []int) (map[int][]ScheduleParticipant, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT schedule_version_id, user_id, position, weight, tier, active
+		FROM schedule_participants
+		WHERE schedule_version_id = ANY($1)
+		ORDER BY schedule_version_id, position`, pq.Array(versionIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rosters := make(map[int][]ScheduleParticipant)
+	for rows.Next() {
+		var versionID int
+		var participant ScheduleParticipant
+		err := rows.Scan(&versionID, &participant.UserID, &participant.Position, &participant.Weight, &participant.Tier, &participant.Active)
+		if err != nil {
+			return nil, err
+		}
+		rosters[versionID] = append(rosters[versionID], participant)
+	}
+	return rosters, rows.Err()
+}
+
+// joinIDs stores a list of IDs as a comma-separated string.
+func joinIDs(ids []int) string {
+	idStrings := make([]string, len(ids))
+	for i, id := range ids {
+		idStrings[i] = strconv.Itoa(id)
+	}
+	return strings.Join(idStrings, ",")
+}
+
+// splitIDs parses a comma-separated list of IDs; an empty string is nil.
+func splitIDs(list string) ([]int, error) {
+	if list == "" {
+		return nil, nil
+	}
+	idStrings := strings.Split(list, ",")
+	ids := make([]int, len(idStrings))
+	for i, idStr := range idStrings {
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+// softDeleteTables are the tables whose rows are soft-deleted.
+var softDeleteTables = map[string]bool{"users": true, "teams": true, "schedules": true}
+
+// markDeleted soft-deletes the row of table with the given ID and bumps its
+// revision. It returns sql.ErrNoRows if there is no such row or it is
+// already deleted.
+func markDeleted(ctx context.Context, q sqlExecutor, table string, id int) error {
+	if !softDeleteTables[table] {
+		return fmt.Errorf("%s rows are not soft-deleted", table)
+	}
+	result, err := q.ExecContext(ctx, "UPDATE "+table+" SET deleted_at = now(), revision = revision + 1 WHERE id = $1 AND deleted_at IS NULL", id)
+	return requireRow(result, err)
+}
+
+// lockLiveRow locks the row, if it is not deleted, until tx ends. Besides
+// holding off other edits, the lock makes inserts that reference the row,
+// such as a schedule for a team or a roster entry for a user, wait for tx:
+// their foreign key checks share-lock it. Checks for dependents made in tx
+// after locking therefore see every one that was committed, and no new one
+// can be committed until tx is done. It returns sql.ErrNoRows if there is
+// no such row or it is deleted.
+func lockLiveRow(ctx context.Context, tx *sql.Tx, table string, id int) error {
+	if !softDeleteTables[table] {
+		return fmt.Errorf("%s rows are not soft-deleted", table)
+	}
+	var locked int
+	return tx.QueryRowContext(ctx, "SELECT id FROM "+table+" WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", id).Scan(&locked)
+}
+
+// restoreDeleted undoes markDeleted. It returns sql.ErrNoRows if there is no
+// such row or it is not deleted.
+func restoreDeleted(ctx context.Context, table string, id int) error {
+	if !softDeleteTables[table] {
+		return fmt.Errorf("%s rows are not soft-deleted", table)
+	}
+	result, err := db.ExecContext(ctx, "UPDATE "+table+" SET deleted_at = NULL, revision = revision + 1 WHERE id = $1 AND deleted_at IS NOT NULL", id)
+	return requireRow(result, err)
+}
+
+// requireRow turns an update or delete that matched no rows into
+// sql.ErrNoRows.
+func requireRow(result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// queryIDs runs a query that selects a single integer column.
+func queryIDs(ctx context.Context, q sqlExecutor, query string, args ...interface{}) ([]int, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// isUniqueViolation reports whether err is a Postgres unique constraint
+// violation, such as a second user with the same email.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// isForeignKeyViolation reports whether err is a Postgres foreign key
+// violation, such as deleting a row that others still reference.
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
+
+// nullableID maps the zero ID to SQL NULL for optional foreign keys.
+func nullableID(id int) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// OnCall Assignment functions
+
+// sqlExecutor is satisfied by both *sql.DB and *sql.Tx, so writes that are
+// part of a rotation can run inside its transaction.
+type sqlExecutor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+const assignmentColumns = "id, schedule_id, user_id, start_time, end_time, role, tier, holiday, active, backfilled"
+
+func scanAssignment(row rowScanner) (OnCallAssignment, error) {
+	var assignment OnCallAssignment
+	err := row.Scan(&assignment.ID, &assignment.ScheduleID, &assignment.UserID, &assignment.StartTime,
+		&assignment.EndTime, &assignment.Role, &assignment.Tier, &assignment.Holiday, &assignment.Active, &assignment.Backfilled)
+	return assignment, err
+}
+
+func scanAssignments(rows *sql.Rows) ([]OnCallAssignment, error) {
+	defer rows.Close()
+
+	var assignments []OnCallAssignment
+	for rows.Next() {
+		assignment, err := scanAssignment(rows)
+		if err != nil {
+			return nil, err
+		}
+		assignments = append(assignments, assignment)
+	}
+	return assignments, rows.Err()
+}
+
+func getCurrentOnCallAssignments(ctx context.Context) ([]OnCallAssignment, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT a.id, a.schedule_id, a.user_id, a.start_time, a.end_time, a.role, a.tier, a.holiday, a.active, a.backfilled
+		FROM oncall_assignments a
+		INNER JOIN (
+			SELECT schedule_id, MAX(start_time) as max_start_time
+			FROM oncall_assignments 
+			WHERE role = 'primary' AND tier = 1
+			GROUP BY schedule_id
+		) latest ON a.schedule_id = latest.schedule_id AND a.start_time = latest.max_start_time
+		WHERE a.role = 'primary' AND a.tier = 1`)
+	if err != nil {
+		return nil, err
+	}
+	return scanAssignments(rows)
+}
+
+// getCurrentOnCallAssignment returns the latest primary assignment of one
+// schedule, or nil if it has none.
+func getCurrentOnCallAssignment(ctx context.Context, scheduleID int) (*OnCallAssignment, error) {
+	assignment, err := scanAssignment(db.QueryRowContext(ctx, `
+		SELECT `+assignmentColumns+`
+		FROM oncall_assignments
+		WHERE schedule_id = $1 AND role = 'primary' AND tier = 1
+		ORDER BY start_time DESC
+		LIMIT 1`, scheduleID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &assignment, nil
+}
+
+// lockFencingToken checks that fencingToken is still the scheduler lease's
+// current token and share-locks the lease row until tx ends, so no other
+// instance can take over while the leader's writes (rotations, coverage
+// alerts and purges) are made. It fails with errFencedOut when the token is
+// stale.
+func lockFencingToken(ctx context.Context, tx *sql.Tx, fencingToken int64) error {
+	var current int64
+	err := tx.QueryRowContext(ctx, "SELECT fencing_token FROM leader_leases WHERE name = $1 FOR SHARE", schedulerLeaseName).Scan(&current)
+	if err == sql.ErrNoRows || (err == nil && current != fencingToken) {
+		return errFencedOut
+	}
+	return err
+}
+
+// createOnCallAssignment records a tier 1 assignment and reports whether it
+// was created. Recording an assignment that already exists for the same
+// schedule and start time (and user, for shadows) is a no-op, so a rotation
+// that is retried or raced never produces duplicates.
+func createOnCallAssignment(ctx context.Context, q sqlExecutor, assignment OnCallAssignment) (bool, error) {
+	conflict := "(schedule_id, start_time, tier) WHERE role = 'primary'"
+	if assignment.Role == AssignmentRoleShadow {
+		conflict = "(schedule_id, start_time, user_id) WHERE role = 'shadow'"
+	}
+	result, err := q.ExecContext(ctx, `
+		INSERT INTO oncall_assignments (schedule_id, user_id, start_time, end_time, role, holiday, active, backfilled)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT `+conflict+` DO NOTHING`,
+		assignment.ScheduleID, assignment.UserID, assignment.StartTime, assignment.EndTime, assignment.Role,
+		assignment.Holiday, assignment.Active, assignment.Backfilled)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// recordRotation implements Store.RecordRotation in one transaction that
+// share-locks the leader lease, so a takeover cannot interleave with it.
+func recordRotation(ctx context.Context, current *OnCallAssignment, endedAt time.Time, primary *OnCallAssignment, shadows []OnCallAssignment, fencingToken int64) (bool, []OnCallAssignment, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, nil, err
+	}
+	defer tx.Rollback()
+	
+	if err := lockFencingToken(ctx, tx, fencingToken); err != nil {
+		return false, nil, err
+	}
+	
+	if current != nil && current.Active {
+		if err := deactivateAssignment(ctx, tx, current.ID, endedAt); err != nil {
+			return false, nil, fmt.Errorf("error deactivating assignment %d: %v", current.ID, err)
+		}
+	}
+	
+	var created bool
+	var createdShadows []OnCallAssignment
+	if primary != nil {
+		created, err = createOnCallAssignment(ctx, tx, *primary)
+		if err != nil {
+			return false, nil, fmt.Errorf("error creating assignment: %v", err)
+		}
+	}
+	
+	if created {
+		for _, shadow := range shadows {
+			shadowCreated, err := createOnCallAssignment(ctx, tx, shadow)
+			if err != nil {
+				return false, nil, fmt.Errorf("error creating shadow assignment: %v", err)
+			}
+			if shadowCreated {
+				createdShadows = append(createdShadows, shadow)
+			}
+		}
+	}
+	
+	if err := tx.Commit(); err != nil {
+		return false, nil, err
+	}
+	return created, createdShadows, nil
+}
+
+// getRecordedAssignments returns the schedule's tier 1 primary assignments
+// overlapping [from, to), oldest first.
+func getRecordedAssignments(ctx context.Context, scheduleID int, from, to time.Time) ([]OnCallAssignment, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT `+assignmentColumns+`
+		FROM oncall_assignments
+		WHERE schedule_id = $1 AND role = 'primary' AND tier = 1 AND start_time < $3 AND end_time > $2
+		ORDER BY start_time`, scheduleID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return scanAssignments(rows)
+}
+
+// deactivateAssignment ends an assignment at endedAt, which is earlier than
+// its recorded end when a new schedule version cut the shift short.
+func deactivateAssignment(ctx context.Context, q sqlExecutor, assignmentID int, endedAt time.Time) error {
+	_, err := q.ExecContext(ctx, "UPDATE oncall_assignments SET active = false, end_time = LEAST(end_time, $2) WHERE id = $1", assignmentID, endedAt)
+	return err
+}
+
+// Shadow shift functions
+func createShadowShift(ctx context.Context, scheduleID, userID int, startTime, endTime time.Time) (int, error) {
+	var id int
+	err := db.QueryRowContext(ctx, "INSERT INTO shadow_shifts (schedule_id, user_id, start_time, end_time) VALUES ($1, $2, $3, $4) RETURNING id",
+		scheduleID, userID, startTime, endTime).Scan(&id)
+	return id, err
+}
+
+func getShadowShifts(ctx context.Context, scheduleID int) ([]ShadowShift, error) {
+	rows, err := db.QueryContext(ctx, "SELECT id, schedule_id, user_id, start_time, end_time, created_at FROM shadow_shifts WHERE schedule_id = $1 ORDER BY start_time", scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanShadowShifts(rows)
+}
+
+// getShadowShiftsForWindow returns the shadow shifts of a schedule that
+// overlap the given rotation window.
+func getShadowShiftsForWindow(ctx context.Context, scheduleID int, startTime, endTime time.Time) ([]ShadowShift, error) {
+	rows, err := db.QueryContext(ctx, "SELECT id, schedule_id, user_id, start_time, end_time, created_at FROM shadow_shifts WHERE schedule_id = $1 AND start_time < $3 AND end_time > $2 ORDER BY start_time",
+		scheduleID, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanShadowShifts(rows)
+}
+
+func scanShadowShifts(rows *sql.Rows) ([]ShadowShift, error) {
+	shifts := []ShadowShift{}
+	for rows.Next() {
+		var shift ShadowShift
+		err := rows.Scan(&shift.ID, &shift.ScheduleID, &shift.UserID, &shift.StartTime, &shift.EndTime, &shift.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		shifts = append(shifts, shift)
+	}
+	return shifts, nil
+}
+
+// Unavailability functions
+func createUnavailability(ctx context.Context, userID int, startTime, endTime time.Time, reason string) (int, error) {
+	var id int
+	err := db.QueryRowContext(ctx, "INSERT INTO user_unavailability (user_id, start_time, end_time, reason) VALUES ($1, $2, $3, $4) RETURNING id",
+		userID, startTime, endTime, reason).Scan(&id)
+	return id, err
+}
+
+func getUnavailability(ctx context.Context) ([]Unavailability, error) {
+	rows, err := db.QueryContext(ctx, "SELECT id, user_id, start_time, end_time, COALESCE(reason, ''), created_at FROM user_unavailability ORDER BY start_time")
+	if err != nil {
+		return nil, err
+	}
+	return scanUnavailability(rows)
+}
+
+// getUnavailabilityBetween returns the unavailability entries overlapping
+// [from, to).
+func getUnavailabilityBetween(ctx context.Context, from, to time.Time) ([]Unavailability, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, user_id, start_time, end_time, COALESCE(reason, ''), created_at
+		FROM user_unavailability
+		WHERE start_time < $2 AND end_time > $1
+		ORDER BY start_time`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return scanUnavailability(rows)
+}
+
+func scanUnavailability(rows *sql.Rows) ([]Unavailability, error) {
+	defer rows.Close()
+
+	entries := []Unavailability{}
+	for rows.Next() {
+		var entry Unavailability
+		err := rows.Scan(&entry.ID, &entry.UserID, &entry.StartTime, &entry.EndTime, &entry.Reason, &entry.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// deleteUnavailability deletes the entry, or returns sql.ErrNoRows if there
+// is none.
+func deleteUnavailability(ctx context.Context, id int) error {
+	return requireRow(db.ExecContext(ctx, "DELETE FROM user_unavailability WHERE id = $1", id))
+}
+
+// getUnavailableUserIDs returns the users who are unavailable for any part
+// of the given window.
+func getUnavailableUserIDs(ctx context.Context, startTime, endTime time.Time) (map[int]bool, error) {
+	rows, err := db.QueryContext(ctx, "SELECT DISTINCT user_id FROM user_unavailability WHERE start_time < $2 AND end_time > $1",
+		startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	unavailable := make(map[int]bool)
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		unavailable[userID] = true
+	}
+	return unavailable, nil
+}
+
+// claimCoverageGapAlert records the alert for the gap unless it is already
+// recorded, and reports whether it was. A gap seen again is kept on record
+// until its latest known end, as gaps reaching the end of the window checked
+// grow with every check. Alerts for gaps that are over are cleared first.
+// Like a rotation, the claim is only made while fencingToken is the
+// leader's (see lockFencingToken).
+func claimCoverageGapAlert(ctx context.Context, gap CoverageGap, fencingToken int64) (bool, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	
+	if err := lockFencingToken(ctx, tx, fencingToken); err != nil {
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM coverage_gap_alerts WHERE end_time < now()"); err != nil {
+		return false, err
+	}
+	// xmax is only zero for a row this statement inserted
+	var inserted bool
+	err = tx.QueryRowContext(ctx, `INSERT INTO coverage_gap_alerts (schedule_id, start_time, end_time) VALUES ($1, $2, $3)
+		ON CONFLICT (schedule_id, start_time) DO UPDATE SET end_time = GREATEST(coverage_gap_alerts.end_time, EXCLUDED.end_time)
+		RETURNING xmax = 0`, gap.ScheduleID, gap.alertStart(), gap.EndTime).Scan(&inserted)
+	if err != nil {
+		return false, err
+	}
+	return inserted, tx.Commit()
+}
+
+// getRotationInputsGeneration returns the count of changes to the tables
+// rotations are replayed from, bumped by their triggers.
+func getRotationInputsGeneration(ctx context.Context) (int64, error) {
+	var generation int64
+	err := db.QueryRowContext(ctx, "SELECT generation FROM rotation_input_changes WHERE id = 1").Scan(&generation)
+	return generation, err
+}
+
+// Holiday functions
+func createHoliday(ctx context.Context, teamID int, date time.Time, name string) (int, error) {
+	return insertHoliday(ctx, db, teamID, date, name)
+}
+
+// importHolidays adds the holidays to the team's calendar, renaming those
+// already on it, in one transaction: either all of them are saved or none.
+func importHolidays(ctx context.Context, teamID int, holidays []Holiday) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	
+	for _, holiday := range holidays {
+		if _, err := insertHoliday(ctx, tx, teamID, holiday.Date, holiday.Name); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func insertHoliday(ctx context.Context, q sqlExecutor, teamID int, date time.Time, name string) (int, error) {
+	var id int
+	err := q.QueryRowContext(ctx, `
+		INSERT INTO holidays (team_id, holiday_date, name) VALUES ($1, $2, $3)
+		ON CONFLICT (team_id, holiday_date) DO UPDATE SET name = EXCLUDED.name
+		RETURNING id`, teamID, date.Format("2006-01-02"), name).Scan(&id)
+	return id, err
+}
+
+func getHolidaysByTeamID(ctx context.Context, teamID int) ([]Holiday, error) {
+	rows, err := db.QueryContext(ctx, "SELECT id, team_id, holiday_date, name, created_at FROM holidays WHERE team_id = $1 ORDER BY holiday_date", teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanHolidays(rows)
+}
+
+// getHolidaysBetween returns the team's holidays whose day could overlap
+// [from, to) in any timezone: those within a day either side of its UTC dates.
+func getHolidaysBetween(ctx context.Context, teamID int, from, to time.Time) ([]Holiday, error) {
+	rows, err := db.QueryContext(ctx, "SELECT id, team_id, holiday_date, name, created_at FROM holidays WHERE team_id = $1 AND holiday_date >= $2::date AND holiday_date <= $3::date ORDER BY holiday_date",
+		teamID, from.UTC().AddDate(0, 0, -1).Format("2006-01-02"), to.UTC().AddDate(0, 0, 1).Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanHolidays(rows)
+}
+
+func scanHolidays(rows *sql.Rows) ([]Holiday, error) {
+	holidays := []Holiday{}
+	for rows.Next() {
+		var holiday Holiday
+		err := rows.Scan(&holiday.ID, &holiday.TeamID, &holiday.Date, &holiday.Name, &holiday.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		holidays = append(holidays, holiday)
+	}
+	return holidays, nil
+}
+
+// deleteHoliday deletes the team's holiday, or returns sql.ErrNoRows if the
+// team has no such holiday.
+func deleteHoliday(ctx context.Context, teamID, holidayID int) error {
+	return requireRow(db.ExecContext(ctx, "DELETE FROM holidays WHERE id = $1 AND team_id = $2", holidayID, teamID))
+}
+
+// Leader lease functions
+func getLeaderLease(ctx context.Context, name string) (*LeaderLease, error) {
+	var lease LeaderLease
+	err := db.QueryRowContext(ctx, "SELECT name, holder, fencing_token, acquired_at, renewed_at FROM leader_leases WHERE name = $1", name).
+		Scan(&lease.Name, &lease.Holder, &lease.FencingToken, &lease.AcquiredAt, &lease.RenewedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &lease, nil
+}
+
+// Report functions
+
+// getOnCallHoursReport sums assignment hours per user within [from, to),
+// keeping primary and shadow hours apart.
+func getOnCallHoursReport(ctx context.Context, from, to time.Time) ([]OnCallHoursReport, error) {
+	query := `
+		SELECT u.id, u.email,
+			COALESCE(SUM(EXTRACT(EPOCH FROM (LEAST(a.end_time, $2) - GREATEST(a.start_time, $1)))) FILTER (WHERE a.role = 'primary'), 0) / 3600,
+			COALESCE(SUM(EXTRACT(EPOCH FROM (LEAST(a.end_time, $2) - GREATEST(a.start_time, $1)))) FILTER (WHERE a.role = 'shadow'), 0) / 3600,
+			COALESCE(SUM(EXTRACT(EPOCH FROM (LEAST(a.end_time, $2) - GREATEST(a.start_time, $1)))) FILTER (WHERE a.role = 'primary' AND a.holiday), 0) / 3600
+		FROM oncall_assignments a
+		INNER JOIN users u ON u.id = a.user_id
+		WHERE a.start_time < $2 AND a.end_time > $1
+		GROUP BY u.id, u.email
+		ORDER BY u.id`
+	rows, err := db.QueryContext(ctx, query, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var report []OnCallHoursReport
+	for rows.Next() {
+		var entry OnCallHoursReport
+		err := rows.Scan(&entry.UserID, &entry.Email, &entry.PrimaryHours, &entry.ShadowHours, &entry.HolidayHours)
+		if err != nil {
+			return nil, err
+		}
+		report = append(report, entry)
+	}
+	return report, nil
 }
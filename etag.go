@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Users, teams and schedules carry a revision that every edit bumps. It is
+// sent as the ETag of the resource, and edits must send it back in
+// If-Match: an edit based on a revision that has since changed fails with
+// 412 instead of overwriting someone else's change.
+
+// etag formats a revision as a strong entity tag.
+func etag(revision int) string {
+	return strconv.Quote(strconv.Itoa(revision))
+}
+
+func setETag(w http.ResponseWriter, revision int) {
+	w.Header().Set("ETag", etag(revision))
+}
+
+// checkIfMatch checks the request's If-Match header against the revision of
+// the resource it edits. Without the header the request is refused with 428
+// if required is set and allowed otherwise.
+func checkIfMatch(r *http.Request, revision int, required bool) error {
+	header := r.Header.Values("If-Match")
+	if len(header) == 0 {
+		if required {
+			return &APIError{Status: http.StatusPreconditionRequired, Code: ErrCodePreconditionRequired,
+				Message: "Send the ETag you read in If-Match, so concurrent edits cannot overwrite each other"}
+		}
+		return nil
+	}
+
+	current := etag(revision)
+	for _, value := range header {
+		for _, tag := range strings.Split(value, ",") {
+			// Weak tags never match, as If-Match compares strongly
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || tag == current {
+				return nil
+			}
+		}
+	}
+	return preconditionFailedError()
+}
+
+// preconditionFailedError answers an edit based on a revision that is no
+// longer current.
+func preconditionFailedError() *APIError {
+	return &APIError{Status: http.StatusPreconditionFailed, Code: ErrCodePreconditionFailed,
+		Message: "It was changed since you read it; reload it and try again"}
+}
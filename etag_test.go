@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckIfMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		ifMatch  []string
+		required bool
+		status   int // 0 for allowed
+	}{
+		{name: "current", ifMatch: []string{`"3"`}, required: true},
+		{name: "one of several", ifMatch: []string{`"1", "3"`}, required: true},
+		{name: "several headers", ifMatch: []string{`"1"`, `"3"`}, required: true},
+		{name: "any", ifMatch: []string{"*"}, required: true},
+		{name: "stale", ifMatch: []string{`"2"`}, required: true, status: http.StatusPreconditionFailed},
+		{name: "weak", ifMatch: []string{`W/"3"`}, status: http.StatusPreconditionFailed},
+		{name: "unquoted", ifMatch: []string{"3"}, status: http.StatusPreconditionFailed},
+		{name: "missing", required: true, status: http.StatusPreconditionRequired},
+		{name: "optional"},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("PUT", "/api/v1/schedules/1", nil)
+		for _, value := range test.ifMatch {
+			r.Header.Add("If-Match", value)
+		}
+
+		err := checkIfMatch(r, 3, test.required)
+		var apiErr *APIError
+		switch {
+		case test.status == 0 && err != nil:
+			t.Errorf("%s: error = %v, want none", test.name, err)
+		case test.status != 0 && (!errors.As(err, &apiErr) || apiErr.Status != test.status):
+			t.Errorf("%s: error = %#v, want status %d", test.name, err, test.status)
+		}
+	}
+}
+
+func TestETagRoundTrip(t *testing.T) {
+	w := httptest.NewRecorder()
+	setETag(w, 12)
+
+	r := httptest.NewRequest("PATCH", "/api/v1/users/1", nil)
+	r.Header.Set("If-Match", w.Header().Get("ETag"))
+	if err := checkIfMatch(r, 12, true); err != nil {
+		t.Errorf("checkIfMatch() with the ETag sent = %v", err)
+	}
+	if err := checkIfMatch(r, 13, true); err == nil {
+		t.Error("checkIfMatch() after another edit succeeded")
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// Weights applied to on-call hours when balancing load. A weekend hour
+// counts for more than a weekday hour, and a holiday hour for more still.
+const (
+	fairnessWeekdayWeight = 1.0
+	fairnessWeekendWeight = 1.5
+	fairnessHolidayWeight = 2.0
+	fairnessWindowDays    = 90
+)
+
+// fairnessLoads computes each participant's weighted on-call load over the
+// last fairnessWindowDays before the shift.
+//
+// The load is counted over the rotation's own shifts, as projectSchedule
+// replays them, but each hour goes to whoever the oncall_assignments table
+// recorded as serving it: a fallback user who covered a pause, or someone
+// who served a shift later marked as PTO, is charged for it rather than the
+// person the replay now picks. Hours with nothing recorded, such as those
+// still to come in a projection, go to the replayed pick. Load is hours
+// alone: pages are not tracked by the service, so they cannot be counted.
+//
+// Participants who first went on call partway through the window (or not
+// yet at all) are credited with the average load for the time before they
+// joined, so a newcomer takes a fair share instead of every shift until they
+// catch up with the veterans.
+func fairnessLoads(input RotationInput) map[int]float64 {
+	now := input.ShiftStart
+	since := now.AddDate(0, 0, -fairnessWindowDays)
+	location := scheduleLocation(input.Schedule.ScheduleDefinition)
+	loads := make(map[int]float64)
+	
+	// Recorded assignments are in start order and never overlap
+	recorded := input.Recorded[sort.Search(len(input.Recorded), func(i int) bool {
+		return input.Recorded[i].EndTime.After(since)
+	}):]
+	for _, assignment := range input.History {
+		start := assignment.StartTime
+		if start.Before(since) {
+			start = since
+		}
+		end := assignment.EndTime
+		if end.After(now) {
+			end = now
+		}
+		for _, served := range servedDuring(assignment.UserID, recorded, start, end) {
+			loads[served.UserID] += shiftLoad(served.StartTime, served.EndTime, input.Holidays, location)
+		}
+	}
+	
+	var veteranLoad float64
+	var veterans int
+	for _, participant := range input.Participants {
+		if joined, ok := input.FirstSeen[participant]; ok && !joined.After(since) {
+			veteranLoad += loads[participant]
+			veterans++
+		}
+	}
+	if veterans == 0 {
+		return loads
+	}
+	
+	averageLoad := veteranLoad / float64(veterans)
+	window := now.Sub(since)
+	for _, participant := range input.Participants {
+		joined, ok := input.FirstSeen[participant]
+		if !ok || joined.After(now) {
+			joined = now
+		}
+		if joined.After(since) {
+			loads[participant] += averageLoad * float64(joined.Sub(since)) / float64(window)
+		}
+	}
+	return loads
+}
+
+// servedDuring splits [start, end) among the recorded assignments covering
+// it, giving the parts nobody was recorded for to replayed.
+func servedDuring(replayed int, recorded []OnCallAssignment, start, end time.Time) []OnCallAssignment {
+	var served []OnCallAssignment
+	cursor := start
+	for _, assignment := range recorded {
+		if !assignment.StartTime.Before(end) {
+			break
+		}
+		if !assignment.EndTime.After(cursor) {
+			continue
+		}
+		if assignment.StartTime.After(cursor) {
+			served = append(served, OnCallAssignment{UserID: replayed, StartTime: cursor, EndTime: assignment.StartTime})
+			cursor = assignment.StartTime
+		}
+		segmentEnd := assignment.EndTime
+		if segmentEnd.After(end) {
+			segmentEnd = end
+		}
+		served = append(served, OnCallAssignment{UserID: assignment.UserID, StartTime: cursor, EndTime: segmentEnd})
+		cursor = segmentEnd
+	}
+	if cursor.Before(end) {
+		served = append(served, OnCallAssignment{UserID: replayed, StartTime: cursor, EndTime: end})
+	}
+	return served
+}
+
+// shiftLoad weighs the hours between start and end, splitting them at day
+// boundaries in location so weekend and holiday hours get their own weight.
+func shiftLoad(start, end time.Time, holidays []Holiday, location *time.Location) float64 {
+	var load float64
+	for cursor := start; cursor.Before(end); {
+		day := cursor.In(location)
+		dayEnd := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, location).AddDate(0, 0, 1)
+		segmentEnd := end
+		if dayEnd.Before(segmentEnd) {
+			segmentEnd = dayEnd
+		}
+		
+		weight := fairnessWeekdayWeight
+		if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+			weight = fairnessWeekendWeight
+		}
+		if holidayForShift(holidays, location, cursor, segmentEnd) != nil {
+			weight = fairnessHolidayWeight
+		}
+		
+		load += segmentEnd.Sub(cursor).Hours() * weight
+		cursor = segmentEnd
+	}
+	return load
+}
@@ -1,648 +1,52 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
-	"html/template"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
-func homeHandler(w http.ResponseWriter, r *http.Request) {
-	tmpl := `
-<!DOCTYPE html>
-<html>
-<head>
-    <title>OnCall Scheduler</title>
-    <style>
-        * { margin: 0; padding: 0; box-sizing: border-box; }
-        body { 
-            font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif;
-            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
-            min-height: 100vh;
-            color: #333;
-        }
-        .container { max-width: 1200px; margin: 0 auto; padding: 20px; }
-        
-        .header {
-            text-align: center;
-            color: white;
-            margin-bottom: 40px;
-            padding: 40px 0;
-        }
-        .header h1 { font-size: 3rem; margin-bottom: 10px; }
-        .header p { font-size: 1.2rem; opacity: 0.9; }
-        
-        .nav-grid {
-            display: grid;
-            grid-template-columns: repeat(auto-fit, minmax(300px, 1fr));
-            gap: 20px;
-            margin-bottom: 40px;
-        }
-        
-        .nav-card {
-            background: rgba(255, 255, 255, 0.95);
-            border-radius: 12px;
-            padding: 25px;
-            text-decoration: none;
-            color: #333;
-            transition: all 0.3s ease;
-            border: 2px solid transparent;
-            backdrop-filter: blur(10px);
-        }
-        
-        .nav-card:hover {
-            transform: translateY(-5px);
-            box-shadow: 0 10px 30px rgba(0, 0, 0, 0.2);
-            border-color: #667eea;
-            color: #333;
-            text-decoration: none;
-        }
-        
-        .nav-card h3 {
-            font-size: 1.4rem;
-            margin-bottom: 10px;
-            color: #667eea;
-        }
-        
-        .nav-card p {
-            color: #666;
-            line-height: 1.5;
-        }
-        
-        .section {
-            background: rgba(255, 255, 255, 0.95);
-            border-radius: 12px;
-            padding: 30px;
-            margin-bottom: 30px;
-            backdrop-filter: blur(10px);
-            display: none;
-        }
-        
-        .section.active { display: block; }
-        
-        .form-group { margin-bottom: 20px; }
-        
-        label {
-            display: block;
-            margin-bottom: 8px;
-            font-weight: 600;
-            color: #555;
-        }
-        
-        input, textarea, select {
-            width: 100%;
-            padding: 12px 16px;
-            border: 2px solid #e1e5e9;
-            border-radius: 8px;
-            font-size: 14px;
-            transition: border-color 0.3s ease;
-            background: white;
-        }
-        
-        input:focus, textarea:focus, select:focus {
-            outline: none;
-            border-color: #667eea;
-            box-shadow: 0 0 0 3px rgba(102, 126, 234, 0.1);
-        }
-        
-        button {
-            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
-            color: white;
-            padding: 12px 24px;
-            border: none;
-            border-radius: 8px;
-            font-size: 16px;
-            font-weight: 600;
-            cursor: pointer;
-            transition: all 0.3s ease;
-        }
-        
-        button:hover {
-            transform: translateY(-2px);
-            box-shadow: 0 5px 15px rgba(102, 126, 234, 0.4);
-        }
-        
-        .back-btn {
-            background: #6c757d;
-            margin-bottom: 20px;
-            padding: 8px 16px;
-            font-size: 14px;
-        }
-        
-        .back-btn:hover {
-            background: #5a6268;
-        }
-        
-        .item-card {
-            background: #f8f9fa;
-            border-radius: 8px;
-            padding: 20px;
-            margin-bottom: 15px;
-            border-left: 4px solid #667eea;
-        }
-        
-        .item-card h4 {
-            color: #667eea;
-            margin-bottom: 10px;
-        }
-        
-        .item-card p {
-            margin-bottom: 5px;
-            color: #666;
-        }
-        
-        .emoji { font-size: 1.5rem; margin-right: 10px; }
-        
-        /* Toast notification styles */
-        .toast-container {
-            position: fixed;
-            top: 20px;
-            right: 20px;
-            z-index: 1000;
-            display: flex;
-            flex-direction: column;
-            gap: 10px;
-        }
-        
-        .toast {
-            background: rgba(255, 255, 255, 0.95);
-            border-radius: 8px;
-            padding: 16px 20px;
-            box-shadow: 0 4px 20px rgba(0, 0, 0, 0.15);
-            backdrop-filter: blur(10px);
-            border-left: 4px solid;
-            min-width: 300px;
-            transform: translateX(100%);
-            opacity: 0;
-            transition: all 0.3s ease;
-        }
-        
-        .toast.show {
-            transform: translateX(0);
-            opacity: 1;
-        }
-        
-        .toast.success {
-            border-left-color: #28a745;
-        }
-        
-        .toast.error {
-            border-left-color: #dc3545;
-        }
-        
-        .toast-header {
-            font-weight: 600;
-            margin-bottom: 4px;
-            display: flex;
-            align-items: center;
-            gap: 8px;
-        }
-        
-        .toast-message {
-            color: #666;
-            font-size: 14px;
-        }
-        
-        .toast-close {
-            position: absolute;
-            top: 8px;
-            right: 12px;
-            background: none;
-            border: none;
-            font-size: 18px;
-            cursor: pointer;
-            color: #999;
-            padding: 0;
-            width: 20px;
-            height: 20px;
-            display: flex;
-            align-items: center;
-            justify-content: center;
-        }
-        
-        .toast-close:hover {
-            color: #666;
-        }
-    </style>
-</head>
-<body>
-    <!-- Toast Container -->
-    <div class="toast-container" id="toast-container"></div>
-    
-    <div class="container">
-        <div class="header">
-            <h1>🚨 OnCall Scheduler</h1>
-            <p>Manage your team's on-call rotations with ease</p>
-        </div>
-
-        <!-- Navigation Grid -->
-        <div id="nav-section">
-            <div class="nav-grid">
-                <a href="#" class="nav-card" onclick="showSection('create-user')">
-                    <h3><span class="emoji">👤</span>Create User</h3>
-                    <p>Add new team members with email and Slack details</p>
-                </a>
-                
-                <a href="#" class="nav-card" onclick="showSection('create-team')">
-                    <h3><span class="emoji">👥</span>Create Team</h3>
-                    <p>Set up new teams for organizing your on-call rotations</p>
-                </a>
-                
-                <a href="#" class="nav-card" onclick="showSection('create-schedule')">
-                    <h3><span class="emoji">📅</span>Create Schedule</h3>
-                    <p>Design on-call schedules with rotation periods</p>
-                </a>
-                
-                <a href="#" class="nav-card" onclick="showSection('view-users')">
-                    <h3><span class="emoji">📋</span>View Users</h3>
-                    <p>Browse all registered users and their details</p>
-                </a>
-                
-                <a href="#" class="nav-card" onclick="showSection('view-teams')">
-                    <h3><span class="emoji">🏢</span>View Teams</h3>
-                    <p>See all teams and their members</p>
-                </a>
-                
-                <a href="#" class="nav-card" onclick="showSection('view-schedules')">
-                    <h3><span class="emoji">⏰</span>View Schedules</h3>
-                    <p>Monitor active schedules and rotations</p>
-                </a>
-            </div>
-        </div>
-
-        <!-- Create User Section -->
-        <div id="create-user" class="section">
-            <button class="back-btn" onclick="showNav()">← Back to Menu</button>
-            <h2>👤 Create User</h2>
-            <form id="userForm">
-                <div class="form-group">
-                    <label for="userEmail">Email Address:</label>
-                    <input type="email" id="userEmail" name="userEmail" placeholder="john.doe@company.com" required>
-                </div>
-                <div class="form-group">
-                    <label for="userSlackHandle">Slack Handle:</label>
-                    <input type="text" id="userSlackHandle" name="userSlackHandle" placeholder="@johndoe" required>
-                </div>
-                <div class="form-group">
-                    <label for="userTeamId">Team ID:</label>
-                    <input type="number" id="userTeamId" name="userTeamId" placeholder="1" required>
-                </div>
-                <button type="submit">Create User</button>
-            </form>
-        </div>
-
-        <!-- Create Team Section -->
-        <div id="create-team" class="section">
-            <button class="back-btn" onclick="showNav()">← Back to Menu</button>
-            <h2>👥 Create Team</h2>
-            <form id="teamForm">
-                <div class="form-group">
-                    <label for="teamName">Team Name:</label>
-                    <input type="text" id="teamName" name="teamName" placeholder="Backend Engineering" required>
-                </div>
-                <button type="submit">Create Team</button>
-            </form>
-        </div>
-
-        <!-- Create Schedule Section -->
-        <div id="create-schedule" class="section">
-            <button class="back-btn" onclick="showNav()">← Back to Menu</button>
-            <h2>📅 Create Schedule</h2>
-            <form id="scheduleForm">
-                <div class="form-group">
-                    <label for="scheduleName">Schedule Name:</label>
-                    <input type="text" id="scheduleName" name="scheduleName" placeholder="Weekend On-Call Rotation" required>
-                </div>
-                <div class="form-group">
-                    <label for="teamId">Team ID:</label>
-                    <input type="number" id="teamId" name="teamId" placeholder="1" required>
-                </div>
-                <div class="form-group">
-                    <label for="startTime">Start Time:</label>
-                    <input type="datetime-local" id="startTime" name="startTime" required>
-                </div>
-                <div class="form-group">
-                    <label for="endTime">End Time:</label>
-                    <input type="datetime-local" id="endTime" name="endTime" required>
-                </div>
-                <div class="form-group">
-                    <label for="rotationPeriod">Rotation Period:</label>
-                    <div style="display: flex; gap: 10px; align-items: center;">
-                        <div style="flex: 1;">
-                            <label for="rotationDays" style="font-size: 12px; margin-bottom: 2px;">Days</label>
-                            <input type="number" id="rotationDays" name="rotationDays" min="0" max="365" placeholder="0" style="width: 100%;">
-                        </div>
-                        <div style="flex: 1;">
-                            <label for="rotationHours" style="font-size: 12px; margin-bottom: 2px;">Hours</label>
-                            <input type="number" id="rotationHours" name="rotationHours" min="0" max="23" placeholder="1" style="width: 100%;">
-                        </div>
-                        <div style="flex: 1;">
-                            <label for="rotationMinutes" style="font-size: 12px; margin-bottom: 2px;">Minutes</label>
-                            <input type="number" id="rotationMinutes" name="rotationMinutes" min="0" max="59" placeholder="0" style="width: 100%;">
-                        </div>
-                        <div style="flex: 1;">
-                            <label for="rotationSeconds" style="font-size: 12px; margin-bottom: 2px;">Seconds</label>
-                            <input type="number" id="rotationSeconds" name="rotationSeconds" min="0" max="59" placeholder="0" style="width: 100%;">
-                        </div>
-                    </div>
-                    <small style="color: #666; font-size: 12px; margin-top: 5px; display: block;">
-                        Minimum rotation period is 1 second. Common examples: 1 day = 1d 0h 0m 0s, 8 hours = 0d 8h 0m 0s
-                    </small>
-                </div>
-                <div class="form-group">
-                    <label for="participants">Participants (comma-separated user IDs):</label>
-                    <textarea id="participants" name="participants" rows="3" placeholder="1,2,3" required></textarea>
-                </div>
-                <button type="submit">Create Schedule</button>
-            </form>
-        </div>
-
-        <!-- View Users Section -->
-        <div id="view-users" class="section">
-            <button class="back-btn" onclick="showNav()">← Back to Menu</button>
-            <h2>📋 All Users</h2>
-            <div id="usersList"></div>
-        </div>
-
-        <!-- View Teams Section -->
-        <div id="view-teams" class="section">
-            <button class="back-btn" onclick="showNav()">← Back to Menu</button>
-            <h2>🏢 All Teams</h2>
-            <div id="teamsList"></div>
-        </div>
-
-        <!-- View Schedules Section -->
-        <div id="view-schedules" class="section">
-            <button class="back-btn" onclick="showNav()">← Back to Menu</button>
-            <h2>⏰ All Schedules</h2>
-            <div id="schedulesList"></div>
-        </div>
-    </div>
-
-    <script>
-        // Toast notification system
-        function showToast(type, title, message, duration = 4000) {
-            const container = document.getElementById('toast-container');
-            const toast = document.createElement('div');
-            toast.className = 'toast ' + type;
-            
-            toast.innerHTML = 
-                '<button class="toast-close" onclick="removeToast(this.parentElement)">&times;</button>' +
-                '<div class="toast-header">' +
-                    (type === 'success' ? '✅' : '❌') + ' ' + title +
-                '</div>' +
-                '<div class="toast-message">' + message + '</div>';
-            
-            container.appendChild(toast);
-            
-            // Trigger animation
-            setTimeout(function() { toast.classList.add('show'); }, 100);
-            
-            // Auto remove
-            setTimeout(function() { removeToast(toast); }, duration);
-        }
-        
-        function removeToast(toast) {
-            toast.classList.remove('show');
-            setTimeout(function() {
-                if (toast.parentElement) {
-                    toast.parentElement.removeChild(toast);
-                }
-            }, 300);
-        }
-        
-        // Utility function to format duration from seconds
-        function formatDuration(totalSeconds) {
-            const days = Math.floor(totalSeconds / (24 * 60 * 60));
-            const hours = Math.floor((totalSeconds % (24 * 60 * 60)) / (60 * 60));
-            const minutes = Math.floor((totalSeconds % (60 * 60)) / 60);
-            const seconds = totalSeconds % 60;
-            
-            const parts = [];
-            if (days > 0) parts.push(days + 'd');
-            if (hours > 0) parts.push(hours + 'h');
-            if (minutes > 0) parts.push(minutes + 'm');
-            if (seconds > 0 || parts.length === 0) parts.push(seconds + 's');
-            
-            return parts.join(' ');
-        }
-        
-        // Navigation functions
-        function showSection(sectionId) {
-            // Hide navigation
-            document.getElementById('nav-section').style.display = 'none';
-            
-            // Hide all sections
-            const sections = document.querySelectorAll('.section');
-            sections.forEach(section => section.classList.remove('active'));
-            
-            // Show selected section
-            const targetSection = document.getElementById(sectionId);
-            if (targetSection) {
-                targetSection.classList.add('active');
-                
-                // Load data for view sections
-                if (sectionId === 'view-users') loadUsers();
-                if (sectionId === 'view-teams') loadTeams();
-                if (sectionId === 'view-schedules') loadSchedules();
-            }
-        }
-        
-        function showNav() {
-            // Show navigation
-            document.getElementById('nav-section').style.display = 'block';
-            
-            // Hide all sections
-            const sections = document.querySelectorAll('.section');
-            sections.forEach(section => section.classList.remove('active'));
-        }
-        
-        // Form handlers
-        document.getElementById('userForm').addEventListener('submit', function(e) {
-            e.preventDefault();
-            const formData = new FormData(this);
-            
-            fetch('/users', {
-                method: 'POST',
-                headers: {'Content-Type': 'application/json'},
-                body: JSON.stringify({
-                    email: formData.get('userEmail'),
-                    slack_handle: formData.get('userSlackHandle'),
-                    team_id: parseInt(formData.get('userTeamId'))
-                })
-            })
-            .then(response => response.json())
-            .then(data => {
-                showToast('success', 'User Created', 'User has been successfully added to the system!');
-                this.reset();
-            })
-            .catch(error => {
-                console.error('Error:', error);
-                showToast('error', 'Creation Failed', 'Failed to create user. Please try again.');
-            });
-        });
-
-        document.getElementById('teamForm').addEventListener('submit', function(e) {
-            e.preventDefault();
-            const formData = new FormData(this);
-            
-            fetch('/teams', {
-                method: 'POST',
-                headers: {'Content-Type': 'application/json'},
-                body: JSON.stringify({
-                    name: formData.get('teamName')
-                })
-            })
-            .then(response => response.json())
-            .then(data => {
-                showToast('success', 'Team Created', 'Team has been successfully created!');
-                this.reset();
-            })
-            .catch(error => {
-                console.error('Error:', error);
-                showToast('error', 'Creation Failed', 'Failed to create team. Please try again.');
-            });
-        });
-
-        document.getElementById('scheduleForm').addEventListener('submit', function(e) {
-            e.preventDefault();
-            const formData = new FormData(this);
-            const participants = formData.get('participants').split(',').map(p => parseInt(p.trim()));
-            
-            // Calculate total rotation period in seconds
-            const days = parseInt(formData.get('rotationDays') || 0);
-            const hours = parseInt(formData.get('rotationHours') || 0);
-            const minutes = parseInt(formData.get('rotationMinutes') || 0);
-            const seconds = parseInt(formData.get('rotationSeconds') || 0);
-            
-            const totalSeconds = (days * 24 * 60 * 60) + (hours * 60 * 60) + (minutes * 60) + seconds;
-            
-            if (totalSeconds < 1) {
-                showToast('error', 'Invalid Rotation Period', 'Rotation period must be at least 1 second.');
-                return;
-            }
-            
-            fetch('/schedules', {
-                method: 'POST',
-                headers: {'Content-Type': 'application/json'},
-                body: JSON.stringify({
-                    team_id: parseInt(formData.get('teamId')),
-                    name: formData.get('scheduleName'),
-                    start_time: formData.get('startTime'),
-                    end_time: formData.get('endTime'),
-                    rotation_period: totalSeconds,
-                    participants: participants
-                })
-            })
-            .then(response => response.json())
-            .then(data => {
-                showToast('success', 'Schedule Created', 'On-call schedule has been successfully created!');
-                this.reset();
-            })
-            .catch(error => {
-                console.error('Error:', error);
-                showToast('error', 'Creation Failed', 'Failed to create schedule. Please check your inputs and try again.');
-            });
-        });
-
-        function loadUsers() {
-            fetch('/users')
-                .then(response => response.json())
-                .then(users => {
-                    const usersList = document.getElementById('usersList');
-                    if (users.length === 0) {
-                        usersList.innerHTML = '<p>No users found. <a href="#" onclick="showSection(\'create-user\')">Create your first user</a></p>';
-                    } else {
-                        usersList.innerHTML = users.map(user => 
-                            '<div class="item-card">' +
-                            '<h4>👤 ' + user.email + ' (ID: ' + user.id + ')</h4>' +
-                            '<p><strong>Slack:</strong> ' + user.slack_handle + '</p>' +
-                            '<p><strong>Team ID:</strong> ' + user.team_id + '</p>' +
-                            '<p><strong>Created:</strong> ' + new Date(user.created_at).toLocaleString() + '</p>' +
-                            '</div>'
-                        ).join('');
-                    }
-                });
-        }
-
-        function loadTeams() {
-            fetch('/teams')
-                .then(response => {
-                    if (!response.ok) {
-                        throw new Error('HTTP ' + response.status + ': ' + response.statusText);
-                    }
-                    return response.json();
-                })
-                .then(teams => {
-                    console.log('Teams loaded:', teams);
-                    const teamsList = document.getElementById('teamsList');
-                    if (!teams || teams.length === 0) {
-                        teamsList.innerHTML = '<p>No teams found. <a href="#" onclick="showSection(\'create-team\')">Create your first team</a></p>';
-                    } else {
-                        teamsList.innerHTML = teams.map(team => 
-                            '<div class="item-card">' +
-                            '<h4>👥 ' + team.name + ' (ID: ' + team.id + ')</h4>' +
-                            '<p><strong>Members:</strong> ' + (team.users && team.users.length > 0 ? team.users.map(u => u.email + ' (' + u.slack_handle + ')').join(', ') : 'No members yet') + '</p>' +
-                            '<p><strong>Created:</strong> ' + new Date(team.created_at).toLocaleString() + '</p>' +
-                            '</div>'
-                        ).join('');
-                    }
-                })
-                .catch(error => {
-                    console.error('Error loading teams:', error);
-                    const teamsList = document.getElementById('teamsList');
-                    teamsList.innerHTML = '<p>Error loading teams: ' + error.message + '</p>';
-                });
-        }
-
-        function loadSchedules() {
-            fetch('/schedules')
-                .then(response => response.json())
-                .then(schedules => {
-                    const schedulesList = document.getElementById('schedulesList');
-                    if (schedules.length === 0) {
-                        schedulesList.innerHTML = '<p>No schedules found. <a href="#" onclick="showSection(\'create-schedule\')">Create your first schedule</a></p>';
-                    } else {
-                        schedulesList.innerHTML = schedules.map(schedule => 
-                            '<div class="item-card">' +
-                            '<h4>📅 ' + schedule.name + ' (Team ID: ' + schedule.team_id + ')</h4>' +
-                            '<p><strong>Start:</strong> ' + new Date(schedule.start_time).toLocaleString() + '</p>' +
-                            '<p><strong>End:</strong> ' + new Date(schedule.end_time).toLocaleString() + '</p>' +
-                            '<p><strong>Rotation:</strong> ' + formatDuration(schedule.rotation_period) + '</p>' +
-                            '<p><strong>Participants (User IDs):</strong> ' + (schedule.participants ? schedule.participants.join(', ') : 'None') + '</p>' +
-                            '</div>'
-                        ).join('');
-                    }
-                });
-        }
-
-        // Initialize the page
-        showNav();
-    </script>
-</body>
-</html>
-	`
-	
-	t := template.Must(template.New("home").Parse(tmpl))
-	t.Execute(w, nil)
+type createUserRequest struct {
+	Email       string `json:"email" openapi:"required,format=email"`
+	SlackHandle string `json:"slack_handle" openapi:"required"`
+	TeamID      int    `json:"team_id"`
 }
 
 func createUserHandler(w http.ResponseWriter, r *http.Request) {
-	var user struct {
-		Email       string `json:"email"`
-		SlackHandle string `json:"slack_handle"`
-		TeamID      int    `json:"team_id"`
+	var user createUserRequest
+	
+	if err := decodeJSON(r, &user); err != nil {
+		writeError(w, r, err)
+		return
 	}
 	
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	var v validator
+	validateUserFields(&v, user.Email, user.SlackHandle)
+	if user.TeamID != 0 {
+		if err := v.merge(checkTeamExists(r.Context(), "team_id", user.TeamID)); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+	if err := v.err(); err != nil {
+		writeError(w, r, err)
 		return
 	}
 	
-	id, err := createUser(user.Email, user.SlackHandle, user.TeamID)
+	id, err := createUser(r.Context(), user.Email, user.SlackHandle, user.TeamID)
+	if isUniqueViolation(err) {
+		writeError(w, r, emailTakenError())
+		return
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 	
@@ -655,30 +59,276 @@ func createUserHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// validateUserFields checks a user's email and Slack handle.
+func validateUserFields(v *validator, email, slackHandle string) {
+	if v.required("email", email) {
+		v.check(strings.Contains(email, "@") && !strings.ContainsAny(email, " \t\n"), "email", FieldInvalidFormat,
+			"Must be an email address")
+	}
+	v.required("slack_handle", slackHandle)
+}
+
+func emailTakenError() *APIError {
+	err := conflictError(ErrCodeEmailTaken, "A user with this email already exists")
+	err.Fields = []FieldError{{Field: "email", Code: FieldDuplicate, Message: "Is already taken"}}
+	return err
+}
+
+// checkTeamExists returns a validation error for field unless the team
+// exists.
+func checkTeamExists(ctx context.Context, field string, teamID int) error {
+	exists, err := teamExists(ctx, teamID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fieldError(field, FieldNotFound, fmt.Sprintf("Team %d does not exist", teamID))
+	}
+	return nil
+}
+
+// checkUserExists returns a validation error for field unless the user
+// exists.
+func checkUserExists(ctx context.Context, field string, userID int) error {
+	_, err := getUserByID(ctx, userID)
+	if err == sql.ErrNoRows {
+		return fieldError(field, FieldNotFound, fmt.Sprintf("User %d does not exist", userID))
+	}
+	return err
+}
+
+// getUsersHandler lists users a page at a time (see pagination.go), sorted
+// by ?sort= (id, email or created_at) and filtered by ?team_id= and ?email=.
+// Deleted users are left out unless ?include_deleted=true.
 func getUsersHandler(w http.ResponseWriter, r *http.Request) {
-	users, err := getUsers()
+	page, err := parsePageRequest(r, userSortFields, "id")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	filter := userFilter{Email: r.URL.Query().Get("email"), IncludeDeleted: r.URL.Query().Get("include_deleted") == "true"}
+	if filter.TeamID, err = queryID(r, "team_id"); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	users, next, err := listUsers(r.Context(), filter, page)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 	
+	setNextPageHeaders(w, r, next)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(users)
 }
 
+func getUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	user, err := getUserByID(r.Context(), userID)
+	if err == sql.ErrNoRows {
+		writeError(w, r, notFoundError("User not found"))
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	setETag(w, user.Revision)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// updateUserHandler replaces the user's email and Slack handle. Team
+// membership is changed through the team's members instead.
+func updateUserHandler(w http.ResponseWriter, r *http.Request) {
+	editUser(w, r, true)
+}
+
+// patchUserHandler changes the fields given in the request and keeps the
+// others.
+func patchUserHandler(w http.ResponseWriter, r *http.Request) {
+	editUser(w, r, false)
+}
+
+type userUpdate struct {
+	Email       *string `json:"email"`
+	SlackHandle *string `json:"slack_handle"`
+}
+
+// editUser saves a PUT (replace) or PATCH request for the user in the path,
+// if If-Match has the user's current ETag.
+func editUser(w http.ResponseWriter, r *http.Request, replace bool) {
+	userID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	var update userUpdate
+	if err := decodeJSON(r, &update); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if replace {
+		var v validator
+		v.check(update.Email != nil, "email", FieldRequired, "Is required")
+		v.check(update.SlackHandle != nil, "slack_handle", FieldRequired, "Is required")
+		if err := v.err(); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+	
+	user, err := getUserByID(r.Context(), userID)
+	if err == sql.ErrNoRows {
+		writeError(w, r, notFoundError("User not found"))
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := checkIfMatch(r, user.Revision, true); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	if update.Email != nil {
+		user.Email = *update.Email
+	}
+	if update.SlackHandle != nil {
+		user.SlackHandle = *update.SlackHandle
+	}
+	var v validator
+	validateUserFields(&v, user.Email, user.SlackHandle)
+	if err := v.err(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	err = updateUser(r.Context(), *user)
+	if err == sql.ErrNoRows {
+		// Edited or deleted since it was read
+		writeError(w, r, preconditionFailedError())
+		return
+	}
+	if isUniqueViolation(err) {
+		writeError(w, r, emailTakenError())
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	user.Revision++
+	
+	setETag(w, user.Revision)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// deleteUserHandler soft-deletes a user, who can be restored until the
+// purge job removes them (see purge.go). Their on-call history stays in
+// reports, but users that a schedule would still put on call (on a current
+// or pending roster, or the fallback of a paused schedule) cannot be
+// deleted until they are taken off it: 409.
+func deleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	scheduleIDs, err := softDeleteUser(r.Context(), userID)
+	if err == sql.ErrNoRows {
+		writeError(w, r, notFoundError("User not found"))
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if len(scheduleIDs) > 0 {
+		writeError(w, r, conflictError(ErrCodeInUse,
+			fmt.Sprintf("User is still on the roster of schedules %s; remove them first", joinIDs(scheduleIDs))))
+		return
+	}
+	
+	response := map[string]interface{}{
+		"id":      userID,
+		"message": "User deleted successfully",
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// restoreUserHandler undoes the deletion of a user. Restoring a user who is
+// not deleted changes nothing.
+func restoreUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	err = restoreDeleted(r.Context(), "users", userID)
+	if isUniqueViolation(err) {
+		// Someone new has been given the address since
+		writeError(w, r, emailTakenError())
+		return
+	}
+	if err != nil && err != sql.ErrNoRows {
+		writeError(w, r, err)
+		return
+	}
+	
+	user, err := getUserByID(r.Context(), userID)
+	if err == sql.ErrNoRows {
+		writeError(w, r, notFoundError("User not found"))
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	setETag(w, user.Revision)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+type createTeamRequest struct {
+	Name         string `json:"name" openapi:"required"`
+	SlackChannel string `json:"slack_channel"`
+}
+
 func createTeamHandler(w http.ResponseWriter, r *http.Request) {
-	var team struct {
-		Name string `json:"name"`
+	var team createTeamRequest
+	
+	if err := decodeJSON(r, &team); err != nil {
+		writeError(w, r, err)
+		return
 	}
 	
-	if err := json.NewDecoder(r.Body).Decode(&team); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	var v validator
+	v.required("name", team.Name)
+	if err := v.err(); err != nil {
+		writeError(w, r, err)
 		return
 	}
 	
-	id, err := createTeam(team.Name)
+	id, err := createTeam(r.Context(), team.Name, team.SlackChannel)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 	
@@ -691,68 +341,1557 @@ func createTeamHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// getTeamsHandler lists teams with their members a page at a time (see
+// pagination.go), sorted by ?sort= (id, name or created_at) and filtered by
+// ?name=. Deleted teams are left out unless ?include_deleted=true.
 func getTeamsHandler(w http.ResponseWriter, r *http.Request) {
-	teams, err := getTeams()
+	page, err := parsePageRequest(r, teamSortFields, "id")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	filter := teamFilter{Name: r.URL.Query().Get("name"), IncludeDeleted: r.URL.Query().Get("include_deleted") == "true"}
+	teams, next, err := listTeams(r.Context(), filter, page)
 	if err != nil {
-		log.Printf("Error getting teams: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 	
 	log.Printf("Retrieved %d teams", len(teams))
+	setNextPageHeaders(w, r, next)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(teams)
 }
 
-func createScheduleHandler(w http.ResponseWriter, r *http.Request) {
-	var schedule struct {
-		TeamID         int      `json:"team_id"`
-		Name           string   `json:"name"`
-		StartTime      string   `json:"start_time"`
-		EndTime        string   `json:"end_time"`
-		RotationPeriod int      `json:"rotation_period"`
-		Participants   []int    `json:"participants"`
+func getTeamHandler(w http.ResponseWriter, r *http.Request) {
+	teamID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	team, err := getTeamByID(r.Context(), teamID)
+	if err == sql.ErrNoRows {
+		writeError(w, r, notFoundError("Team not found"))
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	setETag(w, team.Revision)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(team)
+}
+
+// updateTeamHandler replaces the team's name and Slack channel; leaving out
+// the channel clears it.
+func updateTeamHandler(w http.ResponseWriter, r *http.Request) {
+	editTeam(w, r, true)
+}
+
+// patchTeamHandler changes the fields given in the request and keeps the
+// others.
+func patchTeamHandler(w http.ResponseWriter, r *http.Request) {
+	editTeam(w, r, false)
+}
+
+type teamUpdate struct {
+	Name         *string `json:"name"`
+	SlackChannel *string `json:"slack_channel"`
+}
+
+// editTeam saves a PUT (replace) or PATCH request for the team in the path,
+// if If-Match has the team's current ETag.
+func editTeam(w http.ResponseWriter, r *http.Request, replace bool) {
+	teamID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	var update teamUpdate
+	if err := decodeJSON(r, &update); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if replace && update.Name == nil {
+		writeError(w, r, fieldError("name", FieldRequired, "Is required"))
+		return
+	}
+	
+	team, err := getTeamByID(r.Context(), teamID)
+	if err == sql.ErrNoRows {
+		writeError(w, r, notFoundError("Team not found"))
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := checkIfMatch(r, team.Revision, true); err != nil {
+		writeError(w, r, err)
+		return
 	}
 	
-	if err := json.NewDecoder(r.Body).Decode(&schedule); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if update.Name != nil {
+		team.Name = *update.Name
+	}
+	if update.SlackChannel != nil {
+		team.SlackChannel = *update.SlackChannel
+	} else if replace {
+		team.SlackChannel = ""
+	}
+	var v validator
+	v.required("name", team.Name)
+	if err := v.err(); err != nil {
+		writeError(w, r, err)
 		return
 	}
 	
-	startTime, err := time.Parse("2006-01-02T15:04", schedule.StartTime)
+	err = updateTeam(r.Context(), *team)
+	if err == sql.ErrNoRows {
+		writeError(w, r, preconditionFailedError())
+		return
+	}
 	if err != nil {
-		http.Error(w, "Invalid start time format", http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
+	team.Revision++
 	
-	endTime, err := time.Parse("2006-01-02T15:04", schedule.EndTime)
+	setETag(w, team.Revision)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(team)
+}
+
+// deleteTeamHandler soft-deletes a team, keeping its holidays and
+// memberships for a restore. Teams with schedules that are not deleted,
+// archived ones included, cannot be deleted: 409.
+func deleteTeamHandler(w http.ResponseWriter, r *http.Request) {
+	teamID, err := pathID(r, "id")
 	if err != nil {
-		http.Error(w, "Invalid end time format", http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 	
-	id, err := createSchedule(schedule.TeamID, schedule.Name, startTime, endTime, schedule.RotationPeriod, schedule.Participants)
+	scheduleIDs, err := softDeleteTeam(r.Context(), teamID)
+	if err == sql.ErrNoRows {
+		writeError(w, r, notFoundError("Team not found"))
+		return
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
+		return
+	}
+	if len(scheduleIDs) > 0 {
+		writeError(w, r, conflictError(ErrCodeInUse, fmt.Sprintf("Team has schedules %s and cannot be deleted", joinIDs(scheduleIDs))))
 		return
 	}
 	
 	response := map[string]interface{}{
-		"id":      id,
-		"message": "Schedule created successfully",
+		"id":      teamID,
+		"message": "Team deleted successfully",
 	}
 	
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
-func getSchedulesHandler(w http.ResponseWriter, r *http.Request) {
-	schedules, err := getSchedules()
+// restoreTeamHandler undoes the deletion of a team. Restoring a team that is
+// not deleted changes nothing.
+func restoreTeamHandler(w http.ResponseWriter, r *http.Request) {
+	teamID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	if err := restoreDeleted(r.Context(), "teams", teamID); err != nil && err != sql.ErrNoRows {
+		writeError(w, r, err)
+		return
+	}
+	
+	team, err := getTeamByID(r.Context(), teamID)
+	if err == sql.ErrNoRows {
+		writeError(w, r, notFoundError("Team not found"))
+		return
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 	
+	setETag(w, team.Revision)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(schedules)
-}
\ No newline at end of file
+	json.NewEncoder(w).Encode(team)
+}
+
+type teamMemberRequest struct {
+	UserID int    `json:"user_id" openapi:"required"`
+	Role   string `json:"role"`
+}
+
+// addTeamMemberHandler adds a user to a team, or changes the role of a user
+// who is already a member.
+func addTeamMemberHandler(w http.ResponseWriter, r *http.Request) {
+	teamID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	var member teamMemberRequest
+	if err := decodeJSON(r, &member); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	switch member.Role {
+	case "":
+		member.Role = TeamRoleMember
+	case TeamRoleMember, TeamRoleManager:
+	default:
+		writeError(w, r, fieldError("role", FieldInvalidChoice, "Must be member or manager"))
+		return
+	}
+	
+	if _, err := getTeamByID(r.Context(), teamID); err == sql.ErrNoRows {
+		writeError(w, r, notFoundError("Team not found"))
+		return
+	} else if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	if err := checkUserExists(r.Context(), "user_id", member.UserID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	if err := addUserToTeam(r.Context(), db, teamID, member.UserID, member.Role); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	response := map[string]interface{}{
+		"id":      teamID,
+		"user_id": member.UserID,
+		"role":    member.Role,
+		"message": "Team member saved successfully",
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// removeTeamMemberHandler removes a user from a team. Schedules of the team
+// that still list the user report coverage gaps until they are edited.
+func removeTeamMemberHandler(w http.ResponseWriter, r *http.Request) {
+	teamID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	userID, err := pathID(r, "userID")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	removed, err := removeUserFromTeam(r.Context(), teamID, userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if !removed {
+		writeError(w, r, notFoundError("Team member not found"))
+		return
+	}
+	
+	response := map[string]interface{}{
+		"id":      teamID,
+		"user_id": userID,
+		"message": "Team member removed successfully",
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+type createScheduleRequest struct {
+	TeamID    int    `json:"team_id" openapi:"required"`
+	Name      string `json:"name" openapi:"required"`
+	StartTime string `json:"start_time" openapi:"required,datetime-local"`
+	EndTime   string `json:"end_time" openapi:"required,datetime-local"`
+	ScheduleDefinition
+}
+
+func createScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	var schedule createScheduleRequest
+	
+	if err := decodeJSON(r, &schedule); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	var v validator
+	v.required("name", schedule.Name)
+	startTime := v.timestamp("start_time", schedule.StartTime)
+	endTime := v.timestamp("end_time", schedule.EndTime)
+	if !startTime.IsZero() && !endTime.IsZero() {
+		v.check(endTime.After(startTime), "end_time", FieldOutOfRange, "Must be after the start time")
+	}
+	if err := v.merge(validateScheduleDefinition(&schedule.ScheduleDefinition)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	// Team membership can only be checked once the team is known to exist
+	if v.requiredID("team_id", schedule.TeamID) {
+		err := checkTeamExists(r.Context(), "team_id", schedule.TeamID)
+		if err == nil {
+			err = checkRosterUsers(r.Context(), schedule.TeamID, schedule.Roster, nil)
+		}
+		if err := v.merge(err); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+	if err := v.err(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	id, err := createSchedule(r.Context(), Schedule{
+		TeamID:             schedule.TeamID,
+		Name:               schedule.Name,
+		StartTime:          startTime,
+		EndTime:            endTime,
+		ScheduleDefinition: schedule.ScheduleDefinition,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	response := map[string]interface{}{
+		"id":      id,
+		"message": "Schedule created successfully",
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// validateScheduleDefinition checks a schedule definition from a create or
+// update request and fills in the defaults for omitted options. Every
+// problem is reported as a field of one validation error.
+func validateScheduleDefinition(definition *ScheduleDefinition) error {
+	var v validator
+	v.check(definition.RotationPeriod > 0, "rotation_period", FieldOutOfRange, "Must be a positive number of seconds")
+	
+	if definition.RotationStrategy == "" {
+		definition.RotationStrategy = RotationStrategyRoundRobin
+	}
+	v.check(isValidRotationStrategy(definition.RotationStrategy), "rotation_strategy", FieldInvalidChoice,
+		fmt.Sprintf("Unknown rotation strategy %q", definition.RotationStrategy))
+	
+	if err := v.merge(normalizeRoster(definition)); err != nil {
+		return err
+	}
+	
+	if definition.RotationStrategy == RotationStrategyFixedSequence {
+		v.check(len(definition.Sequence) > 0, "sequence", FieldRequired, "Is required for the fixed sequence strategy")
+		
+		// The sequence can only name people on the roster, whose team
+		// membership is checked
+		onRoster := make(map[int]bool)
+		for _, participant := range definition.Roster {
+			onRoster[participant.UserID] = true
+		}
+		for _, userID := range definition.Sequence {
+			if !onRoster[userID] {
+				v.add("sequence", FieldInvalidChoice, fmt.Sprintf("User %d is not a participant", userID))
+				break
+			}
+		}
+	}
+	
+	switch definition.HolidayMode {
+	case "":
+		definition.HolidayMode = HolidayModeNone
+	case HolidayModeNone, HolidayModeSkip, HolidayModeFlag:
+	case HolidayModeRotation:
+		v.check(definition.HolidayScheduleID != 0, "holiday_schedule_id", FieldRequired,
+			"Is required for holiday rotation mode")
+	default:
+		v.add("holiday_mode", FieldInvalidChoice, fmt.Sprintf("Unknown holiday mode %q", definition.HolidayMode))
+	}
+	
+	if definition.Timezone == "" {
+		definition.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(definition.Timezone); err != nil {
+		v.add("timezone", FieldInvalidChoice, fmt.Sprintf("Unknown timezone %q", definition.Timezone))
+	}
+	return v.err()
+}
+
+type scheduleUpdateRequest struct {
+	EffectiveFrom string `json:"effective_from" openapi:"datetime-local"`
+	ScheduleDefinition
+}
+
+// updateScheduleHandler records a new version of a schedule's definition.
+// The current version stays in effect until effective_from, which defaults
+// to the next handoff, so shifts that have already started are unaffected.
+func updateScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	var update scheduleUpdateRequest
+	
+	if err := decodeJSON(r, &update); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	if err := validateScheduleDefinition(&update.ScheduleDefinition); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	schedule, ok := loadEditableSchedule(w, r)
+	if !ok {
+		return
+	}
+	
+	if err := checkRosterUsers(r.Context(), schedule.TeamID, update.Roster, latestDefinition(schedule).Roster); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	saveScheduleEdit(w, r, schedule, update.ScheduleDefinition, update.EffectiveFrom, "Schedule updated successfully")
+}
+
+func getScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	scheduleID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	schedule, err := getScheduleByID(r.Context(), scheduleID)
+	if err == sql.ErrNoRows {
+		writeError(w, r, notFoundError("Schedule not found"))
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	setETag(w, schedule.Revision)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schedule)
+}
+
+// scheduleDefinitionFields are the JSON fields of a ScheduleDefinition. A
+// PATCH that sets any of them records a new version.
+var scheduleDefinitionFields = []string{"rotation_period", "roster", "participants", "rotation_strategy",
+	"participant_weights", "sequence", "holiday_mode", "holiday_schedule_id", "timezone"}
+
+type schedulePatchRequest struct {
+	Name          *string `json:"name"`
+	EndTime       *string `json:"end_time" openapi:"datetime-local"`
+	EffectiveFrom string  `json:"effective_from" openapi:"datetime-local"`
+}
+
+// patchScheduleHandler changes the fields given in the request. name and
+// end_time apply straight away; definition fields are applied to the latest
+// version and saved as a new one, as with PUT. participants replaces the
+// roster, and participant_weights on its own changes the roster's weights.
+func patchScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		writeError(w, r, jsonError(err))
+		return
+	}
+	var update schedulePatchRequest
+	if err := json.Unmarshal(body, &update); err != nil {
+		writeError(w, r, jsonError(err))
+		return
+	}
+	
+	schedule, ok := loadEditableSchedule(w, r)
+	if !ok {
+		return
+	}
+	
+	var v validator
+	if update.Name != nil && v.required("name", *update.Name) {
+		schedule.Name = *update.Name
+	}
+	if update.EndTime != nil {
+		endTime := v.timestamp("end_time", *update.EndTime)
+		// Ending the schedule in the past would rewrite who was on call
+		if !endTime.IsZero() {
+			v.check(endTime.After(schedule.StartTime) && !endTime.Before(clock.Now()), "end_time", FieldOutOfRange,
+				"Must be after the start time and not in the past")
+			schedule.EndTime = endTime
+		}
+	}
+	if err := v.err(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	var definition *ScheduleDefinition
+	var effectiveFrom time.Time
+	for _, field := range scheduleDefinitionFields {
+		if _, ok := fields[field]; ok {
+			definition = patchedDefinition(schedule)
+			break
+		}
+	}
+	if definition != nil {
+		existing := definition.Roster
+		if err := json.Unmarshal(body, definition); err != nil {
+			writeError(w, r, jsonError(err))
+			return
+		}
+		applyPatchedParticipants(definition, fields)
+		if err := validateScheduleDefinition(definition); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if err := checkRosterUsers(r.Context(), schedule.TeamID, definition.Roster, existing); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		
+		effectiveFrom, err = editEffectiveFrom(schedule, update.EffectiveFrom)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+	
+	version, err := updateSchedule(r.Context(), *schedule, definition, effectiveFrom)
+	if err == sql.ErrNoRows {
+		writeError(w, r, preconditionFailedError())
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	response := map[string]interface{}{
+		"id":       schedule.ID,
+		"revision": schedule.Revision + 1,
+		"message":  "Schedule updated successfully",
+	}
+	if definition != nil {
+		response["version"] = version
+		response["effective_from"] = effectiveFrom
+	}
+	
+	setETag(w, schedule.Revision+1)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// patchedDefinition returns the latest definition to apply a PATCH to. The
+// derived participant fields are cleared so the request's values, if any,
+// can be told apart from the old ones.
+func patchedDefinition(schedule *Schedule) *ScheduleDefinition {
+	definition := latestDefinition(schedule)
+	definition.Participants = nil
+	definition.ParticipantWeights = nil
+	return &definition
+}
+
+// applyPatchedParticipants rebuilds the roster from a PATCH's participants,
+// or applies its participant_weights to the existing roster.
+func applyPatchedParticipants(definition *ScheduleDefinition, fields map[string]json.RawMessage) {
+	if _, ok := fields["roster"]; ok {
+		return
+	}
+	if _, ok := fields["participants"]; ok {
+		definition.Roster = nil
+		return
+	}
+	for i := range definition.Roster {
+		if weight, ok := definition.ParticipantWeights[definition.Roster[i].UserID]; ok {
+			definition.Roster[i].Weight = weight
+		}
+	}
+}
+
+// deleteScheduleHandler soft-deletes a schedule: it stops rotating and is
+// hidden, but its versions and on-call history are kept and it can be
+// restored. Schedules that cover another schedule's holidays cannot be
+// deleted: 409.
+func deleteScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	scheduleID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	dependents, err := softDeleteSchedule(r.Context(), scheduleID)
+	if err == sql.ErrNoRows {
+		writeError(w, r, notFoundError("Schedule not found"))
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if len(dependents) > 0 {
+		writeError(w, r, conflictError(ErrCodeInUse,
+			fmt.Sprintf("Schedule covers the holidays of schedules %s and cannot be deleted", joinIDs(dependents))))
+		return
+	}
+	
+	response := map[string]interface{}{
+		"id":      scheduleID,
+		"message": "Schedule deleted successfully",
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// restoreScheduleHandler undoes the deletion of a schedule, which resumes
+// rotating as it would after downtime. A schedule whose team is deleted
+// cannot be restored until the team is: 409. Restoring a schedule that is
+// not deleted changes nothing.
+func restoreScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	scheduleID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	teamDeleted, err := scheduleTeamDeleted(r.Context(), scheduleID)
+	if err == sql.ErrNoRows {
+		writeError(w, r, notFoundError("Schedule not found"))
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if teamDeleted {
+		writeError(w, r, conflictError(ErrCodeTeamDeleted, "The schedule's team is deleted; restore the team first"))
+		return
+	}
+	
+	if err := restoreDeleted(r.Context(), "schedules", scheduleID); err != nil && err != sql.ErrNoRows {
+		writeError(w, r, err)
+		return
+	}
+	
+	schedule, err := getScheduleByID(r.Context(), scheduleID)
+	if err == sql.ErrNoRows {
+		writeError(w, r, notFoundError("Schedule not found"))
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	setETag(w, schedule.Revision)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schedule)
+}
+
+// loadEditableSchedule loads the schedule in the request path, answering
+// 400, 404, 409 (for archived schedules), or 428 or 412 when If-Match does
+// not have its current ETag, and returning false when it cannot be edited.
+func loadEditableSchedule(w http.ResponseWriter, r *http.Request) (*Schedule, bool) {
+	scheduleID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, err)
+		return nil, false
+	}
+	
+	schedule, err := getScheduleByID(r.Context(), scheduleID)
+	if err == sql.ErrNoRows {
+		writeError(w, r, notFoundError("Schedule not found"))
+		return nil, false
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return nil, false
+	}
+	if err := checkIfMatch(r, schedule.Revision, true); err != nil {
+		writeError(w, r, err)
+		return nil, false
+	}
+	if schedule.Status == ScheduleStatusArchived {
+		writeError(w, r, conflictError(ErrCodeScheduleArchived, "Archived schedules cannot be edited"))
+		return nil, false
+	}
+	return schedule, true
+}
+
+// saveScheduleEdit records definition as a new version of the schedule and
+// writes the response.
+func saveScheduleEdit(w http.ResponseWriter, r *http.Request, schedule *Schedule, definition ScheduleDefinition, requestedEffectiveFrom, message string) {
+	effectiveFrom, err := editEffectiveFrom(schedule, requestedEffectiveFrom)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	version, err := createScheduleVersion(r.Context(), schedule.ID, schedule.Revision, effectiveFrom, definition)
+	if err == sql.ErrNoRows {
+		writeError(w, r, preconditionFailedError())
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	response := map[string]interface{}{
+		"id":             schedule.ID,
+		"revision":       schedule.Revision + 1,
+		"version":        version,
+		"effective_from": effectiveFrom,
+		"message":        message,
+	}
+	
+	setETag(w, schedule.Revision+1)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// editEffectiveFrom returns when an edit of the schedule takes effect, or an
+// error and the status to answer with. requestedEffectiveFrom is a future
+// YYYY-MM-DDTHH:MM; when empty the edit takes effect at the next handoff, or
+// when the latest version does if that is later, so edits stack on pending
+// ones.
+func editEffectiveFrom(schedule *Schedule, requestedEffectiveFrom string) (time.Time, error) {
+	now := clock.Now()
+	latest, hasVersions := latestVersion(*schedule)
+	
+	var effectiveFrom time.Time
+	switch {
+	case requestedEffectiveFrom != "":
+		var err error
+		effectiveFrom, err = time.Parse("2006-01-02T15:04", requestedEffectiveFrom)
+		if err != nil {
+			return time.Time{}, fieldError("effective_from", FieldInvalidFormat, "Must be formatted as YYYY-MM-DDTHH:MM")
+		}
+		if !effectiveFrom.After(now) {
+			return time.Time{}, fieldError("effective_from", FieldOutOfRange, "Must be in the future")
+		}
+	case now.Before(schedule.StartTime):
+		effectiveFrom = schedule.StartTime
+	default:
+		_, effectiveFrom, _ = shiftAt(*schedule, now)
+	}
+	if requestedEffectiveFrom == "" && hasVersions && effectiveFrom.Before(latest.EffectiveFrom) {
+		effectiveFrom = latest.EffectiveFrom
+	}
+	
+	if !effectiveFrom.Before(schedule.EndTime) {
+		return time.Time{}, fieldError("effective_from", FieldOutOfRange, "Must be before the schedule ends")
+	}
+	if hasVersions && effectiveFrom.Before(latest.EffectiveFrom) {
+		return time.Time{}, conflictError(ErrCodeVersionConflict, fmt.Sprintf("Version %d takes effect at %s; a new version cannot take effect before it",
+			latest.Version, latest.EffectiveFrom.Format("2006-01-02T15:04")))
+	}
+	return effectiveFrom, nil
+}
+
+// latestDefinition returns the schedule's newest definition, which roster
+// edits build on even if it has not taken effect yet.
+func latestDefinition(schedule *Schedule) ScheduleDefinition {
+	definition := schedule.ScheduleDefinition
+	if latest, ok := latestVersion(*schedule); ok {
+		definition = latest.ScheduleDefinition
+	}
+	definition.Roster = append([]ScheduleParticipant(nil), definition.Roster...)
+	return definition
+}
+
+type addParticipantRequest struct {
+	UserID        int     `json:"user_id" openapi:"required"`
+	Position      int     `json:"position"`
+	Weight        float64 `json:"weight"`
+	Tier          int     `json:"tier"`
+	Active        *bool   `json:"active"`
+	EffectiveFrom string  `json:"effective_from" openapi:"datetime-local"`
+}
+
+// addScheduleParticipantHandler adds a user to the schedule's roster, at
+// position if given and last otherwise, as a new version.
+func addScheduleParticipantHandler(w http.ResponseWriter, r *http.Request) {
+	var request addParticipantRequest
+	
+	if err := decodeJSON(r, &request); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	schedule, ok := loadEditableSchedule(w, r)
+	if !ok {
+		return
+	}
+	
+	definition := latestDefinition(schedule)
+	if rosterIndex(definition.Roster, request.UserID) != -1 {
+		writeError(w, r, conflictError(ErrCodeAlreadyRostered, fmt.Sprintf("User %d is already a participant", request.UserID)))
+		return
+	}
+	participant := ScheduleParticipant{UserID: request.UserID, Position: request.Position, Weight: request.Weight,
+		Tier: request.Tier, Active: request.Active == nil || *request.Active}
+	definition.Roster = insertIntoRoster(definition.Roster, participant)
+	
+	saveRosterEdit(w, r, schedule, definition, request.EffectiveFrom, "Participant added successfully")
+}
+
+type participantUpdate struct {
+	Position      *int     `json:"position"`
+	Weight        *float64 `json:"weight"`
+	Tier          *int     `json:"tier"`
+	Active        *bool    `json:"active"`
+	EffectiveFrom string   `json:"effective_from" openapi:"datetime-local"`
+}
+
+// updateScheduleParticipantHandler changes a participant's weight, tier,
+// active flag or position, as a new version. Omitted fields are unchanged.
+func updateScheduleParticipantHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := pathID(r, "userID")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	var request participantUpdate
+	
+	if err := decodeJSON(r, &request); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	schedule, ok := loadEditableSchedule(w, r)
+	if !ok {
+		return
+	}
+	
+	definition := latestDefinition(schedule)
+	index := rosterIndex(definition.Roster, userID)
+	if index == -1 {
+		writeError(w, r, notFoundError("Participant not found"))
+		return
+	}
+	
+	participant := definition.Roster[index]
+	if request.Weight != nil {
+		participant.Weight = *request.Weight
+	}
+	if request.Tier != nil {
+		participant.Tier = *request.Tier
+	}
+	if request.Active != nil {
+		participant.Active = *request.Active
+	}
+	definition.Roster = append(definition.Roster[:index], definition.Roster[index+1:]...)
+	if request.Position != nil {
+		participant.Position = *request.Position
+	} else {
+		participant.Position = index + 1
+	}
+	definition.Roster = insertIntoRoster(definition.Roster, participant)
+	
+	saveRosterEdit(w, r, schedule, definition, request.EffectiveFrom, "Participant updated successfully")
+}
+
+// removeScheduleParticipantHandler takes a user off the schedule's roster as
+// a new version; ?effective_from= works as for other edits.
+func removeScheduleParticipantHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := pathID(r, "userID")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	schedule, ok := loadEditableSchedule(w, r)
+	if !ok {
+		return
+	}
+	
+	definition := latestDefinition(schedule)
+	index := rosterIndex(definition.Roster, userID)
+	if index == -1 {
+		writeError(w, r, notFoundError("Participant not found"))
+		return
+	}
+	definition.Roster = append(definition.Roster[:index], definition.Roster[index+1:]...)
+	
+	saveRosterEdit(w, r, schedule, definition, r.URL.Query().Get("effective_from"), "Participant removed successfully")
+}
+
+type reorderParticipantsRequest struct {
+	UserIDs       []int  `json:"user_ids" openapi:"required"`
+	EffectiveFrom string `json:"effective_from" openapi:"datetime-local"`
+}
+
+// reorderScheduleParticipantsHandler sets the rotation order from user_ids,
+// which must list every participant once, as a new version.
+func reorderScheduleParticipantsHandler(w http.ResponseWriter, r *http.Request) {
+	var request reorderParticipantsRequest
+	
+	if err := decodeJSON(r, &request); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	schedule, ok := loadEditableSchedule(w, r)
+	if !ok {
+		return
+	}
+	
+	definition := latestDefinition(schedule)
+	roster, err := reorderRoster(definition.Roster, request.UserIDs)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	definition.Roster = roster
+	
+	saveRosterEdit(w, r, schedule, definition, request.EffectiveFrom, "Participants reordered successfully")
+}
+
+// saveRosterEdit checks an edited roster and records it as a new version.
+func saveRosterEdit(w http.ResponseWriter, r *http.Request, schedule *Schedule, definition ScheduleDefinition, effectiveFrom, message string) {
+	// The roster is authoritative; the derived fields are rebuilt from it
+	definition.Participants = nil
+	definition.ParticipantWeights = nil
+	for i := range definition.Roster {
+		definition.Roster[i].Position = i + 1
+	}
+	if err := normalizeRoster(&definition); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := checkRosterUsers(r.Context(), schedule.TeamID, definition.Roster, latestDefinition(schedule).Roster); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	saveScheduleEdit(w, r, schedule, definition, effectiveFrom, message)
+}
+
+// getScheduleVersionsHandler lists every version of a schedule, or with
+// ?at=YYYY-MM-DDTHH:MM only the version in effect at that instant.
+func getScheduleVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	scheduleID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	schedule, err := getScheduleByID(r.Context(), scheduleID)
+	if err == sql.ErrNoRows {
+		writeError(w, r, notFoundError("Schedule not found"))
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	
+	if at := r.URL.Query().Get("at"); at != "" {
+		var v validator
+		instant := v.timestamp("at", at)
+		if err := v.err(); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		current := versionAt(*schedule, instant)
+		for _, version := range schedule.Versions {
+			if version.Version == current.Version {
+				json.NewEncoder(w).Encode(version)
+				return
+			}
+		}
+		writeError(w, r, notFoundError("Schedule has no versions"))
+		return
+	}
+	
+	json.NewEncoder(w).Encode(schedule.Versions)
+}
+
+// getSchedulesHandler lists schedules a page at a time (see pagination.go),
+// sorted by ?sort= (id, name, start_time, end_time or created_at) and
+// filtered by ?team_id=, ?name= and ?active=. Archived schedules are left out
+// unless ?include_archived=true, and deleted ones unless ?include_deleted=true.
+func getSchedulesHandler(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePageRequest(r, scheduleSortFields, "id")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	query := r.URL.Query()
+	filter := scheduleFilter{Name: query.Get("name"), IncludeArchived: query.Get("include_archived") == "true",
+		IncludeDeleted: query.Get("include_deleted") == "true"}
+	if filter.TeamID, err = queryID(r, "team_id"); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if v := query.Get("active"); v != "" {
+		active, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, r, fieldError("active", FieldInvalidChoice, "Must be true or false"))
+			return
+		}
+		filter.Active = &active
+	}
+	
+	schedules, next, err := listSchedules(r.Context(), filter, page)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	setNextPageHeaders(w, r, next)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schedules)
+}
+
+type pauseRequest struct {
+	FallbackUserID int `json:"fallback_user_id"`
+}
+
+// pauseScheduleHandler stops a schedule's rotation. The optional
+// fallback_user_id is assigned the shifts that start while it is paused;
+// pausing a paused schedule changes its fallback user.
+func pauseScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	var pause pauseRequest
+	
+	// The body is optional
+	if err := json.NewDecoder(r.Body).Decode(&pause); err != nil && err != io.EOF {
+		writeError(w, r, jsonError(err))
+		return
+	}
+	
+	if pause.FallbackUserID != 0 {
+		if err := checkUserExists(r.Context(), "fallback_user_id", pause.FallbackUserID); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+	
+	changeScheduleStatus(w, r, ScheduleStatusPaused, pause.FallbackUserID, ScheduleStatusActive, ScheduleStatusPaused)
+}
+
+// resumeScheduleHandler restarts a paused schedule. The scheduler hands the
+// rest of the shift in progress from the fallback user to whoever the
+// rotation has reached, without backfilling the shifts the schedule was
+// paused for.
+func resumeScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	changeScheduleStatus(w, r, ScheduleStatusActive, 0, ScheduleStatusPaused)
+}
+
+// archiveScheduleHandler retires a schedule for good. It stops rotating and
+// is hidden from listings, but its assignments are kept for reports.
+func archiveScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	changeScheduleStatus(w, r, ScheduleStatusArchived, 0, ScheduleStatusActive, ScheduleStatusPaused)
+}
+
+// changeScheduleStatus moves the schedule in the request path to status if
+// it is currently in one of the from states, and answers 409 otherwise.
+// Like other edits, it requires If-Match.
+func changeScheduleStatus(w http.ResponseWriter, r *http.Request, status string, fallbackUserID int, from ...string) {
+	scheduleID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	schedule, err := getScheduleByID(r.Context(), scheduleID)
+	if err == sql.ErrNoRows {
+		writeError(w, r, notFoundError("Schedule not found"))
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	if err := checkIfMatch(r, schedule.Revision, true); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	allowed := false
+	for _, state := range from {
+		if schedule.Status == state {
+			allowed = true
+		}
+	}
+	if !allowed {
+		writeError(w, r, conflictError(ErrCodeStatusTransition, fmt.Sprintf("Schedule is %s and cannot be made %s", schedule.Status, status)))
+		return
+	}
+	
+	err = updateScheduleStatus(r.Context(), scheduleID, schedule.Revision, status, fallbackUserID)
+	if err == sql.ErrNoRows {
+		writeError(w, r, preconditionFailedError())
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	response := map[string]interface{}{
+		"id":       scheduleID,
+		"revision": schedule.Revision + 1,
+		"status":   status,
+		"message":  "Schedule is now " + status,
+	}
+	
+	setETag(w, schedule.Revision+1)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+type shadowShiftRequest struct {
+	UserID    int    `json:"user_id" openapi:"required"`
+	StartTime string `json:"start_time" openapi:"required,datetime-local"`
+	EndTime   string `json:"end_time" openapi:"required,datetime-local"`
+}
+
+func createShadowShiftHandler(w http.ResponseWriter, r *http.Request) {
+	scheduleID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	var shift shadowShiftRequest
+	
+	if err := decodeJSON(r, &shift); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	var v validator
+	startTime := v.timestamp("start_time", shift.StartTime)
+	endTime := v.timestamp("end_time", shift.EndTime)
+	if !startTime.IsZero() && !endTime.IsZero() {
+		v.check(endTime.After(startTime), "end_time", FieldOutOfRange, "Must be after the start time")
+	}
+	if v.requiredID("user_id", shift.UserID) {
+		if err := v.merge(checkUserExists(r.Context(), "user_id", shift.UserID)); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+	if err := v.err(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	id, err := createShadowShift(r.Context(), scheduleID, shift.UserID, startTime, endTime)
+	if isForeignKeyViolation(err) {
+		writeError(w, r, notFoundError("Schedule not found"))
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	response := map[string]interface{}{
+		"id":      id,
+		"message": "Shadow shift created successfully",
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func getShadowShiftsHandler(w http.ResponseWriter, r *http.Request) {
+	scheduleID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	exists, err := scheduleExists(r.Context(), scheduleID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if !exists {
+		writeError(w, r, notFoundError("Schedule not found"))
+		return
+	}
+	
+	shifts, err := getShadowShifts(r.Context(), scheduleID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(shifts)
+}
+
+// getOnCallHoursReportHandler reports primary and shadow hours per user.
+// The window defaults to the last 30 days; override it with ?from= and
+// ?to= as YYYY-MM-DD dates.
+func getOnCallHoursReportHandler(w http.ResponseWriter, r *http.Request) {
+	to := clock.Now()
+	from := to.AddDate(0, 0, -30)
+	
+	var v validator
+	if value := r.URL.Query().Get("from"); value != "" {
+		from = v.date("from", value)
+	}
+	if value := r.URL.Query().Get("to"); value != "" {
+		to = v.date("to", value)
+	}
+	if err := v.err(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if to.Before(from) {
+		writeError(w, r, fieldError("to", FieldOutOfRange, "Must not be before from"))
+		return
+	}
+	
+	report, err := getOnCallHoursReport(r.Context(), from, to)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+type unavailabilityRequest struct {
+	UserID    int    `json:"user_id" openapi:"required"`
+	StartTime string `json:"start_time" openapi:"required,datetime-local"`
+	EndTime   string `json:"end_time" openapi:"required,datetime-local"`
+	Reason    string `json:"reason"`
+}
+
+func createUnavailabilityHandler(w http.ResponseWriter, r *http.Request) {
+	var entry unavailabilityRequest
+	
+	if err := decodeJSON(r, &entry); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	var v validator
+	startTime := v.timestamp("start_time", entry.StartTime)
+	endTime := v.timestamp("end_time", entry.EndTime)
+	if !startTime.IsZero() && !endTime.IsZero() {
+		v.check(endTime.After(startTime), "end_time", FieldOutOfRange, "Must be after the start time")
+	}
+	if v.requiredID("user_id", entry.UserID) {
+		if err := v.merge(checkUserExists(r.Context(), "user_id", entry.UserID)); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+	if err := v.err(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	id, err := createUnavailability(r.Context(), entry.UserID, startTime, endTime, entry.Reason)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	response := map[string]interface{}{
+		"id":      id,
+		"message": "Unavailability created successfully",
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func getUnavailabilityHandler(w http.ResponseWriter, r *http.Request) {
+	entries, err := getUnavailability(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+func deleteUnavailabilityHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	err = deleteUnavailability(r.Context(), id)
+	if err == sql.ErrNoRows {
+		writeError(w, r, notFoundError("Unavailability not found"))
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	response := map[string]interface{}{
+		"id":      id,
+		"message": "Unavailability deleted successfully",
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+type holidayRequest struct {
+	Date string `json:"date" openapi:"required,format=date"`
+	Name string `json:"name" openapi:"required"`
+}
+
+func createHolidayHandler(w http.ResponseWriter, r *http.Request) {
+	teamID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	var holiday holidayRequest
+	
+	if err := decodeJSON(r, &holiday); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	var v validator
+	date := v.date("date", holiday.Date)
+	v.required("name", holiday.Name)
+	if err := v.err(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	id, err := createHoliday(r.Context(), teamID, date, holiday.Name)
+	if isForeignKeyViolation(err) {
+		writeError(w, r, notFoundError("Team not found"))
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	response := map[string]interface{}{
+		"id":      id,
+		"message": "Holiday created successfully",
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// importHolidaysHandler imports the all-day events of an .ics file sent as
+// the request body into the team's holiday calendar.
+func importHolidaysHandler(w http.ResponseWriter, r *http.Request) {
+	teamID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	holidays, err := parseICSHolidays(r.Body)
+	if err != nil {
+		writeError(w, r, fieldError("file", FieldInvalidFormat, "Is not a valid iCalendar file: "+err.Error()))
+		return
+	}
+	
+	err = importHolidays(r.Context(), teamID, holidays)
+	if isForeignKeyViolation(err) {
+		writeError(w, r, notFoundError("Team not found"))
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	response := map[string]interface{}{
+		"imported": len(holidays),
+		"message":  "Holidays imported successfully",
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func getHolidaysHandler(w http.ResponseWriter, r *http.Request) {
+	teamID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	exists, err := teamExists(r.Context(), teamID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if !exists {
+		writeError(w, r, notFoundError("Team not found"))
+		return
+	}
+	
+	holidays, err := getHolidaysByTeamID(r.Context(), teamID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(holidays)
+}
+
+func deleteHolidayHandler(w http.ResponseWriter, r *http.Request) {
+	teamID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	holidayID, err := pathID(r, "holidayID")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	err = deleteHoliday(r.Context(), teamID, holidayID)
+	if err == sql.ErrNoRows {
+		writeError(w, r, notFoundError("Holiday not found"))
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	response := map[string]interface{}{
+		"id":      holidayID,
+		"message": "Holiday deleted successfully",
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// getScheduleProjectionHandler returns the expected on-call shifts for the
+// next ?days= days (default 14), including holiday shifts.
+func getScheduleProjectionHandler(w http.ResponseWriter, r *http.Request) {
+	scheduleID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	days := 14
+	if v := r.URL.Query().Get("days"); v != "" {
+		days, err = strconv.Atoi(v)
+		if err != nil || days < 1 || days > 366 {
+			writeError(w, r, fieldError("days", FieldOutOfRange, "Must be between 1 and 366"))
+			return
+		}
+	}
+	
+	schedule, err := getScheduleByID(r.Context(), scheduleID)
+	if err == sql.ErrNoRows {
+		writeError(w, r, notFoundError("Schedule not found"))
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	now := clock.Now()
+	shifts, err := projectSchedule(r.Context(), *schedule, now, now.AddDate(0, 0, days))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(shifts)
+}
+
+// getCoverageGapsHandler lists windows over the next ?days= days (default
+// COVERAGE_LOOKAHEAD_DAYS) in which a schedule has nobody on call.
+func getCoverageGapsHandler(w http.ResponseWriter, r *http.Request) {
+	days := coverageLookaheadDays()
+	if v := r.URL.Query().Get("days"); v != "" {
+		var err error
+		days, err = strconv.Atoi(v)
+		if err != nil || days < 1 || days > 366 {
+			writeError(w, r, fieldError("days", FieldOutOfRange, "Must be between 1 and 366"))
+			return
+		}
+	}
+	
+	now := clock.Now()
+	gaps, err := findCoverageGaps(r.Context(), now, now.AddDate(0, 0, days))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(gaps)
+}
+
+// getLeaderStatusHandler reports which replica currently leads the
+// scheduler and whether it is this one.
+func getLeaderStatusHandler(w http.ResponseWriter, r *http.Request) {
+	lease, err := getLeaderLease(r.Context(), schedulerLeaseName)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	
+	response := map[string]interface{}{
+		"instance_id": elector.instanceID,
+		"is_leader":   elector.isLeader(),
+		"lease":       lease,
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
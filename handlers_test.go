@@ -0,0 +1,23 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateScheduleDefinitionSequence(t *testing.T) {
+	definition := ScheduleDefinition{RotationPeriod: 86400, Participants: []int{1, 2},
+		RotationStrategy: RotationStrategyFixedSequence, Sequence: []int{1, 2, 1}}
+	if err := validateScheduleDefinition(&definition); err != nil {
+		t.Fatalf("validateScheduleDefinition() error = %v", err)
+	}
+
+	definition = ScheduleDefinition{RotationPeriod: 86400, Participants: []int{1, 2},
+		RotationStrategy: RotationStrategyFixedSequence, Sequence: []int{1, 3}}
+	err := validateScheduleDefinition(&definition)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || len(apiErr.Fields) != 1 || apiErr.Fields[0].Field != "sequence" ||
+		apiErr.Fields[0].Code != FieldInvalidChoice {
+		t.Errorf("validateScheduleDefinition() error = %#v, want a sequence field error", err)
+	}
+}
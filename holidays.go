@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// parseICSHolidays reads the all-day events of an iCalendar (.ics) feed as
+// holidays. Multi-day events produce one holiday per day; DTEND is exclusive
+// as in RFC 5545.
+func parseICSHolidays(r io.Reader) ([]Holiday, error) {
+	lines, err := unfoldICSLines(r)
+	if err != nil {
+		return nil, err
+	}
+	
+	var holidays []Holiday
+	var inEvent bool
+	var name, start, end string
+	for _, line := range lines {
+		switch {
+		case line == "BEGIN:VEVENT":
+			inEvent = true
+			name, start, end = "", "", ""
+		case line == "END:VEVENT":
+			inEvent = false
+			if start == "" {
+				return nil, fmt.Errorf("event %q has no DTSTART", name)
+			}
+			days, err := icsEventDays(start, end)
+			if err != nil {
+				return nil, fmt.Errorf("event %q: %v", name, err)
+			}
+			if name == "" {
+				name = "Holiday"
+			}
+			for _, day := range days {
+				holidays = append(holidays, Holiday{Date: day, Name: name})
+			}
+		case inEvent:
+			key, value, ok := splitICSProperty(line)
+			if !ok {
+				continue
+			}
+			switch key {
+			case "SUMMARY":
+				name = unescapeICSText(value)
+			case "DTSTART":
+				start = value
+			case "DTEND":
+				end = value
+			}
+		}
+	}
+	return holidays, nil
+}
+
+// unfoldICSLines joins folded continuation lines (those starting with a
+// space or tab) back onto the line they continue.
+func unfoldICSLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// splitICSProperty splits "DTSTART;VALUE=DATE:20241225" into its name
+// without parameters and its value.
+func splitICSProperty(line string) (string, string, bool) {
+	colon := strings.Index(line, ":")
+	if colon < 0 {
+		return "", "", false
+	}
+	key := line[:colon]
+	if semicolon := strings.Index(key, ";"); semicolon >= 0 {
+		key = key[:semicolon]
+	}
+	return strings.ToUpper(key), line[colon+1:], true
+}
+
+func unescapeICSText(value string) string {
+	replacer := strings.NewReplacer(`\,`, ",", `\;`, ";", `\n`, " ", `\N`, " ", `\\`, `\`)
+	return replacer.Replace(value)
+}
+
+func icsEventDays(start, end string) ([]time.Time, error) {
+	startDay, err := parseICSDate(start)
+	if err != nil {
+		return nil, err
+	}
+	if end == "" {
+		return []time.Time{startDay}, nil
+	}
+	
+	endDay, err := parseICSDate(end)
+	if err != nil {
+		return nil, err
+	}
+	
+	days := []time.Time{startDay}
+	for day := startDay.AddDate(0, 0, 1); day.Before(endDay); day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+// parseICSDate accepts DATE (20241225) and DATE-TIME (20241225T000000Z)
+// values and keeps only the calendar day.
+func parseICSDate(value string) (time.Time, error) {
+	if len(value) < 8 {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+	return time.Parse("20060102", value[:8])
+}
+
+// holidayForShift returns the first holiday whose day overlaps the shift, or
+// nil. Holidays are whole days in location, the schedule's timezone.
+func holidayForShift(holidays []Holiday, location *time.Location, shiftStart, shiftEnd time.Time) *Holiday {
+	for i, holiday := range holidays {
+		dayStart := time.Date(holiday.Date.Year(), holiday.Date.Month(), holiday.Date.Day(), 0, 0, 0, 0, location)
+		dayEnd := dayStart.AddDate(0, 0, 1)
+		if dayStart.Before(shiftEnd) && dayEnd.After(shiftStart) {
+			return &holidays[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseICSHolidays(t *testing.T) {
+	calendar := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:20241225",
+		"DTEND;VALUE=DATE:20241227",
+		"SUMMARY:Christmas\\, Boxing",
+		"  Day",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART:20250101T000000Z",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	holidays, err := parseICSHolidays(strings.NewReader(calendar))
+	if err != nil {
+		t.Fatalf("parseICSHolidays() error = %v", err)
+	}
+	want := []Holiday{
+		{Date: time.Date(2024, 12, 25, 0, 0, 0, 0, time.UTC), Name: "Christmas, Boxing Day"},
+		{Date: time.Date(2024, 12, 26, 0, 0, 0, 0, time.UTC), Name: "Christmas, Boxing Day"},
+		{Date: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Name: "Holiday"},
+	}
+	if len(holidays) != len(want) {
+		t.Fatalf("got %d holidays, want %d: %+v", len(holidays), len(want), holidays)
+	}
+	for i, holiday := range holidays {
+		if !holiday.Date.Equal(want[i].Date) || holiday.Name != want[i].Name {
+			t.Errorf("holiday %d = %s %q, want %s %q", i, holiday.Date.Format("2006-01-02"), holiday.Name,
+				want[i].Date.Format("2006-01-02"), want[i].Name)
+		}
+	}
+
+	for _, invalid := range []string{
+		"BEGIN:VEVENT\nSUMMARY:No start\nEND:VEVENT",
+		"BEGIN:VEVENT\nDTSTART:2024\nEND:VEVENT",
+		"BEGIN:VEVENT\nDTSTART:20241225\nDTEND:next week\nEND:VEVENT",
+	} {
+		if _, err := parseICSHolidays(strings.NewReader(invalid)); err == nil {
+			t.Errorf("parseICSHolidays(%q) succeeded, want an error", invalid)
+		}
+	}
+}
+
+func TestHolidayForShiftTimezone(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip("no timezone data:", err)
+	}
+	holidays := []Holiday{{TeamID: 1, Date: monday, Name: "Founders' Day"}}
+
+	// Monday in Tokyo runs from 15:00 UTC on Sunday to 15:00 UTC on Monday
+	tests := []struct {
+		start time.Time
+		want  bool
+	}{
+		{monday.Add(-12 * time.Hour), true},
+		{monday.Add(12 * time.Hour), true},
+		{monday.Add(15 * time.Hour), false},
+		{monday.Add(-21 * time.Hour), false},
+	}
+	for _, test := range tests {
+		got := holidayForShift(holidays, tokyo, test.start, test.start.Add(6*time.Hour)) != nil
+		if got != test.want {
+			t.Errorf("shift at %s: holiday = %v, want %v", test.start.Format(time.RFC3339), got, test.want)
+		}
+	}
+	if holidayForShift(holidays, time.UTC, monday.Add(-12*time.Hour), monday.Add(-6*time.Hour)) != nil {
+		t.Error("Sunday shift in UTC was flagged as a Monday holiday")
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// POST requests may carry an Idempotency-Key header, so clients can retry
+// them safely after a timeout. The first request with a key runs and its
+// response is stored; later requests with the same key get that response
+// back, marked with Idempotent-Replayed, instead of creating a duplicate.
+
+const (
+	// idempotencyKeyTTL is how long a key and its response are kept.
+	idempotencyKeyTTL = 24 * time.Hour
+	// idempotencyLockTimeout is how long a request may hold its key before
+	// the key is taken to be abandoned by a crashed replica.
+	idempotencyLockTimeout  = time.Minute
+	maxIdempotencyKeyLength = 255
+)
+
+// idempotencyKeyStore keeps the keys in use and their stored responses.
+type idempotencyKeyStore interface {
+	claim(ctx context.Context, key, fingerprint string) (bool, error)
+	get(ctx context.Context, key string) (storedResponse, error)
+	save(ctx context.Context, key string, response storedResponse) error
+	release(ctx context.Context, key string) error
+}
+
+// idempotencyKeys is the idempotency_keys table; tests swap in their own.
+var idempotencyKeys idempotencyKeyStore = postgresIdempotencyKeys{}
+
+// storedResponse is a response saved under an idempotency key. Status is 0
+// while the request is still running.
+type storedResponse struct {
+	fingerprint string
+	status      int
+	contentType string
+	etag        string
+	body        []byte
+}
+
+// idempotent makes a POST handler honour the Idempotency-Key header.
+// Responses are stored unless they are server errors, which are worth
+// retrying for real.
+func idempotent(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			handler(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			writeError(w, r, fieldError("Idempotency-Key", FieldInvalid, "Must be at most 255 characters"))
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, r, &APIError{Status: http.StatusBadRequest, Code: ErrCodeInvalidJSON, Message: "The request body could not be read"})
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		fingerprint := requestFingerprint(r, body)
+
+		claimed, err := idempotencyKeys.claim(r.Context(), key, fingerprint)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if !claimed {
+			replayResponse(w, r, key, fingerprint)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		handler(recorder, r)
+
+		// The client may have given up, but the response is still worth keeping
+		ctx := context.WithoutCancel(r.Context())
+		if recorder.status >= 500 {
+			err = idempotencyKeys.release(ctx, key)
+		} else {
+			err = idempotencyKeys.save(ctx, key, storedResponse{status: recorder.status,
+				contentType: recorder.Header().Get("Content-Type"), etag: recorder.Header().Get("ETag"), body: recorder.body.Bytes()})
+		}
+		if err != nil {
+			log.Printf("Error storing the response for idempotency key %q: %v", key, err)
+		}
+	}
+}
+
+// replayResponse answers a request whose key was already used with the
+// stored response.
+func replayResponse(w http.ResponseWriter, r *http.Request, key, fingerprint string) {
+	stored, err := idempotencyKeys.get(r.Context(), key)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Released after a server error between the claim and now
+		writeError(w, r, conflictError(ErrCodeRequestInProgress, "A request with this Idempotency-Key is being retried; try again"))
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	switch {
+	case stored.fingerprint != fingerprint:
+		writeError(w, r, &APIError{Status: http.StatusUnprocessableEntity, Code: ErrCodeIdempotencyKeyReused,
+			Message: "This Idempotency-Key was used for a different request"})
+	case stored.status == 0:
+		w.Header().Set("Retry-After", "1")
+		writeError(w, r, conflictError(ErrCodeRequestInProgress, "A request with this Idempotency-Key is still in progress"))
+	default:
+		if stored.contentType != "" {
+			w.Header().Set("Content-Type", stored.contentType)
+		}
+		if stored.etag != "" {
+			w.Header().Set("ETag", stored.etag)
+		}
+		w.Header().Set("Idempotent-Replayed", "true")
+		w.WriteHeader(stored.status)
+		w.Write(stored.body)
+	}
+}
+
+// requestFingerprint identifies a request by its method, path and body. The
+// path is taken relative to apiPrefix, so a request to a deprecated alias
+// matches the same request under /api/v1.
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + strings.TrimPrefix(r.URL.Path, apiPrefix) + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// responseRecorder passes a response through while keeping a copy of it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(data []byte) (int, error) {
+	rec.wroteHeader = true
+	rec.body.Write(data)
+	return rec.ResponseWriter.Write(data)
+}
+
+// postgresIdempotencyKeys keeps idempotency keys in the database.
+type postgresIdempotencyKeys struct{}
+
+func (postgresIdempotencyKeys) claim(ctx context.Context, key, fingerprint string) (bool, error) {
+	return claimIdempotencyKey(ctx, key, fingerprint)
+}
+
+func (postgresIdempotencyKeys) get(ctx context.Context, key string) (storedResponse, error) {
+	return getIdempotentResponse(ctx, key)
+}
+
+func (postgresIdempotencyKeys) save(ctx context.Context, key string, response storedResponse) error {
+	return saveIdempotentResponse(ctx, key, response)
+}
+
+func (postgresIdempotencyKeys) release(ctx context.Context, key string) error {
+	return releaseIdempotencyKey(ctx, key)
+}
+
+// claimIdempotencyKey records key as in use by the request with fingerprint
+// and reports whether it was free. Expired keys are cleared first, and a key
+// whose request has held it past idempotencyLockTimeout without finishing is
+// taken over by a retry of the same request.
+func claimIdempotencyKey(ctx context.Context, key, fingerprint string) (bool, error) {
+	_, err := db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE created_at < NOW() - make_interval(secs => $1)",
+		idempotencyKeyTTL.Seconds())
+	if err != nil {
+		return false, err
+	}
+
+	err = db.QueryRowContext(ctx, `INSERT INTO idempotency_keys (key, fingerprint) VALUES ($1, $2)
+		ON CONFLICT (key) DO UPDATE SET created_at = NOW()
+		WHERE idempotency_keys.status_code IS NULL AND idempotency_keys.fingerprint = EXCLUDED.fingerprint
+		AND idempotency_keys.created_at < NOW() - make_interval(secs => $3)
+		RETURNING key`, key, fingerprint, idempotencyLockTimeout.Seconds()).Scan(&key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func getIdempotentResponse(ctx context.Context, key string) (storedResponse, error) {
+	var stored storedResponse
+	var status sql.NullInt64
+	var contentType, etag sql.NullString
+	err := db.QueryRowContext(ctx, `SELECT fingerprint, status_code, content_type, etag, response_body
+		FROM idempotency_keys WHERE key = $1`, key).Scan(&stored.fingerprint, &status, &contentType, &etag, &stored.body)
+	stored.status = int(status.Int64)
+	stored.contentType = contentType.String
+	stored.etag = etag.String
+	return stored, err
+}
+
+func saveIdempotentResponse(ctx context.Context, key string, response storedResponse) error {
+	_, err := db.ExecContext(ctx, `UPDATE idempotency_keys SET status_code = $2, content_type = $3, etag = NULLIF($4, ''),
+		response_body = $5 WHERE key = $1`, key, response.status, response.contentType, response.etag, response.body)
+	return err
+}
+
+// releaseIdempotencyKey frees key so the request can be retried.
+func releaseIdempotencyKey(ctx context.Context, key string) error {
+	_, err := db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE key = $1", key)
+	return err
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// memoryIdempotencyKeys keeps idempotency keys in memory.
+type memoryIdempotencyKeys struct {
+	mu   sync.Mutex
+	keys map[string]storedResponse
+}
+
+func (m *memoryIdempotencyKeys) claim(ctx context.Context, key, fingerprint string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.keys[key]; ok {
+		return false, nil
+	}
+	m.keys[key] = storedResponse{fingerprint: fingerprint}
+	return true, nil
+}
+
+func (m *memoryIdempotencyKeys) get(ctx context.Context, key string) (storedResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.keys[key]
+	if !ok {
+		return storedResponse{}, sql.ErrNoRows
+	}
+	return stored, nil
+}
+
+func (m *memoryIdempotencyKeys) save(ctx context.Context, key string, response storedResponse) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	response.fingerprint = m.keys[key].fingerprint
+	m.keys[key] = response
+	return nil
+}
+
+func (m *memoryIdempotencyKeys) release(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.keys, key)
+	return nil
+}
+
+// useMemoryIdempotencyKeys keeps idempotency keys in memory until the test
+// ends.
+func useMemoryIdempotencyKeys(t *testing.T) *memoryIdempotencyKeys {
+	t.Helper()
+	keys := &memoryIdempotencyKeys{keys: make(map[string]storedResponse)}
+	previous := idempotencyKeys
+	idempotencyKeys = keys
+	t.Cleanup(func() { idempotencyKeys = previous })
+	return keys
+}
+
+// postWithKey sends a POST with an Idempotency-Key through handler.
+func postWithKey(handler http.HandlerFunc, key, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("POST", "/api/v1/schedules", strings.NewReader(body))
+	r.Header.Set("Idempotency-Key", key)
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+// errorCode returns the code of the JSON error in the response.
+func errorCode(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	var apiErr APIError
+	if err := json.NewDecoder(w.Body).Decode(&apiErr); err != nil {
+		t.Fatalf("decoding the error: %v", err)
+	}
+	return apiErr.Code
+}
+
+func TestIdempotentReplay(t *testing.T) {
+	useMemoryIdempotencyKeys(t)
+	calls := 0
+	handler := idempotent(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		setETag(w, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": 7}`))
+	})
+
+	first := postWithKey(handler, "retry-me", `{"name": "ops"}`)
+	retry := postWithKey(handler, "retry-me", `{"name": "ops"}`)
+	if calls != 1 {
+		t.Fatalf("handler ran %d times, want once", calls)
+	}
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+		t.Errorf("replay = %d %s, want %d %s", retry.Code, retry.Body, first.Code, first.Body)
+	}
+	for _, header := range []string{"ETag", "Content-Type"} {
+		if got, want := retry.Header().Get(header), first.Header().Get(header); got != want || got == "" {
+			t.Errorf("replayed %s = %q, want %q", header, got, want)
+		}
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("replay is not marked Idempotent-Replayed")
+	}
+
+	reused := postWithKey(handler, "retry-me", `{"name": "dev"}`)
+	if reused.Code != http.StatusUnprocessableEntity || errorCode(t, reused) != ErrCodeIdempotencyKeyReused {
+		t.Errorf("key reused for another body: status %d", reused.Code)
+	}
+	if calls != 1 {
+		t.Errorf("handler ran %d times, want once", calls)
+	}
+}
+
+func TestIdempotentInProgress(t *testing.T) {
+	keys := useMemoryIdempotencyKeys(t)
+	handler := idempotent(func(w http.ResponseWriter, r *http.Request) {
+		// A retry arriving while the first request is still running
+		retry := postWithKey(idempotent(func(w http.ResponseWriter, r *http.Request) {
+			t.Error("retry ran while the first request held its key")
+		}), "slow", `{}`)
+		if retry.Code != http.StatusConflict || retry.Header().Get("Retry-After") == "" ||
+			errorCode(t, retry) != ErrCodeRequestInProgress {
+			t.Errorf("retry in progress: status %d", retry.Code)
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+	postWithKey(handler, "slow", `{}`)
+
+	if keys.keys["slow"].status != http.StatusCreated {
+		t.Errorf("stored status = %d, want 201", keys.keys["slow"].status)
+	}
+}
+
+func TestIdempotentServerErrorIsRetried(t *testing.T) {
+	keys := useMemoryIdempotencyKeys(t)
+	calls := 0
+	handler := idempotent(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			writeError(w, r, sql.ErrConnDone)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	if w := postWithKey(handler, "flaky", `{}`); w.Code != http.StatusInternalServerError {
+		t.Fatalf("first attempt: status %d, want 500", w.Code)
+	}
+	if _, ok := keys.keys["flaky"]; ok {
+		t.Error("key kept after a server error")
+	}
+	if w := postWithKey(handler, "flaky", `{}`); w.Code != http.StatusCreated || calls != 2 {
+		t.Errorf("retry: status %d after %d calls, want 201 after 2", w.Code, calls)
+	}
+}
+
+func TestIdempotencyKeyTooLong(t *testing.T) {
+	useMemoryIdempotencyKeys(t)
+	handler := idempotent(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler ran with an invalid key")
+	})
+	w := postWithKey(handler, strings.Repeat("k", maxIdempotencyKeyLength+1), `{}`)
+	if w.Code != http.StatusBadRequest || errorCode(t, w) != ErrCodeValidation {
+		t.Errorf("status %d, want 400", w.Code)
+	}
+}
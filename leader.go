@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// schedulerLeaseName names the lease and advisory lock held by the
+	// instance that runs rotations and sends notifications.
+	schedulerLeaseName = "scheduler"
+	schedulerLockKey   = int64(0x6f6e63616c6c) // "oncall"
+	
+	// leaderHeartbeat is how often the leader renews its lease and followers
+	// try to take over.
+	leaderHeartbeat = 5 * time.Second
+)
+
+// errFencedOut is returned by fenced writes when another instance has taken
+// over leadership since the fencing token was issued.
+var errFencedOut = errors.New("fencing token is stale, another instance is leader")
+
+// leaderElector elects one instance among replicas with a session-level
+// Postgres advisory lock. The lock lives on a dedicated connection, so if
+// the leader dies or loses its database session the lock is released and a
+// follower takes over within one heartbeat.
+type leaderElector struct {
+	instanceID string
+	
+	mu    sync.Mutex
+	conn  *sql.Conn
+	token int64
+}
+
+func newLeaderElector(instanceID string) *leaderElector {
+	return &leaderElector{instanceID: instanceID}
+}
+
+// instanceID identifies this replica, from INSTANCE_ID or hostname and pid.
+func instanceID() string {
+	if id := os.Getenv("INSTANCE_ID"); id != "" {
+		return id
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
+
+// run takes part in the election until ctx is done. Leadership is kept
+// after that, so the scheduler can finish its pass; call resign once it has.
+func (e *leaderElector) run(ctx context.Context) {
+	ticker := time.NewTicker(leaderHeartbeat)
+	defer ticker.Stop()
+	
+	log.Printf("Leader election started for instance %s", e.instanceID)
+	
+	for {
+		e.heartbeat(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *leaderElector) heartbeat(ctx context.Context) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	
+	if e.conn == nil {
+		e.tryAcquire(ctx)
+		return
+	}
+	
+	if err := e.renew(); err != nil {
+		log.Printf("Lost leadership of %s: %v", schedulerLeaseName, err)
+		e.release()
+	}
+}
+
+// tryAcquire takes the advisory lock if nobody holds it and bumps the
+// fencing token. Callers must hold e.mu.
+func (e *leaderElector) tryAcquire(ctx context.Context) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		log.Printf("Error getting connection for leader election: %v", err)
+		return
+	}
+	
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", schedulerLockKey).Scan(&acquired); err != nil || !acquired {
+		if err != nil {
+			log.Printf("Error trying leader lock: %v", err)
+		}
+		conn.Close()
+		return
+	}
+	
+	var token int64
+	err = conn.QueryRowContext(ctx, `
+		INSERT INTO leader_leases (name, holder, fencing_token, acquired_at, renewed_at)
+		VALUES ($1, $2, 1, now(), now())
+		ON CONFLICT (name) DO UPDATE SET holder = EXCLUDED.holder, fencing_token = leader_leases.fencing_token + 1,
+			acquired_at = now(), renewed_at = now()
+		RETURNING fencing_token`, schedulerLeaseName, e.instanceID).Scan(&token)
+	if err != nil {
+		log.Printf("Error recording leader lease: %v", err)
+		conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", schedulerLockKey)
+		conn.Close()
+		return
+	}
+	
+	e.conn = conn
+	e.token = token
+	log.Printf("Instance %s is now leader of %s (fencing token %d)", e.instanceID, schedulerLeaseName, token)
+}
+
+// renew checks the lock's session is alive and that the lease is still
+// ours. Callers must hold e.mu.
+func (e *leaderElector) renew() error {
+	ctx, cancel := context.WithTimeout(context.Background(), leaderHeartbeat)
+	defer cancel()
+	
+	result, err := e.conn.ExecContext(ctx, "UPDATE leader_leases SET renewed_at = now() WHERE name = $1 AND holder = $2 AND fencing_token = $3",
+		schedulerLeaseName, e.instanceID, e.token)
+	if err != nil {
+		return err
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return errFencedOut
+	}
+	return nil
+}
+
+// release gives up leadership. Closing the connection ends the session,
+// which releases the advisory lock even if the unlock fails. Callers must
+// hold e.mu.
+func (e *leaderElector) release() {
+	if e.conn == nil {
+		return
+	}
+	e.conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", schedulerLockKey)
+	e.conn.Close()
+	e.conn = nil
+	e.token = 0
+}
+
+// resign gives up leadership on shutdown, so another instance can take over
+// while this one is still draining notifications.
+func (e *leaderElector) resign() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	
+	if e.conn != nil {
+		log.Printf("Instance %s is resigning leadership of %s", e.instanceID, schedulerLeaseName)
+		e.release()
+	}
+}
+
+// fencingToken returns the current fencing token and whether this instance
+// is the leader.
+func (e *leaderElector) fencingToken() (int64, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.token, e.conn != nil
+}
+
+func (e *leaderElector) isLeader() bool {
+	_, leader := e.fencingToken()
+	return leader
+}
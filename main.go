@@ -1,20 +1,49 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
 	_ "github.com/lib/pq"
 )
 
+// shutdownTimeout bounds how long shutdown waits for in-flight requests,
+// the scheduler's current pass and pending notifications.
+const shutdownTimeout = 25 * time.Second
+
 var db *sql.DB
 
+// elector decides which replica runs rotations and sends notifications.
+var elector *leaderElector
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "simulate" {
+		os.Exit(runSimulateCommand(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "openapi" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(openAPISpec(apiRoutes())); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	
 	var err error
 	
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	
 	dbURL := os.Getenv("DATABASE_URL")
 	if dbURL == "" {
 		dbURL = "postgres://localhost/oncall?sslmode=disable"
@@ -28,18 +57,115 @@ func main() {
 	
 	initDB()
 	
+	r := newRouter()
+	
+	elector = newLeaderElector(instanceID())
+	
+	var workers sync.WaitGroup
+	workers.Add(4)
+	go func() {
+		defer workers.Done()
+		elector.run(ctx)
+	}()
+	go func() {
+		defer workers.Done()
+		scheduleChecker(ctx, dbURL)
+	}()
+	go func() {
+		defer workers.Done()
+		coverageChecker(ctx)
+	}()
+	go func() {
+		defer workers.Done()
+		purger(ctx)
+	}()
+	
+	server := &http.Server{Addr: ":8080", Handler: r}
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Println("Server starting on :8080")
+		serverErr <- server.ListenAndServe()
+	}()
+	
+	select {
+	case err := <-serverErr:
+		log.Fatal(err)
+	case <-ctx.Done():
+	}
+	
+	// A second signal kills the process straight away
+	stop()
+	shutdown(server, &workers)
+}
+
+// newRouter routes the web UI and the JSON API, which is served under
+// apiPrefix and, deprecated, at its old unversioned paths.
+func newRouter() *mux.Router {
 	r := mux.NewRouter()
+	r.NotFoundHandler = http.HandlerFunc(notFoundHandler)
+	r.MethodNotAllowedHandler = http.HandlerFunc(methodNotAllowedHandler)
 	
-	r.HandleFunc("/", homeHandler).Methods("GET")
-	r.HandleFunc("/users", createUserHandler).Methods("POST")
-	r.HandleFunc("/users", getUsersHandler).Methods("GET")
-	r.HandleFunc("/teams", createTeamHandler).Methods("POST")
-	r.HandleFunc("/teams", getTeamsHandler).Methods("GET")
-	r.HandleFunc("/schedules", createScheduleHandler).Methods("POST")
-	r.HandleFunc("/schedules", getSchedulesHandler).Methods("GET")
+	// The web UI has its own routes, so it can change without the API
+	r.Handle("/", http.RedirectHandler("/ui/", http.StatusFound)).Methods("GET")
+	r.Handle("/ui", http.RedirectHandler("/ui/", http.StatusFound)).Methods("GET")
+	r.HandleFunc("/ui/", uiHandler).Methods("GET")
 	
-	go scheduleChecker()
+	api := r.PathPrefix(apiPrefix).Subrouter()
+	for _, route := range apiRoutes() {
+		handler := route.handler
+		if route.method == http.MethodPost {
+			handler = idempotent(handler)
+		}
+		api.HandleFunc(route.path, handler).Methods(route.method)
+		r.HandleFunc(route.path, deprecatedAlias(handler)).Methods(route.method)
+	}
+	return r
+}
+
+// legacyPathsDeprecatedAt is when the API moved under /api/v1.
+var legacyPathsDeprecatedAt = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+// deprecatedAlias serves an API route at its old path, outside /api/v1. The
+// response says so with a Deprecation header (RFC 9745) and links to the
+// same resource under /api/v1.
+func deprecatedAlias(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		successor := *r.URL
+		successor.Path = apiPrefix + r.URL.Path
+		successor.RawPath = ""
+		w.Header().Set("Deprecation", fmt.Sprintf("@%d", legacyPathsDeprecatedAt.Unix()))
+		w.Header().Add("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor.RequestURI()))
+		handler(w, r)
+	}
+}
+
+// shutdown stops accepting requests and lets in-flight ones finish, waits for
+// the scheduler and coverage checker to stop, hands leadership over and then
+// sends the notifications still pending, all within shutdownTimeout.
+func shutdown(server *http.Server, workers *sync.WaitGroup) {
+	log.Println("Shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
 	
-	log.Println("Server starting on :8080")
-	log.Fatal(http.ListenAndServe(":8080", r))
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("Error shutting down HTTP server: %v", err)
+	}
+	
+	stopped := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		log.Println("Timed out waiting for the scheduler to stop")
+	}
+	
+	elector.resign()
+	
+	if err := notifier.Drain(ctx); err != nil {
+		log.Printf("Gave up waiting for pending notifications: %v", err)
+	}
+	log.Println("Shutdown complete")
 }
@@ -0,0 +1,20 @@
+-- Shadow (trainee) on-call participants
+
+-- Assignment role: 'primary' is the real on-caller, 'shadow' pairs a trainee with them
+ALTER TABLE oncall_assignments ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'primary';
+
+-- Shifts a shadow is paired with the primary on-caller of a schedule
+CREATE TABLE IF NOT EXISTS shadow_shifts (
+    id SERIAL PRIMARY KEY,
+    schedule_id INTEGER REFERENCES schedules(id),
+    user_id INTEGER REFERENCES users(id),
+    start_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    end_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_shadow_shifts_schedule ON shadow_shifts(schedule_id);
+CREATE INDEX IF NOT EXISTS idx_shadow_shifts_times ON shadow_shifts(start_time, end_time);
+CREATE INDEX IF NOT EXISTS idx_oncall_assignments_role ON oncall_assignments(role);
+
+COMMENT ON COLUMN oncall_assignments.role IS 'primary for the on-caller, shadow for a paired trainee (never escalated to)';
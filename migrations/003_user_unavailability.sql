@@ -0,0 +1,13 @@
+-- PTO / unavailability calendar skipped by the rotation
+
+CREATE TABLE IF NOT EXISTS user_unavailability (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    start_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    end_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    reason VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_unavailability_user ON user_unavailability(user_id);
+CREATE INDEX IF NOT EXISTS idx_user_unavailability_times ON user_unavailability(start_time, end_time);
@@ -0,0 +1,24 @@
+-- Holiday calendars per team and per-schedule holiday handling
+
+CREATE TABLE IF NOT EXISTS holidays (
+    id SERIAL PRIMARY KEY,
+    team_id INTEGER NOT NULL REFERENCES teams(id),
+    holiday_date DATE NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (team_id, holiday_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_holidays_team_date ON holidays(team_id, holiday_date);
+
+-- none: ignore holidays, skip: no rotation on holiday shifts,
+-- rotation: route holiday shifts to holiday_schedule_id's participants,
+-- flag: rotate normally but flag holiday shifts for compensation
+ALTER TABLE schedules ADD COLUMN IF NOT EXISTS holiday_mode VARCHAR(20) NOT NULL DEFAULT 'none';
+ALTER TABLE schedules ADD COLUMN IF NOT EXISTS holiday_schedule_id INTEGER REFERENCES schedules(id);
+
+ALTER TABLE oncall_assignments ADD COLUMN IF NOT EXISTS holiday BOOLEAN NOT NULL DEFAULT false;
+
+COMMENT ON COLUMN schedules.holiday_mode IS 'How holiday shifts are handled: none, skip, rotation or flag';
+COMMENT ON COLUMN schedules.holiday_schedule_id IS 'Schedule whose participants cover holiday shifts when holiday_mode is rotation';
+COMMENT ON COLUMN oncall_assignments.holiday IS 'True when the shift overlaps a team holiday';
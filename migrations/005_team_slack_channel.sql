@@ -0,0 +1,5 @@
+-- Per-team Slack channel for coverage warnings
+
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS slack_channel VARCHAR(255);
+
+COMMENT ON COLUMN teams.slack_channel IS 'Slack channel for team alerts; falls back to SLACK_CHANNEL when empty';
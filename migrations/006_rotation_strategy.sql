@@ -0,0 +1,8 @@
+-- Per-schedule rotation strategy
+
+-- round_robin: strict participant order, fairness: lowest weighted on-call load
+ALTER TABLE schedules ADD COLUMN IF NOT EXISTS rotation_strategy VARCHAR(20) NOT NULL DEFAULT 'round_robin';
+
+CREATE INDEX IF NOT EXISTS idx_oncall_assignments_schedule_user ON oncall_assignments(schedule_id, user_id);
+
+COMMENT ON COLUMN schedules.rotation_strategy IS 'How the next on-caller is chosen: round_robin or fairness';
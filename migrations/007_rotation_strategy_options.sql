@@ -0,0 +1,8 @@
+-- Options for the pluggable rotation strategies
+
+ALTER TABLE schedules ADD COLUMN IF NOT EXISTS participant_weights JSONB;
+ALTER TABLE schedules ADD COLUMN IF NOT EXISTS sequence_ids TEXT;
+
+COMMENT ON COLUMN schedules.rotation_strategy IS 'How the next on-caller is chosen: round_robin, fairness, weighted, random or fixed_sequence';
+COMMENT ON COLUMN schedules.participant_weights IS 'JSON object of user ID to share weight, used by the weighted strategy';
+COMMENT ON COLUMN schedules.sequence_ids IS 'Comma-separated explicit order of user IDs, used by the fixed_sequence strategy';
@@ -0,0 +1,28 @@
+-- Wake the scheduler via LISTEN/NOTIFY whenever rotation inputs change
+
+CREATE OR REPLACE FUNCTION notify_schedule_change() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('schedule_changes', TG_TABLE_NAME);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS schedules_notify_change ON schedules;
+CREATE TRIGGER schedules_notify_change
+    AFTER INSERT OR UPDATE OR DELETE ON schedules
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_schedule_change();
+
+DROP TRIGGER IF EXISTS shadow_shifts_notify_change ON shadow_shifts;
+CREATE TRIGGER shadow_shifts_notify_change
+    AFTER INSERT OR UPDATE OR DELETE ON shadow_shifts
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_schedule_change();
+
+DROP TRIGGER IF EXISTS user_unavailability_notify_change ON user_unavailability;
+CREATE TRIGGER user_unavailability_notify_change
+    AFTER INSERT OR UPDATE OR DELETE ON user_unavailability
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_schedule_change();
+
+DROP TRIGGER IF EXISTS holidays_notify_change ON holidays;
+CREATE TRIGGER holidays_notify_change
+    AFTER INSERT OR UPDATE OR DELETE ON holidays
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_schedule_change();
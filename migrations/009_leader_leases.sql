@@ -0,0 +1,12 @@
+-- Leader election for running several replicas
+
+-- One row per elected role. The leader holds a Postgres advisory lock for
+-- the role and bumps fencing_token on every takeover; writes carry the token
+-- so a deposed leader cannot clobber its successor.
+CREATE TABLE IF NOT EXISTS leader_leases (
+    name VARCHAR(64) PRIMARY KEY,
+    holder VARCHAR(255) NOT NULL,
+    fencing_token BIGINT NOT NULL DEFAULT 1,
+    acquired_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    renewed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
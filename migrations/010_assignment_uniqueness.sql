@@ -0,0 +1,24 @@
+-- Make rotations idempotent: one primary assignment per schedule, window and tier
+
+-- Escalation tier of the assignment; 1 is the first responder
+ALTER TABLE oncall_assignments ADD COLUMN IF NOT EXISTS tier INTEGER NOT NULL DEFAULT 1;
+
+-- Remove duplicates left by earlier non-transactional rotations, keeping the first row
+DELETE FROM oncall_assignments a
+USING oncall_assignments b
+WHERE a.role = 'primary' AND b.role = 'primary'
+  AND a.schedule_id = b.schedule_id AND a.start_time = b.start_time AND a.tier = b.tier
+  AND a.id > b.id;
+
+DELETE FROM oncall_assignments a
+USING oncall_assignments b
+WHERE a.role = 'shadow' AND b.role = 'shadow'
+  AND a.schedule_id = b.schedule_id AND a.start_time = b.start_time AND a.user_id = b.user_id
+  AND a.id > b.id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_oncall_assignments_unique_primary
+    ON oncall_assignments(schedule_id, start_time, tier) WHERE role = 'primary';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_oncall_assignments_unique_shadow
+    ON oncall_assignments(schedule_id, start_time, user_id) WHERE role = 'shadow';
+
+COMMENT ON COLUMN oncall_assignments.tier IS 'Escalation tier, 1 being the first responder';
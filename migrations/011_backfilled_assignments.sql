@@ -0,0 +1,5 @@
+-- Mark assignments reconstructed for rotations missed while the scheduler was down
+
+ALTER TABLE oncall_assignments ADD COLUMN IF NOT EXISTS backfilled BOOLEAN NOT NULL DEFAULT false;
+
+COMMENT ON COLUMN oncall_assignments.backfilled IS 'Reconstructed after downtime rather than assigned at the handoff; nobody was notified';
@@ -0,0 +1,37 @@
+-- Versioned schedule definitions that take effect from a chosen instant
+
+CREATE TABLE IF NOT EXISTS schedule_versions (
+    id SERIAL PRIMARY KEY,
+    schedule_id INTEGER NOT NULL REFERENCES schedules(id),
+    version INTEGER NOT NULL,
+    effective_from TIMESTAMP WITH TIME ZONE NOT NULL,
+    rotation_period INTEGER NOT NULL,
+    participant_ids TEXT,
+    rotation_strategy VARCHAR(20) NOT NULL DEFAULT 'round_robin',
+    participant_weights JSONB,
+    sequence_ids TEXT,
+    holiday_mode VARCHAR(20) NOT NULL DEFAULT 'none',
+    holiday_schedule_id INTEGER REFERENCES schedules(id),
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (schedule_id, version)
+);
+
+CREATE INDEX IF NOT EXISTS idx_schedule_versions_effective ON schedule_versions(schedule_id, effective_from);
+
+-- Every existing schedule starts out with its current definition as version 1
+INSERT INTO schedule_versions (schedule_id, version, effective_from, rotation_period, participant_ids, rotation_strategy,
+    participant_weights, sequence_ids, holiday_mode, holiday_schedule_id)
+SELECT id, 1, start_time, rotation_period, participant_ids, rotation_strategy,
+    participant_weights, sequence_ids, holiday_mode, holiday_schedule_id
+FROM schedules s
+WHERE NOT EXISTS (SELECT 1 FROM schedule_versions v WHERE v.schedule_id = s.id);
+
+DROP TRIGGER IF EXISTS schedule_versions_notify_change ON schedule_versions;
+CREATE TRIGGER schedule_versions_notify_change
+    AFTER INSERT OR UPDATE OR DELETE ON schedule_versions
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_schedule_change();
+
+COMMENT ON TABLE schedule_versions IS 'Schedule definitions; each one is in effect from effective_from until the next version takes effect';
+COMMENT ON COLUMN schedule_versions.timezone IS 'IANA time zone that shift times are shown in';
+COMMENT ON TABLE schedules IS 'Schedule identity and lifetime; its rotation columns keep version 1, schedule_versions holds every definition';
@@ -0,0 +1,12 @@
+-- Schedule lifecycle: active, paused (optionally covered by a fallback user) or archived
+
+ALTER TABLE schedules ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'active'
+    CHECK (status IN ('active', 'paused', 'archived'));
+ALTER TABLE schedules ADD COLUMN IF NOT EXISTS fallback_user_id INTEGER REFERENCES users(id);
+ALTER TABLE schedules ADD COLUMN IF NOT EXISTS status_changed_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_schedules_status ON schedules(status);
+
+COMMENT ON COLUMN schedules.status IS 'active schedules rotate; paused ones do not; archived ones are hidden but kept for history';
+COMMENT ON COLUMN schedules.fallback_user_id IS 'User on call while the schedule is paused';
+COMMENT ON COLUMN schedules.status_changed_at IS 'When the status last changed; shifts before a resume are not backfilled';
@@ -0,0 +1,40 @@
+-- Schedule participants as an ordered join table, one roster per schedule version
+
+CREATE TABLE IF NOT EXISTS schedule_participants (
+    id SERIAL PRIMARY KEY,
+    schedule_version_id INTEGER NOT NULL REFERENCES schedule_versions(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    position INTEGER NOT NULL CHECK (position > 0),
+    weight DOUBLE PRECISION NOT NULL DEFAULT 1 CHECK (weight > 0),
+    tier INTEGER NOT NULL DEFAULT 1 CHECK (tier > 0),
+    active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (schedule_version_id, user_id),
+    UNIQUE (schedule_version_id, position)
+);
+
+CREATE INDEX IF NOT EXISTS idx_schedule_participants_user ON schedule_participants(user_id);
+
+-- Convert the comma-separated lists of existing versions, keeping their
+-- order and weights. IDs that don't parse or name no user are dropped, as
+-- are repeats of the same user.
+WITH listed AS (
+    SELECT DISTINCT ON (v.id, u.id) v.id AS version_id, u.id AS user_id, p.ordinality,
+        v.participant_weights ->> u.id::text AS weight
+    FROM schedule_versions v
+    CROSS JOIN LATERAL unnest(string_to_array(v.participant_ids, ',')) WITH ORDINALITY AS p(raw_id, ordinality)
+    JOIN users u ON u.id = CASE WHEN trim(p.raw_id) ~ '^[0-9]{1,9}$' THEN trim(p.raw_id)::int END
+    WHERE NOT EXISTS (SELECT 1 FROM schedule_participants sp WHERE sp.schedule_version_id = v.id)
+    ORDER BY v.id, u.id, p.ordinality
+)
+INSERT INTO schedule_participants (schedule_version_id, user_id, position, weight)
+SELECT version_id, user_id, ROW_NUMBER() OVER (PARTITION BY version_id ORDER BY ordinality),
+    COALESCE(NULLIF(weight, '')::double precision, 1)
+FROM listed;
+
+COMMENT ON TABLE schedule_participants IS 'Roster of each schedule version; active tier 1 participants rotate in position order';
+COMMENT ON COLUMN schedule_participants.tier IS 'Escalation tier; tier 1 takes rotation shifts, higher tiers back them up';
+COMMENT ON COLUMN schedule_versions.participant_ids IS 'Superseded by schedule_participants and no longer written';
+COMMENT ON COLUMN schedule_versions.participant_weights IS 'Superseded by schedule_participants.weight and no longer written';
+COMMENT ON COLUMN schedules.participant_ids IS 'Superseded by schedule_participants and no longer written';
+COMMENT ON COLUMN schedules.participant_weights IS 'Superseded by schedule_participants.weight and no longer written';
@@ -0,0 +1,20 @@
+-- Many-to-many team membership with roles
+
+CREATE TABLE IF NOT EXISTS team_users (
+    team_id INTEGER NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL DEFAULT 'member' CHECK (role IN ('member', 'manager')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (team_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_team_users_user ON team_users(user_id);
+
+-- Existing users become members of the team they were created in
+INSERT INTO team_users (team_id, user_id)
+SELECT team_id, id FROM users WHERE team_id IS NOT NULL
+ON CONFLICT (team_id, user_id) DO NOTHING;
+
+COMMENT ON TABLE team_users IS 'Team membership; a user can belong to several teams';
+COMMENT ON COLUMN team_users.role IS 'member or manager';
+COMMENT ON COLUMN users.team_id IS 'Superseded by team_users and no longer written';
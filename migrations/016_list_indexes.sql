@@ -0,0 +1,10 @@
+-- Indexes for paging through lists in each sort order (see pagination.go)
+
+CREATE INDEX IF NOT EXISTS idx_users_email_id ON users(email, id);
+CREATE INDEX IF NOT EXISTS idx_users_created_id ON users(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_teams_name_id ON teams(name, id);
+CREATE INDEX IF NOT EXISTS idx_teams_created_id ON teams(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_schedules_name_id ON schedules(name, id);
+CREATE INDEX IF NOT EXISTS idx_schedules_start_id ON schedules(start_time, id);
+CREATE INDEX IF NOT EXISTS idx_schedules_end_id ON schedules(end_time, id);
+CREATE INDEX IF NOT EXISTS idx_schedules_created_id ON schedules(created_at, id);
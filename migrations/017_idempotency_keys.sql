@@ -0,0 +1,17 @@
+-- Idempotency keys for retried POST requests (see idempotency.go)
+
+-- One row per Idempotency-Key. The row is claimed before the request runs,
+-- with status_code NULL, and holds the response once it has finished, so a
+-- retry with the same key gets the same response back. fingerprint is a hash
+-- of the method, path and body, so a key cannot be reused for a different
+-- request.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key VARCHAR(255) PRIMARY KEY,
+    fingerprint VARCHAR(64) NOT NULL,
+    status_code INTEGER,
+    content_type VARCHAR(255),
+    response_body BYTEA,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created ON idempotency_keys(created_at);
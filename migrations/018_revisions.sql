@@ -0,0 +1,9 @@
+-- Revisions for optimistic concurrency (see etag.go)
+
+-- Every edit of a user, team or schedule bumps its revision, and edits are
+-- only saved if the revision is still the one the client read. Schedules
+-- already have versions of their definition, so this is called a revision
+-- rather than a version.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS revision INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS revision INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE schedules ADD COLUMN IF NOT EXISTS revision INTEGER NOT NULL DEFAULT 1;
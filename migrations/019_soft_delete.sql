@@ -0,0 +1,12 @@
+-- Soft delete for users, teams and schedules (see purge.go)
+
+-- Deleting sets deleted_at and hides the row, so on-call history that
+-- refers to it stays intact and it can be restored. The purge job removes
+-- rows deleted longer ago than the retention period that no history needs.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE schedules ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_users_deleted ON users(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_teams_deleted ON teams(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_schedules_deleted ON schedules(deleted_at) WHERE deleted_at IS NOT NULL;
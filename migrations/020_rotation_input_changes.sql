@@ -0,0 +1,20 @@
+-- Count changes to rotation inputs, so replays can be checkpointed (see projection.go)
+
+-- A single row whose generation is bumped in the same transaction as every
+-- change to the tables the rotation is replayed from, by the triggers that
+-- already wake the scheduler (see 008_schedule_change_notify.sql). A replay
+-- checkpoint taken at one generation is discarded once it moves on.
+CREATE TABLE IF NOT EXISTS rotation_input_changes (
+    id INTEGER PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    generation BIGINT NOT NULL DEFAULT 0
+);
+
+INSERT INTO rotation_input_changes (id) VALUES (1) ON CONFLICT (id) DO NOTHING;
+
+CREATE OR REPLACE FUNCTION notify_schedule_change() RETURNS trigger AS $$
+BEGIN
+    UPDATE rotation_input_changes SET generation = generation + 1 WHERE id = 1;
+    PERFORM pg_notify('schedule_changes', TG_TABLE_NAME);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,15 @@
+-- Coverage gaps already alerted about (see coverage.go and scheduler.go)
+
+-- One row per gap, keyed by when it began, so its alert is posted once
+-- rather than on every scheduler pass or coverage check until it ends, and
+-- not again after a restart or a change of leader. Rows are cleared once
+-- their gap is over.
+CREATE TABLE IF NOT EXISTS coverage_gap_alerts (
+    schedule_id INTEGER NOT NULL REFERENCES schedules(id) ON DELETE CASCADE,
+    start_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    end_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (schedule_id, start_time)
+);
+
+CREATE INDEX IF NOT EXISTS idx_coverage_gap_alerts_end ON coverage_gap_alerts(end_time);
@@ -0,0 +1,5 @@
+-- ETag of stored idempotent responses (see idempotency.go)
+
+-- Creates and restores answer with the resource's ETag, which a replay must
+-- send back too
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS etag VARCHAR(64);
@@ -0,0 +1,20 @@
+-- Pause history of schedules (see projection.go)
+
+-- One row per pause with the user who covered it, so the shifts a fallback
+-- user took while a schedule was paused still project to them after it is
+-- resumed. resumed_at is NULL while the pause lasts; archiving a paused
+-- schedule ends its pause too.
+CREATE TABLE IF NOT EXISTS schedule_pauses (
+    id SERIAL PRIMARY KEY,
+    schedule_id INTEGER NOT NULL REFERENCES schedules(id),
+    paused_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    resumed_at TIMESTAMP WITH TIME ZONE,
+    fallback_user_id INTEGER REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_schedule_pauses_schedule ON schedule_pauses(schedule_id, paused_at);
+
+-- Earlier pauses were not recorded; carry over the ones still going on
+INSERT INTO schedule_pauses (schedule_id, paused_at, fallback_user_id)
+SELECT id, status_changed_at, fallback_user_id FROM schedules s
+WHERE status = 'paused' AND NOT EXISTS (SELECT 1 FROM schedule_pauses WHERE schedule_id = s.id);
@@ -0,0 +1,12 @@
+-- Email uniqueness among live users only (see handlers.go)
+
+-- A deleted user keeps their row until purged, which used to keep their
+-- address taken. Only users who are not deleted need distinct emails, so a
+-- deleted user's address can be given to someone new; restoring them while
+-- it is in use fails instead.
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_live ON users(email) WHERE deleted_at IS NULL;
+
+-- Superseded by team_users (see 015_team_users.sql) and no longer read or
+-- written; its foreign key would otherwise block purging a team.
+ALTER TABLE users DROP COLUMN IF EXISTS team_id;
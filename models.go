@@ -1,33 +1,141 @@
 package main
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type User struct {
-	ID          int       `json:"id"`
-	Email       string    `json:"email"`
-	SlackHandle string    `json:"slack_handle"`
-	TeamID      int       `json:"team_id"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID          int        `json:"id"`
+	Email       string     `json:"email"`
+	SlackHandle string     `json:"slack_handle"`
+	TeamIDs     []int      `json:"team_ids"` // teams the user is a member of
+	Revision    int        `json:"revision"` // bumped by every edit; the ETag
+	CreatedAt   time.Time  `json:"created_at"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"` // set while soft-deleted
 }
 
 type Team struct {
+	ID           int          `json:"id"`
+	Name         string       `json:"name"`
+	SlackChannel string       `json:"slack_channel"`
+	Members      []TeamMember `json:"members"`
+	Revision     int          `json:"revision"` // bumped by every edit; the ETag
+	CreatedAt    time.Time    `json:"created_at"`
+	DeletedAt    *time.Time   `json:"deleted_at,omitempty"` // set while soft-deleted
+}
+
+// TeamMember is a user's membership of a team. A user can be a member of
+// several teams, with a role in each.
+type TeamMember struct {
+	User
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// Roles a user can have in a team.
+const (
+	TeamRoleMember  = "member"
+	TeamRoleManager = "manager"
+)
+
+type Schedule struct {
 	ID        int       `json:"id"`
+	TeamID    int       `json:"team_id"`
 	Name      string    `json:"name"`
-	Users     []User    `json:"users"`
-	CreatedAt time.Time `json:"created_at"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	
+	// Lifecycle state; see the ScheduleStatus constants
+	Status          string    `json:"status"`
+	FallbackUserID  int       `json:"fallback_user_id,omitempty"` // on call while paused
+	StatusChangedAt time.Time `json:"status_changed_at"`
+	
+	// The definition in effect, which versions change over time
+	ScheduleDefinition
+	Version       int       `json:"version"`
+	EffectiveFrom time.Time `json:"effective_from"`
+	
+	Revision  int               `json:"revision"` // bumped by every edit; the ETag
+	CreatedAt time.Time         `json:"created_at"`
+	DeletedAt *time.Time        `json:"deleted_at,omitempty"` // set while soft-deleted
+	Versions  []ScheduleVersion `json:"-"`                    // every version, oldest first
+	Pauses    []SchedulePause   `json:"-"`                    // every pause, oldest first
 }
 
-type Schedule struct {
-	ID             int       `json:"id"`
-	TeamID         int       `json:"team_id"`
-	Name           string    `json:"name"`
-	StartTime      time.Time `json:"start_time"`
-	EndTime        time.Time `json:"end_time"`
-	RotationPeriod int       `json:"rotation_period"` // in seconds
-	Participants   []int     `json:"participants"`    // user IDs
-	CreatedAt      time.Time `json:"created_at"`
+// SchedulePause is a time a schedule was paused, with the user on call
+// meanwhile.
+type SchedulePause struct {
+	PausedAt       time.Time  `json:"paused_at"`
+	ResumedAt      *time.Time `json:"resumed_at,omitempty"` // nil while paused
+	FallbackUserID int        `json:"fallback_user_id,omitempty"`
+}
+
+// ScheduleDefinition is the part of a schedule that can be edited. Edits
+// create a new version rather than changing it in place.
+type ScheduleDefinition struct {
+	RotationPeriod     int                   `json:"rotation_period"`               // in seconds
+	Roster             []ScheduleParticipant `json:"roster"`                        // everyone on the schedule, in rotation order
+	Participants       []int                 `json:"participants"`                  // user IDs that rotate, derived from Roster
+	RotationStrategy   string                `json:"rotation_strategy"`             // see rotation.go
+	ParticipantWeights map[int]float64       `json:"participant_weights,omitempty"` // user ID to share, derived from Roster
+	Sequence           []int                 `json:"sequence,omitempty"`            // explicit user order, for fixed_sequence
+	HolidayMode        string                `json:"holiday_mode"`                  // none, skip, rotation or flag
+	HolidayScheduleID  int                   `json:"holiday_schedule_id,omitempty"` // covers holiday shifts in rotation mode
+	Timezone           string                `json:"timezone"`                      // IANA name, for showing shift times
+}
+
+// ScheduleParticipant is a member of a schedule's roster. Only active tier 1
+// participants take rotation shifts; higher tiers are the escalation
+// contacts behind them, and inactive participants are kept but skipped.
+type ScheduleParticipant struct {
+	UserID   int     `json:"user_id"`
+	Position int     `json:"position"` // 1-based rotation order
+	Weight   float64 `json:"weight"`   // share of shifts, for weighted
+	Tier     int     `json:"tier"`     // escalation tier, 1 is the rotation
+	Active   bool    `json:"active"`
 }
 
+// UnmarshalJSON makes participants active unless the request says otherwise.
+func (p *ScheduleParticipant) UnmarshalJSON(data []byte) error {
+	type plain ScheduleParticipant
+	participant := plain{Active: true}
+	if err := json.Unmarshal(data, &participant); err != nil {
+		return err
+	}
+	*p = ScheduleParticipant(participant)
+	return nil
+}
+
+// ScheduleVersion is a schedule definition in effect from EffectiveFrom
+// until the next version takes effect.
+type ScheduleVersion struct {
+	ID            int       `json:"id"`
+	ScheduleID    int       `json:"schedule_id"`
+	Version       int       `json:"version"`
+	EffectiveFrom time.Time `json:"effective_from"`
+	ScheduleDefinition
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Schedule lifecycle states. Only active schedules rotate. A paused schedule
+// sends no notifications and, if it has a fallback user, is covered by them
+// until it is resumed. An archived schedule is hidden from listings and can
+// no longer change, but its assignments are kept for history.
+const (
+	ScheduleStatusActive   = "active"
+	ScheduleStatusPaused   = "paused"
+	ScheduleStatusArchived = "archived"
+)
+
+// Holiday handling modes for a schedule.
+const (
+	HolidayModeNone     = "none"
+	HolidayModeSkip     = "skip"
+	HolidayModeRotation = "rotation"
+	HolidayModeFlag     = "flag"
+)
+
 type OnCallAssignment struct {
 	ID         int       `json:"id"`
 	ScheduleID int       `json:"schedule_id"`
@@ -35,5 +143,104 @@ type OnCallAssignment struct {
 	StartTime  time.Time `json:"start_time"`
 	EndTime    time.Time `json:"end_time"`
 	Timezone   string    `json:"timezone"`
+	Role       string    `json:"role"` // primary or shadow
+	Tier       int       `json:"tier"` // escalation tier, 1 is the first responder
+	Holiday    bool      `json:"holiday"`
 	Active     bool      `json:"active"`
-}
\ No newline at end of file
+	Backfilled bool      `json:"backfilled"` // reconstructed after downtime
+}
+
+// Assignment roles. A shadow is paired with the primary on-caller for
+// training and is never treated as the current on-call person.
+const (
+	AssignmentRolePrimary = "primary"
+	AssignmentRoleShadow  = "shadow"
+)
+
+type ShadowShift struct {
+	ID         int       `json:"id"`
+	ScheduleID int       `json:"schedule_id"`
+	UserID     int       `json:"user_id"`
+	StartTime  time.Time `json:"start_time"`
+	EndTime    time.Time `json:"end_time"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type Unavailability struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Holiday struct {
+	ID        int       `json:"id"`
+	TeamID    int       `json:"team_id"`
+	Date      time.Time `json:"date"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ProjectedShift is an upcoming shift as the rotation expects to fill it.
+// UserID is 0 when nobody will be on call, e.g. a skipped holiday.
+type ProjectedShift struct {
+	ScheduleID  int       `json:"schedule_id"`
+	UserID      int       `json:"user_id"`
+	StartTime   time.Time `json:"start_time"`
+	EndTime     time.Time `json:"end_time"`
+	Holiday     bool      `json:"holiday"`
+	HolidayName string    `json:"holiday_name,omitempty"`
+	Skipped     bool      `json:"skipped"`
+	Paused      bool      `json:"paused"` // covered by the fallback user, if any
+}
+
+// CoverageGap is a window in which a schedule has nobody on call.
+type CoverageGap struct {
+	ScheduleID   int       `json:"schedule_id"`
+	ScheduleName string    `json:"schedule_name"`
+	TeamID       int       `json:"team_id"`
+	StartTime    time.Time `json:"start_time"`
+	EndTime      time.Time `json:"end_time"`
+	Reason       string    `json:"reason"`
+	
+	// firstStart is when the gap began, before StartTime was clipped to the
+	// window checked, so the same gap is recognized from one check to the
+	// next. Zero when StartTime was not clipped.
+	firstStart time.Time
+}
+
+// alertStart identifies the gap among those already alerted about.
+func (g CoverageGap) alertStart() time.Time {
+	if g.firstStart.IsZero() {
+		return g.StartTime
+	}
+	return g.firstStart
+}
+
+// Reasons a coverage gap exists.
+const (
+	GapReasonScheduleEnds        = "schedule_ends"
+	GapReasonNoParticipants      = "no_participants"
+	GapReasonInvalidParticipants = "invalid_participants"
+	GapReasonParticipantLeft     = "participant_left_team"
+	GapReasonUnfilledPTO         = "unfilled_pto"
+	GapReasonPaused              = "schedule_paused"
+)
+
+type OnCallHoursReport struct {
+	UserID       int     `json:"user_id"`
+	Email        string  `json:"email"`
+	PrimaryHours float64 `json:"primary_hours"`
+	ShadowHours  float64 `json:"shadow_hours"`
+	HolidayHours float64 `json:"holiday_hours"` // primary hours on holiday shifts
+}
+
+type LeaderLease struct {
+	Name         string    `json:"name"`
+	Holder       string    `json:"holder"`
+	FencingToken int64     `json:"fencing_token"`
+	AcquiredAt   time.Time `json:"acquired_at"`
+	RenewedAt    time.Time `json:"renewed_at"`
+}
@@ -0,0 +1,504 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The API is described by apiRoutes, which main registers under apiPrefix
+// and openAPISpec turns into an OpenAPI 3 document. Request and response
+// schemas are read from the Go types by reflection, so the spec follows the
+// models as they change. The client package is written against it.
+
+// apiVersion is the version of the API in the spec. Breaking changes get a
+// new apiPrefix, and the routes keep being served under the old one.
+const (
+	apiVersion = "1.0.0"
+	apiPrefix  = "/api/v1"
+)
+
+// apiRoute is one endpoint of the JSON API.
+type apiRoute struct {
+	method  string
+	path    string // a gorilla/mux path template
+	handler http.HandlerFunc
+
+	id      string // operationId, and the name of the client method
+	tag     string
+	summary string
+
+	query        []queryParam
+	sorts        map[string]sortField // the sort fields of a paged list
+	request      interface{}          // the body's type, or nil
+	bodyType     string               // media type of a body that isn't JSON
+	optionalBody bool
+	response     interface{} // the type of the 200 response
+
+	etag    bool         // the response has the resource's ETag
+	ifMatch precondition // and edits check it (see etag.go)
+}
+
+// precondition is whether a route checks If-Match.
+type precondition int
+
+const (
+	noIfMatch precondition = iota
+	requiredIfMatch
+)
+
+type queryParam struct {
+	name        string
+	schema      string // integer, string or boolean
+	format      string
+	description string
+}
+
+// oneOf is a response that is one of several types.
+type oneOf []interface{}
+
+// The responses that handlers write as maps, described for the spec.
+type idResponse struct {
+	ID      int    `json:"id"`
+	Message string `json:"message"`
+}
+
+type teamMemberResponse struct {
+	ID      int    `json:"id"` // the team
+	UserID  int    `json:"user_id"`
+	Role    string `json:"role,omitempty"`
+	Message string `json:"message"`
+}
+
+type scheduleEditResponse struct {
+	ID            int        `json:"id"`
+	Revision      int        `json:"revision"`
+	Version       int        `json:"version,omitempty"`
+	EffectiveFrom *time.Time `json:"effective_from,omitempty"`
+	Message       string     `json:"message"`
+}
+
+type scheduleStatusResponse struct {
+	ID       int    `json:"id"`
+	Revision int    `json:"revision"`
+	Status   string `json:"status"`
+	Message  string `json:"message"`
+}
+
+type importResponse struct {
+	Imported int    `json:"imported"`
+	Message  string `json:"message"`
+}
+
+type leaderStatusResponse struct {
+	InstanceID string       `json:"instance_id"`
+	IsLeader   bool         `json:"is_leader"`
+	Lease      *LeaderLease `json:"lease"`
+}
+
+// schedulePatch is the body of PATCH /schedules/{id}: the fields
+// patchScheduleHandler reads itself, and any definition fields.
+type schedulePatch struct {
+	schedulePatchRequest
+	ScheduleDefinition
+}
+
+var teamIDParam = queryParam{name: "team_id", schema: "integer", description: "Only those in this team"}
+
+var includeDeletedParam = queryParam{name: "include_deleted", schema: "boolean", description: "Include deleted ones"}
+
+var daysParam = queryParam{name: "days", schema: "integer", description: "How many days ahead to look, from 1 to 366"}
+
+var effectiveFromParam = queryParam{name: "effective_from", schema: "string", format: "datetime-local",
+	description: "When the new version takes effect; defaults to the next handoff"}
+
+// apiRoutes returns every endpoint of the JSON API.
+func apiRoutes() []apiRoute {
+	return []apiRoute{
+		{method: "POST", path: "/users", handler: createUserHandler, id: "createUser", tag: "users",
+			summary: "Create a user, optionally as a member of team_id", request: createUserRequest{}, response: idResponse{}},
+		{method: "GET", path: "/users", handler: getUsersHandler, id: "listUsers", tag: "users",
+			summary: "List users", sorts: userSortFields, response: []User{},
+			query: []queryParam{teamIDParam, {name: "email", schema: "string", description: "Email contains this, ignoring case"},
+				includeDeletedParam}},
+		{method: "GET", path: "/users/{id}", handler: getUserHandler, id: "getUser", tag: "users",
+			summary: "Get a user", response: User{}, etag: true},
+		{method: "PUT", path: "/users/{id}", handler: updateUserHandler, id: "replaceUser", tag: "users",
+			summary: "Replace a user's email and Slack handle", request: userUpdate{}, response: User{},
+			ifMatch: requiredIfMatch},
+		{method: "PATCH", path: "/users/{id}", handler: patchUserHandler, id: "updateUser", tag: "users",
+			summary: "Change the given fields of a user", request: userUpdate{}, response: User{}, ifMatch: requiredIfMatch},
+		{method: "DELETE", path: "/users/{id}", handler: deleteUserHandler, id: "deleteUser", tag: "users",
+			summary: "Delete a user who is on no schedule's roster, until restored", response: idResponse{}},
+		{method: "POST", path: "/users/{id}/restore", handler: restoreUserHandler, id: "restoreUser", tag: "users",
+			summary: "Restore a deleted user", response: User{}, etag: true},
+
+		{method: "POST", path: "/teams", handler: createTeamHandler, id: "createTeam", tag: "teams",
+			summary: "Create a team", request: createTeamRequest{}, response: idResponse{}},
+		{method: "GET", path: "/teams", handler: getTeamsHandler, id: "listTeams", tag: "teams",
+			summary: "List teams with their members", sorts: teamSortFields, response: []Team{},
+			query: []queryParam{{name: "name", schema: "string", description: "Name contains this, ignoring case"},
+				includeDeletedParam}},
+		{method: "GET", path: "/teams/{id}", handler: getTeamHandler, id: "getTeam", tag: "teams",
+			summary: "Get a team with its members", response: Team{}, etag: true},
+		{method: "PUT", path: "/teams/{id}", handler: updateTeamHandler, id: "replaceTeam", tag: "teams",
+			summary: "Replace a team's name and Slack channel", request: teamUpdate{}, response: Team{},
+			ifMatch: requiredIfMatch},
+		{method: "PATCH", path: "/teams/{id}", handler: patchTeamHandler, id: "updateTeam", tag: "teams",
+			summary: "Change the given fields of a team", request: teamUpdate{}, response: Team{}, ifMatch: requiredIfMatch},
+		{method: "DELETE", path: "/teams/{id}", handler: deleteTeamHandler, id: "deleteTeam", tag: "teams",
+			summary: "Delete a team that has no schedules, until restored", response: idResponse{}},
+		{method: "POST", path: "/teams/{id}/restore", handler: restoreTeamHandler, id: "restoreTeam", tag: "teams",
+			summary: "Restore a deleted team", response: Team{}, etag: true},
+		{method: "POST", path: "/teams/{id}/members", handler: addTeamMemberHandler, id: "addTeamMember", tag: "teams",
+			summary: "Add a user to a team, or change their role", request: teamMemberRequest{}, response: teamMemberResponse{}},
+		{method: "DELETE", path: "/teams/{id}/members/{userID:[0-9]+}", handler: removeTeamMemberHandler, id: "removeTeamMember",
+			tag: "teams", summary: "Remove a user from a team", response: teamMemberResponse{}},
+		{method: "POST", path: "/teams/{id}/holidays", handler: createHolidayHandler, id: "createHoliday", tag: "holidays",
+			summary: "Add a holiday to a team's calendar", request: holidayRequest{}, response: idResponse{}},
+		{method: "GET", path: "/teams/{id}/holidays", handler: getHolidaysHandler, id: "listHolidays", tag: "holidays",
+			summary: "List a team's holidays", response: []Holiday{}},
+		{method: "POST", path: "/teams/{id}/holidays/import", handler: importHolidaysHandler, id: "importHolidays", tag: "holidays",
+			summary: "Import the all-day events of an iCalendar file as holidays", bodyType: "text/calendar", response: importResponse{}},
+		{method: "DELETE", path: "/teams/{id}/holidays/{holidayID}", handler: deleteHolidayHandler, id: "deleteHoliday",
+			tag: "holidays", summary: "Delete a holiday", response: idResponse{}},
+
+		{method: "POST", path: "/schedules", handler: createScheduleHandler, id: "createSchedule", tag: "schedules",
+			summary: "Create a schedule", request: createScheduleRequest{}, response: idResponse{}},
+		{method: "GET", path: "/schedules", handler: getSchedulesHandler, id: "listSchedules", tag: "schedules",
+			summary: "List schedules", sorts: scheduleSortFields, response: []Schedule{},
+			query: []queryParam{teamIDParam,
+				{name: "name", schema: "string", description: "Name contains this, ignoring case"},
+				{name: "active", schema: "boolean", description: "true for active schedules, false for paused ones"},
+				{name: "include_archived", schema: "boolean", description: "Include archived schedules"},
+				includeDeletedParam}},
+		{method: "GET", path: "/schedules/{id}", handler: getScheduleHandler, id: "getSchedule", tag: "schedules",
+			summary: "Get a schedule with the definition in effect", response: Schedule{}, etag: true},
+		{method: "PUT", path: "/schedules/{id}", handler: updateScheduleHandler, id: "replaceSchedule", tag: "schedules",
+			summary: "Record a new version of a schedule's definition", request: scheduleUpdateRequest{}, response: scheduleEditResponse{},
+			ifMatch: requiredIfMatch},
+		{method: "PATCH", path: "/schedules/{id}", handler: patchScheduleHandler, id: "updateSchedule", tag: "schedules",
+			summary: "Change the given fields of a schedule; definition fields make a new version",
+			request: schedulePatch{}, response: scheduleEditResponse{}, ifMatch: requiredIfMatch},
+		{method: "DELETE", path: "/schedules/{id}", handler: deleteScheduleHandler, id: "deleteSchedule", tag: "schedules",
+			summary: "Delete a schedule, keeping its on-call history, until restored", response: idResponse{}},
+		{method: "POST", path: "/schedules/{id}/restore", handler: restoreScheduleHandler, id: "restoreSchedule",
+			tag: "schedules", summary: "Restore a deleted schedule whose team is not deleted", response: Schedule{}, etag: true},
+		{method: "GET", path: "/schedules/{id}/versions", handler: getScheduleVersionsHandler, id: "listScheduleVersions",
+			tag: "schedules", summary: "List a schedule's versions, or with at only the one in effect then",
+			query:    []queryParam{{name: "at", schema: "string", format: "datetime-local"}},
+			response: oneOf{[]ScheduleVersion{}, ScheduleVersion{}}},
+		{method: "POST", path: "/schedules/{id}/pause", handler: pauseScheduleHandler, id: "pauseSchedule", tag: "schedules",
+			summary: "Pause a schedule", request: pauseRequest{}, optionalBody: true, response: scheduleStatusResponse{},
+			ifMatch: requiredIfMatch},
+		{method: "POST", path: "/schedules/{id}/resume", handler: resumeScheduleHandler, id: "resumeSchedule", tag: "schedules",
+			summary: "Resume a paused schedule", response: scheduleStatusResponse{}, ifMatch: requiredIfMatch},
+		{method: "POST", path: "/schedules/{id}/archive", handler: archiveScheduleHandler, id: "archiveSchedule", tag: "schedules",
+			summary: "Archive a schedule for good", response: scheduleStatusResponse{}, ifMatch: requiredIfMatch},
+		{method: "POST", path: "/schedules/{id}/participants", handler: addScheduleParticipantHandler, id: "addScheduleParticipant",
+			tag: "schedules", summary: "Add a participant to the roster, as a new version",
+			request: addParticipantRequest{}, response: scheduleEditResponse{}, ifMatch: requiredIfMatch},
+		{method: "PUT", path: "/schedules/{id}/participants/order", handler: reorderScheduleParticipantsHandler,
+			id: "reorderScheduleParticipants", tag: "schedules", summary: "Set the rotation order, as a new version",
+			request: reorderParticipantsRequest{}, response: scheduleEditResponse{}, ifMatch: requiredIfMatch},
+		{method: "PUT", path: "/schedules/{id}/participants/{userID:[0-9]+}", handler: updateScheduleParticipantHandler,
+			id: "updateScheduleParticipant", tag: "schedules", summary: "Change a participant, as a new version",
+			request: participantUpdate{}, response: scheduleEditResponse{}, ifMatch: requiredIfMatch},
+		{method: "DELETE", path: "/schedules/{id}/participants/{userID:[0-9]+}", handler: removeScheduleParticipantHandler,
+			id: "removeScheduleParticipant", tag: "schedules", summary: "Remove a participant from the roster, as a new version",
+			query: []queryParam{effectiveFromParam}, response: scheduleEditResponse{}, ifMatch: requiredIfMatch},
+		{method: "GET", path: "/schedules/{id}/projection", handler: getScheduleProjectionHandler, id: "getScheduleProjection",
+			tag: "schedules", summary: "Project the upcoming shifts (14 days by default)",
+			query: []queryParam{daysParam}, response: []ProjectedShift{}},
+		{method: "POST", path: "/schedules/{id}/shadows", handler: createShadowShiftHandler, id: "createShadowShift",
+			tag: "schedules", summary: "Pair a shadow with the on-caller", request: shadowShiftRequest{}, response: idResponse{}},
+		{method: "GET", path: "/schedules/{id}/shadows", handler: getShadowShiftsHandler, id: "listShadowShifts",
+			tag: "schedules", summary: "List a schedule's shadow shifts", response: []ShadowShift{}},
+
+		{method: "POST", path: "/unavailability", handler: createUnavailabilityHandler, id: "createUnavailability",
+			tag: "unavailability", summary: "Record time off", request: unavailabilityRequest{}, response: idResponse{}},
+		{method: "GET", path: "/unavailability", handler: getUnavailabilityHandler, id: "listUnavailability",
+			tag: "unavailability", summary: "List time off", response: []Unavailability{}},
+		{method: "DELETE", path: "/unavailability/{id}", handler: deleteUnavailabilityHandler, id: "deleteUnavailability",
+			tag: "unavailability", summary: "Delete time off", response: idResponse{}},
+
+		{method: "GET", path: "/reports/oncall-hours", handler: getOnCallHoursReportHandler, id: "getOnCallHoursReport",
+			tag: "reports", summary: "Report on-call hours per user (the last 30 days by default)",
+			query:    []queryParam{{name: "from", schema: "string", format: "date"}, {name: "to", schema: "string", format: "date"}},
+			response: []OnCallHoursReport{}},
+		{method: "GET", path: "/coverage/gaps", handler: getCoverageGapsHandler, id: "listCoverageGaps", tag: "reports",
+			summary: "List windows in which a schedule has nobody on call", query: []queryParam{daysParam},
+			response: []CoverageGap{}},
+		{method: "GET", path: "/leader", handler: getLeaderStatusHandler, id: "getLeaderStatus", tag: "operations",
+			summary: "Show which replica runs the scheduler", response: leaderStatusResponse{}},
+		{method: "GET", path: "/openapi.json", handler: openAPIHandler, id: "getOpenAPISpec", tag: "operations",
+			summary: "This document", response: map[string]interface{}{}},
+	}
+}
+
+// schemaEnums lists the allowed values of string fields, by type and JSON
+// field name, from the constants the handlers check against.
+func schemaEnums() map[string][]string {
+	strategies := make([]string, 0, len(rotationStrategies))
+	for name := range rotationStrategies {
+		strategies = append(strategies, name)
+	}
+	sort.Strings(strategies)
+
+	roles := []string{TeamRoleMember, TeamRoleManager}
+	return map[string][]string{
+		"TeamMember.role":                      roles,
+		"teamMemberRequest.role":               roles,
+		"Schedule.status":                      {ScheduleStatusActive, ScheduleStatusPaused, ScheduleStatusArchived},
+		"scheduleStatusResponse.status":        {ScheduleStatusActive, ScheduleStatusPaused, ScheduleStatusArchived},
+		"ScheduleDefinition.rotation_strategy": strategies,
+		"ScheduleDefinition.holiday_mode":      {HolidayModeNone, HolidayModeSkip, HolidayModeRotation, HolidayModeFlag},
+		"CoverageGap.reason": {GapReasonScheduleEnds, GapReasonNoParticipants, GapReasonInvalidParticipants,
+			GapReasonParticipantLeft, GapReasonUnfilledPTO, GapReasonPaused},
+	}
+}
+
+func openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(openAPISpec(apiRoutes()))
+}
+
+var pathVariable = regexp.MustCompile(`\{(\w+)(:[^}]*)?\}`)
+
+// openAPISpec describes routes as an OpenAPI 3 document.
+func openAPISpec(routes []apiRoute) map[string]interface{} {
+	schemas := &schemaBuilder{schemas: make(map[string]interface{}), enums: schemaEnums()}
+	errorResponse := map[string]interface{}{
+		"description": "An error; see the code",
+		"content":     jsonContent(schemas.schemaOf(reflect.TypeOf(APIError{}))),
+	}
+
+	paths := make(map[string]map[string]interface{})
+	for _, route := range routes {
+		var parameters []interface{}
+		for _, match := range pathVariable.FindAllStringSubmatch(route.path, -1) {
+			parameters = append(parameters, map[string]interface{}{
+				"name": match[1], "in": "path", "required": true,
+				"schema": map[string]interface{}{"type": "integer"},
+			})
+		}
+		query := route.query
+		if route.sorts != nil {
+			query = append(query, pageParams(route.sorts)...)
+		}
+		for _, param := range query {
+			schema := map[string]interface{}{"type": param.schema}
+			if param.format != "" {
+				schema["format"] = param.format
+			}
+			parameter := map[string]interface{}{"name": param.name, "in": "query", "schema": schema}
+			if param.description != "" {
+				parameter["description"] = param.description
+			}
+			parameters = append(parameters, parameter)
+		}
+		if route.ifMatch != noIfMatch {
+			parameters = append(parameters, map[string]interface{}{
+				"name": "If-Match", "in": "header", "required": route.ifMatch == requiredIfMatch,
+				"schema":      map[string]interface{}{"type": "string"},
+				"description": "The ETag the edit is based on; 412 if the resource has changed since",
+			})
+		}
+		if route.method == http.MethodPost {
+			parameters = append(parameters, map[string]interface{}{
+				"name": "Idempotency-Key", "in": "header", "schema": map[string]interface{}{"type": "string", "maxLength": maxIdempotencyKeyLength},
+				"description": "Retries with the same key get the first response back, with Idempotent-Replayed: true, for 24 hours",
+			})
+		}
+
+		success := map[string]interface{}{"description": "OK", "content": jsonContent(schemas.responseSchema(route.response))}
+		if route.sorts != nil {
+			success["headers"] = map[string]interface{}{
+				"X-Next-Cursor": map[string]interface{}{"description": "The cursor of the next page, if there is one",
+					"schema": map[string]interface{}{"type": "string"}},
+				"Link": map[string]interface{}{"description": "The URL of the next page, with rel=\"next\"",
+					"schema": map[string]interface{}{"type": "string"}},
+			}
+		}
+		if route.etag || route.ifMatch != noIfMatch {
+			success["headers"] = map[string]interface{}{
+				"ETag": map[string]interface{}{"description": "The resource's revision, to send back in If-Match",
+					"schema": map[string]interface{}{"type": "string"}},
+			}
+		}
+
+		operation := map[string]interface{}{
+			"operationId": route.id,
+			"summary":     route.summary,
+			"tags":        []string{route.tag},
+			"responses":   map[string]interface{}{"200": success, "default": errorResponse},
+		}
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+		switch {
+		case route.bodyType != "":
+			operation["requestBody"] = map[string]interface{}{"required": true, "content": map[string]interface{}{
+				route.bodyType: map[string]interface{}{"schema": map[string]interface{}{"type": "string"}},
+			}}
+		case route.request != nil:
+			operation["requestBody"] = map[string]interface{}{"required": !route.optionalBody,
+				"content": jsonContent(schemas.schemaOf(reflect.TypeOf(route.request)))}
+		}
+
+		path := pathVariable.ReplaceAllString(route.path, "{$1}")
+		if paths[path] == nil {
+			paths[path] = make(map[string]interface{})
+		}
+		paths[path][strings.ToLower(route.method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "go-oncall",
+			"version":     apiVersion,
+			"description": "On-call scheduling with rotations, PTO, holidays and Slack notifications.",
+		},
+		"servers":    []interface{}{map[string]interface{}{"url": apiPrefix}},
+		"paths":      paths,
+		"components": map[string]interface{}{"schemas": schemas.schemas},
+	}
+}
+
+// pageParams are the query parameters of a paged list (see pagination.go).
+func pageParams(sorts map[string]sortField) []queryParam {
+	names := make([]string, 0, len(sorts))
+	for name := range sorts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return []queryParam{
+		{name: "limit", schema: "integer", description: "Rows per page, at most 500; defaults to 100"},
+		{name: "sort", schema: "string",
+			description: "One of " + strings.Join(names, ", ") + "; prefix with - for descending order"},
+		{name: "cursor", schema: "string", description: "The X-Next-Cursor of the previous page"},
+	}
+}
+
+func jsonContent(schema interface{}) map[string]interface{} {
+	return map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+}
+
+// schemaBuilder makes JSON schemas from Go types. Named structs become
+// components, referred to by their name with its first letter capitalised.
+//
+// Struct fields follow encoding/json, and can add to their schema with an
+// openapi tag: required, format=<format>, or datetime-local for the
+// YYYY-MM-DDTHH:MM times requests use, which are read as UTC.
+type schemaBuilder struct {
+	schemas map[string]interface{}
+	enums   map[string][]string
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (b *schemaBuilder) responseSchema(response interface{}) map[string]interface{} {
+	if alternatives, ok := response.(oneOf); ok {
+		schemas := make([]interface{}, len(alternatives))
+		for i, alternative := range alternatives {
+			schemas[i] = b.schemaOf(reflect.TypeOf(alternative))
+		}
+		return map[string]interface{}{"oneOf": schemas}
+	}
+	return b.schemaOf(reflect.TypeOf(response))
+}
+
+func (b *schemaBuilder) schemaOf(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+		if _, ok := b.schemas[name]; !ok {
+			b.schemas[name] = nil // in case the type refers to itself
+			b.schemas[name] = b.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": b.schemaOf(t.Elem())}
+	case reflect.Map:
+		// Maps with integer keys are encoded with the keys as strings
+		return map[string]interface{}{"type": "object", "additionalProperties": b.schemaOf(t.Elem())}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	}
+	return map[string]interface{}{}
+}
+
+func (b *schemaBuilder) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	var required []string
+	b.addFields(t, properties, &required)
+
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// addFields adds the JSON fields of struct type t, including those of
+// embedded structs, as encoding/json would encode them.
+func (b *schemaBuilder) addFields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			b.addFields(field.Type, properties, required)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema := b.schemaOf(field.Type)
+		for _, option := range strings.Split(field.Tag.Get("openapi"), ",") {
+			switch {
+			case option == "required":
+				*required = append(*required, name)
+			case option == "datetime-local":
+				schema["format"] = "datetime-local"
+				schema["pattern"] = `^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}$`
+			case strings.HasPrefix(option, "format="):
+				schema["format"] = strings.TrimPrefix(option, "format=")
+			}
+		}
+		if values, ok := b.enums[t.Name()+"."+name]; ok {
+			schema["enum"] = values
+		}
+		properties[name] = schema
+	}
+}
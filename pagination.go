@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// List endpoints return a page of rows at a time, ordered by a sort field
+// and then by ID. The next page starts after the last row of this one: its
+// cursor is returned in the X-Next-Cursor and Link headers and passed back
+// as ?cursor=. Because pages are found by position in the order rather than
+// by offset, rows added or removed meanwhile never shift a page.
+const (
+	defaultPageSize = 100
+	maxPageSize     = 500
+)
+
+// sortField is a field a list can be sorted by: the SQL expression and the
+// Postgres type its cursor values are cast to.
+type sortField struct {
+	column string
+	cast   string
+}
+
+// validValue reports whether a cursor value casts to the field's type, so a
+// tampered cursor is rejected before it reaches Postgres.
+func (f sortField) validValue(value string) bool {
+	switch f.cast {
+	case "integer":
+		_, err := strconv.ParseInt(value, 10, 32)
+		return err == nil
+	case "timestamptz":
+		_, err := time.Parse(time.RFC3339Nano, value)
+		return err == nil
+	default:
+		return utf8.ValidString(value) && !strings.ContainsRune(value, 0)
+	}
+}
+
+// pageRequest is a list request's ?limit=, ?sort= and ?cursor=.
+type pageRequest struct {
+	Limit int
+	Sort  string
+	Desc  bool
+	After *pageCursor
+}
+
+// pageCursor marks the last row of a page: its value for the sort field and
+// its ID. It is sent to clients as opaque base64.
+type pageCursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d,omitempty"`
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+// parsePageRequest reads the paging parameters. sort is a field name,
+// prefixed with - for descending order; a cursor only continues the sort it
+// was made for.
+func parsePageRequest(r *http.Request, fields map[string]sortField, defaultSort string) (pageRequest, error) {
+	query := r.URL.Query()
+	page := pageRequest{Limit: defaultPageSize, Sort: defaultSort}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return page, fieldError("limit", FieldOutOfRange, fmt.Sprintf("Must be between 1 and %d", maxPageSize))
+		}
+		page.Limit = limit
+	}
+
+	if v := query.Get("sort"); v != "" {
+		page.Desc = strings.HasPrefix(v, "-")
+		page.Sort = strings.TrimPrefix(v, "-")
+	}
+	if _, ok := fields[page.Sort]; !ok {
+		return page, fieldError("sort", FieldInvalidChoice, fmt.Sprintf("Cannot sort by %q", page.Sort))
+	}
+
+	if v := query.Get("cursor"); v != "" {
+		cursor, err := decodePageCursor(v, fields)
+		if err != nil {
+			return page, fieldError("cursor", FieldInvalid, "Is not a cursor from this API")
+		}
+		if cursor.Sort != page.Sort || cursor.Desc != page.Desc {
+			return page, fieldError("cursor", FieldInvalid, "Belongs to a different sort order")
+		}
+		page.After = cursor
+	}
+	return page, nil
+}
+
+// decodePageCursor decodes a cursor, checking that its value fits the field
+// it sorts by.
+func decodePageCursor(encoded string, fields map[string]sortField) (*pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	var cursor pageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+	field, ok := fields[cursor.Sort]
+	if !ok || !field.validValue(cursor.Value) {
+		return nil, fmt.Errorf("invalid cursor value %q for %q", cursor.Value, cursor.Sort)
+	}
+	return &cursor, nil
+}
+
+func (c pageCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// listQuery builds the WHERE clause of a list query, numbering its
+// arguments.
+type listQuery struct {
+	conditions []string
+	args       []interface{}
+}
+
+// arg adds an argument and returns its placeholder.
+func (q *listQuery) arg(value interface{}) string {
+	q.args = append(q.args, value)
+	return "$" + strconv.Itoa(len(q.args))
+}
+
+func (q *listQuery) where(condition string) {
+	q.conditions = append(q.conditions, condition)
+}
+
+// page returns the query for one page of base, a SELECT without WHERE or
+// ORDER BY clauses. It asks for one row more than the limit, so the caller
+// can tell whether there is a next page.
+func (q *listQuery) page(base string, page pageRequest, fields map[string]sortField) string {
+	field := fields[page.Sort]
+	direction, compare := "ASC", ">"
+	if page.Desc {
+		direction, compare = "DESC", "<"
+	}
+
+	if page.After != nil {
+		q.where(fmt.Sprintf("(%s, id) %s (%s::%s, %s)", field.column, compare,
+			q.arg(page.After.Value), field.cast, q.arg(page.After.ID)))
+	}
+
+	query := base
+	if len(q.conditions) > 0 {
+		query += " WHERE " + strings.Join(q.conditions, " AND ")
+	}
+	return fmt.Sprintf("%s ORDER BY %s %s, id %s LIMIT %s", query, field.column, direction, direction, q.arg(page.Limit+1))
+}
+
+// cursorAfter returns the cursor of the page after the row with the given
+// sort value and ID.
+func (p pageRequest) cursorAfter(value string, id int) *pageCursor {
+	return &pageCursor{Sort: p.Sort, Desc: p.Desc, Value: value, ID: id}
+}
+
+// setNextPageHeaders points the client at the next page, if there is one.
+func setNextPageHeaders(w http.ResponseWriter, r *http.Request, next *pageCursor) {
+	if next == nil {
+		return
+	}
+	cursor := next.encode()
+
+	nextURL := *r.URL
+	query := nextURL.Query()
+	query.Set("cursor", cursor)
+	nextURL.RawQuery = query.Encode()
+
+	w.Header().Set("X-Next-Cursor", cursor)
+	w.Header().Add("Link", fmt.Sprintf("<%s>; rel=\"next\"", nextURL.RequestURI()))
+}
+
+// cursorTime formats a timestamp sort value for a cursor without losing
+// precision.
+func cursorTime(t time.Time) string {
+	return t.Format(time.RFC3339Nano)
+}
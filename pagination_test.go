@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParsePageRequestCursor(t *testing.T) {
+	first, err := parsePageRequest(httptest.NewRequest("GET", "/schedules?sort=-start_time&limit=2", nil), scheduleSortFields, "id")
+	if err != nil {
+		t.Fatalf("parsePageRequest() error = %v", err)
+	}
+	next := first.cursorAfter(cursorTime(monday.Add(1500*time.Millisecond)), 7).encode()
+
+	page, err := parsePageRequest(httptest.NewRequest("GET", "/schedules?sort=-start_time&limit=2&cursor="+next, nil), scheduleSortFields, "id")
+	if err != nil {
+		t.Fatalf("parsePageRequest() with its own cursor error = %v", err)
+	}
+	if page.After == nil || page.After.ID != 7 || page.After.Value != "2024-03-04T00:00:01.5Z" || !page.Desc || page.Limit != 2 {
+		t.Errorf("page = %+v, after = %+v", page, page.After)
+	}
+
+	tests := []struct {
+		name   string
+		sort   string
+		cursor string
+	}{
+		{"garbage", "id", "not-a-cursor"},
+		{"other sort", "id", next},
+		{"text for an integer", "id", pageCursor{Sort: "id", Value: "abc", ID: 1}.encode()},
+		{"out of range integer", "id", pageCursor{Sort: "id", Value: "99999999999", ID: 1}.encode()},
+		{"text for a timestamp", "-start_time", pageCursor{Sort: "start_time", Desc: true, Value: "yesterday", ID: 1}.encode()},
+		{"NUL in text", "name", pageCursor{Sort: "name", Value: "a\x00b", ID: 1}.encode()},
+		{"unknown sort", "id", pageCursor{Sort: "nope", Value: "1", ID: 1}.encode()},
+	}
+	for _, test := range tests {
+		_, err := parsePageRequest(httptest.NewRequest("GET", "/schedules?sort="+test.sort+"&cursor="+test.cursor, nil), scheduleSortFields, "id")
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.Code != ErrCodeValidation || len(apiErr.Fields) != 1 ||
+			apiErr.Fields[0].Field != "cursor" || apiErr.Fields[0].Code != FieldInvalid {
+			t.Errorf("%s: error = %#v, want a cursor field error", test.name, err)
+		}
+	}
+}
+
+func TestListQueryPage(t *testing.T) {
+	var q listQuery
+	q.where("deleted_at IS NULL")
+	page := pageRequest{Limit: 10, Sort: "name", After: &pageCursor{Sort: "name", Value: "ops", ID: 4}}
+	got := q.page("SELECT id FROM teams", page, teamSortFields)
+	want := "SELECT id FROM teams WHERE deleted_at IS NULL AND (name, id) > ($1::text, $2) ORDER BY name ASC, id ASC LIMIT $3"
+	if got != want {
+		t.Errorf("page() = %q, want %q", got, want)
+	}
+	if len(q.args) != 3 || q.args[0] != "ops" || q.args[1] != 4 || q.args[2] != 11 {
+		t.Errorf("args = %v", q.args)
+	}
+}
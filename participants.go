@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// normalizeRoster fills in the definition's roster and the fields derived
+// from it. A definition given as a plain participants list (with optional
+// participant_weights) becomes a roster of active tier 1 participants in that
+// order. Positions are renumbered from 1 in rotation order.
+func normalizeRoster(definition *ScheduleDefinition) error {
+	field := "roster"
+	if len(definition.Roster) == 0 {
+		field = "participants"
+		for i, userID := range definition.Participants {
+			definition.Roster = append(definition.Roster, ScheduleParticipant{UserID: userID, Position: i + 1,
+				Weight: definition.ParticipantWeights[userID], Active: true})
+		}
+	}
+
+	seen := make(map[int]bool)
+	for i := range definition.Roster {
+		participant := &definition.Roster[i]
+		if seen[participant.UserID] {
+			return fieldError(field, FieldDuplicate, fmt.Sprintf("User %d is listed more than once", participant.UserID))
+		}
+		seen[participant.UserID] = true
+
+		if participant.Weight == 0 {
+			participant.Weight = 1
+		}
+		if participant.Weight < 0 {
+			return fieldError(field, FieldOutOfRange,
+				fmt.Sprintf("Participant weights must be positive, got %v for user %d", participant.Weight, participant.UserID))
+		}
+		if participant.Tier == 0 {
+			participant.Tier = 1
+		}
+		if participant.Tier < 0 {
+			return fieldError(field, FieldOutOfRange,
+				fmt.Sprintf("Participant tiers start at 1, got %d for user %d", participant.Tier, participant.UserID))
+		}
+	}
+
+	// Participants without a position go last, in the order given
+	sort.SliceStable(definition.Roster, func(i, j int) bool {
+		a, b := definition.Roster[i].Position, definition.Roster[j].Position
+		return a != 0 && (b == 0 || a < b)
+	})
+	applyRoster(definition)
+	return nil
+}
+
+// applyRoster renumbers the roster's positions and derives the rotation from
+// it: Participants are the active tier 1 participants in position order, and
+// ParticipantWeights holds every participant's weight.
+func applyRoster(definition *ScheduleDefinition) {
+	definition.Participants = []int{}
+	definition.ParticipantWeights = make(map[int]float64, len(definition.Roster))
+	for i := range definition.Roster {
+		participant := &definition.Roster[i]
+		participant.Position = i + 1
+		definition.ParticipantWeights[participant.UserID] = participant.Weight
+		if participant.Active && participant.Tier == 1 {
+			definition.Participants = append(definition.Participants, participant.UserID)
+		}
+	}
+}
+
+// rosterIndex returns the position in the roster of the user's entry, or -1.
+func rosterIndex(roster []ScheduleParticipant, userID int) int {
+	for i, participant := range roster {
+		if participant.UserID == userID {
+			return i
+		}
+	}
+	return -1
+}
+
+// reorderRoster returns the roster in the order of userIDs, which must list
+// every participant exactly once.
+func reorderRoster(roster []ScheduleParticipant, userIDs []int) ([]ScheduleParticipant, error) {
+	if len(userIDs) != len(roster) {
+		return nil, fieldError("user_ids", FieldInvalid, fmt.Sprintf("Must list all %d participants", len(roster)))
+	}
+
+	reordered := make([]ScheduleParticipant, 0, len(roster))
+	for _, userID := range userIDs {
+		index := rosterIndex(roster, userID)
+		if index == -1 {
+			return nil, fieldError("user_ids", FieldInvalid, fmt.Sprintf("User %d is not a participant", userID))
+		}
+		if rosterIndex(reordered, userID) != -1 {
+			return nil, fieldError("user_ids", FieldDuplicate, fmt.Sprintf("User %d is listed more than once", userID))
+		}
+		reordered = append(reordered, roster[index])
+	}
+	return reordered, nil
+}
+
+// insertIntoRoster returns the roster with the participant inserted at its
+// 1-based position, or appended when the position is 0 or past the end.
+func insertIntoRoster(roster []ScheduleParticipant, participant ScheduleParticipant) []ScheduleParticipant {
+	index := participant.Position - 1
+	if index < 0 || index > len(roster) {
+		index = len(roster)
+	}
+
+	updated := make([]ScheduleParticipant, 0, len(roster)+1)
+	updated = append(updated, roster[:index]...)
+	updated = append(updated, participant)
+	return append(updated, roster[index:]...)
+}
+
+// checkRosterUsers fails if a participant is not an existing user, or is
+// new to the roster and not a member of the schedule's team. Participants in
+// existing who have since left the team are let through: edits should not be
+// blocked by them, and coverage reports them instead.
+func checkRosterUsers(ctx context.Context, teamID int, roster, existing []ScheduleParticipant) error {
+	var v validator
+	for _, participant := range roster {
+		user, err := getUserByID(ctx, participant.UserID)
+		if err == sql.ErrNoRows {
+			v.add("roster", FieldNotFound, fmt.Sprintf("User %d does not exist", participant.UserID))
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if rosterIndex(existing, participant.UserID) == -1 && !slices.Contains(user.TeamIDs, teamID) {
+			v.add("roster", FieldNotTeamMember, fmt.Sprintf("User %d is not a member of team %d", participant.UserID, teamID))
+		}
+	}
+	return v.err()
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// projectSchedule returns who is on call for each shift of the schedule
+// between from and to, past or future. The answer is a pure function of the
+// schedule's versions and its overrides (PTO and team holidays): the
+// rotation strategy is replayed from the schedule's first shift, each shift
+// following the version in effect when it starts and feeding its pick back
+// in as history. Recorded assignments are only read to charge fairness loads
+// to whoever actually served (see fairnessLoads), and match the replay
+// unless the schedule was paused or PTO was entered after the fact. The
+// scheduler only materializes these shifts, so deleting an assignment row,
+// restarting the scheduler or editing the schedule cannot change who was on
+// call before.
+//
+// Pausing does not stop the rotation's clock: shifts starting while the
+// schedule is paused go to its fallback user and are marked as paused, and
+// on resume the rotation carries on where it has got to. An archived
+// schedule has no shifts after it was archived.
+//
+// Replaying every shift on every call would grow without bound, so the
+// replay resumes from the checkpoint the last projection of the schedule
+// left (see replayCheckpoints) and only loads the overrides from there on.
+func projectSchedule(ctx context.Context, schedule Schedule, from, to time.Time) ([]ProjectedShift, error) {
+	if from.Before(schedule.StartTime) {
+		from = schedule.StartTime
+	}
+	if to.After(schedule.EndTime) {
+		to = schedule.EndTime
+	}
+	if schedule.Status == ScheduleStatusArchived && to.After(schedule.StatusChangedAt) {
+		to = schedule.StatusChangedAt
+	}
+	
+	definitions := []ScheduleDefinition{schedule.ScheduleDefinition}
+	for _, version := range schedule.Versions {
+		definitions = append(definitions, version.ScheduleDefinition)
+	}
+	holidayPools := make(map[int][]int)
+	for _, definition := range definitions {
+		if definition.RotationPeriod <= 0 {
+			return nil, fmt.Errorf("schedule %d has no rotation period", schedule.ID)
+		}
+		if _, ok := holidayPools[definition.HolidayScheduleID]; ok || definition.HolidayMode != HolidayModeRotation {
+			continue
+		}
+		pool, err := holidayParticipants(ctx, Schedule{ScheduleDefinition: definition})
+		if err != nil {
+			return nil, err
+		}
+		holidayPools[definition.HolidayScheduleID] = pool
+	}
+	
+	if !from.Before(to) {
+		return nil, nil
+	}
+	
+	// Read before the overrides, so a checkpoint is never filed under a
+	// generation newer than what it was replayed from
+	generation, err := store.GetRotationInputsGeneration(ctx)
+	if err != nil {
+		return nil, err
+	}
+	firstStart, _, _ := shiftAt(schedule, from)
+	state := replayCheckpoints.get(schedule, generation, firstStart)
+	
+	holidays, err := store.GetHolidaysBetween(ctx, schedule.TeamID, state.historyStart(), to)
+	if err != nil {
+		return nil, err
+	}
+	
+	unavailability, err := store.GetUnavailabilityBetween(ctx, state.shiftStart, to)
+	if err != nil {
+		return nil, err
+	}
+	
+	recorded, err := store.GetRecordedAssignments(ctx, schedule.ID, state.historyStart(), to)
+	if err != nil {
+		return nil, err
+	}
+	
+	shifts, checkpoint := replaySchedule(schedule, state, holidayPools, holidays, unavailability, recorded, from, to)
+	replayCheckpoints.put(schedule, generation, checkpoint)
+	return applyPauses(schedule, shifts, to), nil
+}
+
+// applyPauses gives the shifts that started while the schedule was paused to
+// that pause's fallback user, past pauses included, so projections agree
+// with the assignments recorded. A shift during which the pause ended is
+// split at the resume, when the rest of it went back to whoever the rotation
+// had reached (see handBackSchedule), unless that was the fallback user
+// anyway. The rotation itself carries on through pauses as if they had not
+// happened.
+func applyPauses(schedule Schedule, shifts []ProjectedShift, to time.Time) []ProjectedShift {
+	if len(schedule.Pauses) == 0 {
+		return shifts
+	}
+	
+	applied := make([]ProjectedShift, 0, len(shifts))
+	for _, shift := range shifts {
+		pause := pauseAt(schedule, shift.StartTime)
+		if pause == nil {
+			applied = append(applied, shift)
+			continue
+		}
+		
+		paused := shift
+		paused.Paused = true
+		paused.UserID = pause.FallbackUserID
+		if pause.ResumedAt != nil && pause.ResumedAt.Before(shift.EndTime) && pause.ResumedAt.Before(to) && shift.UserID != pause.FallbackUserID {
+			paused.EndTime = *pause.ResumedAt
+			shift.StartTime = *pause.ResumedAt
+			applied = append(applied, paused, shift)
+			continue
+		}
+		applied = append(applied, paused)
+	}
+	return applied
+}
+
+// pauseAt returns the pause the schedule was in at t, or nil if it was not
+// paused then.
+func pauseAt(schedule Schedule, t time.Time) *SchedulePause {
+	for i := len(schedule.Pauses) - 1; i >= 0; i-- {
+		pause := &schedule.Pauses[i]
+		if t.Before(pause.PausedAt) {
+			continue
+		}
+		if pause.ResumedAt == nil || t.Before(*pause.ResumedAt) {
+			return pause
+		}
+		return nil
+	}
+	return nil
+}
+
+// replaySchedule walks the schedule's shifts from where state has got to up
+// to to, and returns those from the shift in progress at from onwards along
+// with the state at that shift. holidayPools maps each holiday schedule ID
+// to its participants, and recorded holds the schedule's recorded
+// assignments, which fairness loads are counted from. It reads nothing but
+// its arguments, so the same inputs always give the same shifts.
+func replaySchedule(schedule Schedule, state replayState, holidayPools map[int][]int, holidays []Holiday, unavailability []Unavailability, recorded []OnCallAssignment, from, to time.Time) ([]ProjectedShift, replayState) {
+	firstStart, _, _ := shiftAt(schedule, from)
+	
+	// Both rotations share when each user first went on call, and carry
+	// their history across versions. Holidays weigh into fairness loads
+	// whatever the holiday mode.
+	regular := RotationInput{FirstSeen: state.firstSeen, History: state.regular, Holidays: holidays, Recorded: recorded}
+	holidayRotation := RotationInput{FirstSeen: state.firstSeen, History: state.holidayRotation, Holidays: holidays, Recorded: recorded}
+	checkpoint := newReplayState(schedule)
+	
+	var shifts []ProjectedShift
+	for shiftStart := state.shiftStart; shiftStart.Before(to); {
+		if shiftStart.Equal(firstStart) {
+			checkpoint = replayState{shiftStart: shiftStart, firstSeen: regular.FirstSeen,
+				regular: regular.History, holidayRotation: holidayRotation.History}.clone()
+		}
+		
+		_, shiftEnd, definition := shiftAt(schedule, shiftStart)
+		shift := ProjectedShift{
+			ScheduleID: schedule.ID,
+			StartTime:  shiftStart,
+			EndTime:    shiftEnd,
+		}
+		
+		var holiday *Holiday
+		if definition.HolidayMode != "" && definition.HolidayMode != HolidayModeNone {
+			holiday = holidayForShift(holidays, scheduleLocation(definition.ScheduleDefinition), shift.StartTime, shift.EndTime)
+		}
+		if holiday != nil {
+			shift.Holiday = true
+			shift.HolidayName = holiday.Name
+		}
+		
+		unavailable := unavailableDuring(unavailability, shift.StartTime, shift.EndTime)
+		
+		switch {
+		case holiday != nil && definition.HolidayMode == HolidayModeSkip:
+			shift.Skipped = true
+		case holiday != nil && definition.HolidayMode == HolidayModeRotation:
+			holidayRotation.Schedule = definition
+			holidayRotation.Participants = holidayPools[definition.HolidayScheduleID]
+			shift.UserID = nextProjectedUser(roundRobinStrategy{}, &holidayRotation, shift, unavailable)
+		default:
+			regular.Schedule = definition
+			regular.Participants = definition.Participants
+			shift.UserID = nextProjectedUser(strategyFor(definition), &regular, shift, unavailable)
+		}
+		
+		// Earlier shifts are only replayed as history
+		if !shiftStart.Before(firstStart) {
+			shifts = append(shifts, shift)
+		}
+		shiftStart = shiftEnd
+	}
+	return shifts, checkpoint
+}
+
+// replayState is how far a replay has got: the start of the next shift, and
+// what both rotations carry into it.
+type replayState struct {
+	shiftStart      time.Time
+	firstSeen       map[int]time.Time
+	regular         []OnCallAssignment
+	holidayRotation []OnCallAssignment
+}
+
+// newReplayState is the state before the schedule's first shift.
+func newReplayState(schedule Schedule) replayState {
+	return replayState{shiftStart: schedule.StartTime, firstSeen: make(map[int]time.Time)}
+}
+
+// clone copies the state, so a replay resuming from it leaves it as it was.
+func (state replayState) clone() replayState {
+	state.firstSeen = maps.Clone(state.firstSeen)
+	state.regular = slices.Clone(state.regular)
+	state.holidayRotation = slices.Clone(state.holidayRotation)
+	return state
+}
+
+// historyStart returns the start of the oldest shift in the state's
+// history, whose holidays still weigh into fairness loads.
+func (state replayState) historyStart() time.Time {
+	start := state.shiftStart
+	for _, history := range [][]OnCallAssignment{state.regular, state.holidayRotation} {
+		if len(history) > 0 && history[0].StartTime.Before(start) {
+			start = history[0].StartTime
+		}
+	}
+	return start
+}
+
+// replayCheckpoints holds the replay state of each schedule at the first
+// shift it was last projected from. Nearly every projection starts at or
+// after the last one, around now, so resuming from there replays a handful
+// of shifts instead of the schedule's whole life. A checkpoint is only used
+// for the revision of the schedule and the generation of the rotation
+// inputs (see migrations/020_rotation_input_changes.sql) it was taken at, so
+// any edit, PTO or holiday change, on any replica, starts a fresh replay.
+// Recording an assignment does not: fairness loads read the recorded
+// assignments afresh on every replay, and the picks a checkpoint holds were
+// made after the shifts before them had been recorded.
+var replayCheckpoints = newReplayCache()
+
+type replayCache struct {
+	mu          sync.Mutex
+	checkpoints map[int]replayCheckpoint
+}
+
+type replayCheckpoint struct {
+	revision   int
+	generation int64
+	state      replayState
+}
+
+func newReplayCache() *replayCache {
+	return &replayCache{checkpoints: make(map[int]replayCheckpoint)}
+}
+
+// get returns a copy of the schedule's checkpoint if it is still valid and
+// no later than firstStart, or else the state before its first shift.
+func (c *replayCache) get(schedule Schedule, generation int64, firstStart time.Time) replayState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	checkpoint, ok := c.checkpoints[schedule.ID]
+	if !ok || checkpoint.revision != schedule.Revision || checkpoint.generation != generation || checkpoint.state.shiftStart.After(firstStart) {
+		return newReplayState(schedule)
+	}
+	return checkpoint.state.clone()
+}
+
+// put files state as the schedule's checkpoint.
+func (c *replayCache) put(schedule Schedule, generation int64, state replayState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	c.checkpoints[schedule.ID] = replayCheckpoint{revision: schedule.Revision, generation: generation, state: state}
+}
+
+// nextProjectedUser asks the strategy who covers the shift, then records the
+// pick as history so the following shift sees it. History older than the
+// strategies look at is dropped as the replay moves on.
+func nextProjectedUser(strategy RotationStrategy, input *RotationInput, shift ProjectedShift, unavailable map[int]bool) int {
+	input.ShiftStart = shift.StartTime
+	input.ShiftEnd = shift.EndTime
+	input.Unavailable = unavailable
+	
+	since := shift.StartTime.Add(-rotationLookback(input.Schedule, len(input.Participants)))
+	for len(input.History) > 0 && !input.History[0].EndTime.After(since) {
+		input.History = input.History[1:]
+	}
+	
+	userID := strategy.Next(*input)
+	if userID == 0 {
+		return 0
+	}
+	
+	input.History = append(input.History, OnCallAssignment{
+		ScheduleID: shift.ScheduleID,
+		UserID:     userID,
+		StartTime:  shift.StartTime,
+		EndTime:    shift.EndTime,
+		Role:       AssignmentRolePrimary,
+		Holiday:    shift.Holiday,
+	})
+	if _, ok := input.FirstSeen[userID]; !ok {
+		input.FirstSeen[userID] = shift.StartTime
+	}
+	return userID
+}
+
+// rotationLookback is how much history the strategies need: enough for
+// fairness and for a full round-robin cycle.
+func rotationLookback(schedule Schedule, participants int) time.Duration {
+	lookback := time.Duration(fairnessWindowDays) * 24 * time.Hour
+	if cycle := time.Duration(schedule.RotationPeriod) * time.Second * time.Duration(participants+1); cycle > lookback {
+		lookback = cycle
+	}
+	return lookback
+}
+
+// unavailableDuring returns the users with unavailability overlapping any
+// part of [start, end).
+func unavailableDuring(entries []Unavailability, start, end time.Time) map[int]bool {
+	unavailable := make(map[int]bool)
+	for _, entry := range entries {
+		if entry.StartTime.Before(end) && entry.EndTime.After(start) {
+			unavailable[entry.UserID] = true
+		}
+	}
+	return unavailable
+}
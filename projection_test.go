@@ -0,0 +1,102 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReplayFromCheckpoint(t *testing.T) {
+	schedule := Schedule{ID: 1, StartTime: monday, EndTime: monday.AddDate(1, 0, 0),
+		ScheduleDefinition: ScheduleDefinition{RotationPeriod: 12 * 3600, Participants: []int{1, 2, 3, 4},
+			RotationStrategy: RotationStrategyFairness}}
+	holidays := []Holiday{{TeamID: 1, Date: monday.AddDate(0, 0, 40), Name: "Founders' Day"}}
+	unavailability := []Unavailability{{UserID: 2, StartTime: monday.AddDate(0, 0, 30), EndTime: monday.AddDate(0, 0, 45)}}
+
+	from := monday.AddDate(0, 0, 50)
+	to := from.AddDate(0, 0, 7)
+	want, _ := replaySchedule(schedule, newReplayState(schedule), nil, holidays, unavailability, nil, from, to)
+
+	// Resume from a checkpoint taken by an earlier projection
+	_, checkpoint := replaySchedule(schedule, newReplayState(schedule), nil, holidays, unavailability, nil, monday.AddDate(0, 0, 35), from)
+	got, _ := replaySchedule(schedule, checkpoint, nil, holidays, unavailability, nil, from, to)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("replay from the checkpoint at %v differs from a full replay:\n got %v\nwant %v", checkpoint.shiftStart, got, want)
+	}
+}
+
+func TestReplayCache(t *testing.T) {
+	schedule := Schedule{ID: 1, Revision: 3, StartTime: monday}
+	state := replayState{shiftStart: monday.AddDate(0, 0, 5), firstSeen: map[int]time.Time{1: monday},
+		regular: pastShifts(monday.AddDate(0, 0, 5), 1)}
+	cache := newReplayCache()
+	cache.put(schedule, 7, state)
+
+	tests := []struct {
+		name       string
+		revision   int
+		generation int64
+		firstStart time.Time
+		want       time.Time
+	}{
+		{name: "resumes from the checkpoint", revision: 3, generation: 7, firstStart: monday.AddDate(0, 0, 6), want: state.shiftStart},
+		{name: "projection before the checkpoint", revision: 3, generation: 7, firstStart: monday.AddDate(0, 0, 4), want: monday},
+		{name: "schedule edited", revision: 4, generation: 7, firstStart: monday.AddDate(0, 0, 6), want: monday},
+		{name: "overrides changed", revision: 3, generation: 8, firstStart: monday.AddDate(0, 0, 6), want: monday},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule.Revision = tt.revision
+			if got := cache.get(schedule, tt.generation, tt.firstStart); !got.shiftStart.Equal(tt.want) {
+				t.Errorf("get() resumes at %v, want %v", got.shiftStart, tt.want)
+			}
+		})
+	}
+
+	// A replay resuming from the checkpoint must leave it as it was
+	schedule.Revision = 3
+	resumed := cache.get(schedule, 7, state.shiftStart)
+	resumed.firstSeen[2] = monday
+	resumed.regular[0].UserID = 2
+	if again := cache.get(schedule, 7, state.shiftStart); !reflect.DeepEqual(again, state) {
+		t.Errorf("checkpoint changed by a replay: %+v", again)
+	}
+}
+
+func TestApplyPauses(t *testing.T) {
+	at := func(day, hour int) time.Time { return monday.AddDate(0, 0, day).Add(time.Duration(hour) * time.Hour) }
+	resumed := at(3, 12)
+	schedule := Schedule{ID: 1, StartTime: monday, EndTime: monday.AddDate(1, 0, 0), Status: ScheduleStatusPaused,
+		StatusChangedAt: at(5, 6), FallbackUserID: 8,
+		ScheduleDefinition: ScheduleDefinition{RotationPeriod: 86400, Participants: []int{1, 2}},
+		Pauses: []SchedulePause{
+			{PausedAt: at(1, 6), ResumedAt: &resumed, FallbackUserID: 9},
+			{PausedAt: at(5, 6), FallbackUserID: 8},
+		}}
+	shifts, _ := replaySchedule(schedule, newReplayState(schedule), nil, nil, nil, nil, monday, at(7, 0))
+
+	type piece struct {
+		start, end time.Time
+		userID     int
+		paused     bool
+	}
+	var got []piece
+	for _, shift := range applyPauses(schedule, shifts, at(7, 0)) {
+		got = append(got, piece{shift.StartTime, shift.EndTime, shift.UserID, shift.Paused})
+	}
+	want := []piece{
+		// The shift in progress when the first pause began keeps its on-caller
+		{at(0, 0), at(1, 0), 1, false},
+		{at(1, 0), at(2, 0), 2, false},
+		{at(2, 0), at(3, 0), 9, true},
+		// Handed back at the resume to whoever the rotation had reached
+		{at(3, 0), resumed, 9, true},
+		{resumed, at(4, 0), 2, false},
+		{at(4, 0), at(5, 0), 1, false},
+		{at(5, 0), at(6, 0), 2, false},
+		{at(6, 0), at(7, 0), 8, true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("applyPauses() =\n%v\nwant\n%v", got, want)
+	}
+}
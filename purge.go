@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Deleted users, teams and schedules are only marked deleted, so they can be
+// restored. The purger removes them for good once they have been deleted for
+// longer than the retention period, unless they are still part of the
+// on-call history: schedules that have had assignments, and users who have
+// been rostered, on call or shadowing, stay deleted but are never purged so
+// reports keep their names.
+
+const defaultDeletedRetentionDays = 30
+
+// purger purges expired deletions every hour until ctx is done.
+func purger(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	days := deletedRetentionDays()
+	log.Printf("Purger started (purging deletions older than %d days every hour)", days)
+
+	for {
+		// Only the leader purges, so replicas don't race each other
+		if fencingToken, leader := elector.fencingToken(); leader {
+			if err := purgeDeleted(ctx, clock.Now().AddDate(0, 0, -days), fencingToken); err != nil {
+				log.Printf("Stopping purge: %v", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("Purger stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purgeDeleted purges the schedules, teams and users deleted before cutoff
+// that nothing depends on any more. Schedules go first, as they may be all
+// that keeps a team or user around. Each purge is fenced like a rotation, and
+// the first one made after this instance lost leadership with fencingToken
+// ends the pass with errFencedOut.
+func purgeDeleted(ctx context.Context, cutoff time.Time, fencingToken int64) error {
+	// Schedules that never had anyone on call; a schedule still covering the
+	// holidays of a deleted one fails on the foreign key and is retried later
+	err := purgeExpired(ctx, "schedules", purgeSchedule, `SELECT id FROM schedules s
+		WHERE deleted_at < $1 AND NOT EXISTS (SELECT 1 FROM oncall_assignments WHERE schedule_id = s.id)
+		ORDER BY id`, cutoff, fencingToken)
+	if err != nil {
+		return err
+	}
+	err = purgeExpired(ctx, "teams", purgeTeam, `SELECT id FROM teams t
+		WHERE deleted_at < $1 AND NOT EXISTS (SELECT 1 FROM schedules WHERE team_id = t.id)
+		ORDER BY id`, cutoff, fencingToken)
+	if err != nil {
+		return err
+	}
+
+	userIDs, err := queryIDs(ctx, db, "SELECT id FROM users WHERE deleted_at < $1 ORDER BY id", cutoff)
+	if err != nil {
+		log.Printf("Error finding users to purge: %v", err)
+		return nil
+	}
+	purged := 0
+	defer func() {
+		if purged > 0 {
+			log.Printf("Purged %d deleted users", purged)
+		}
+	}()
+	for _, userID := range userIDs {
+		scheduleIDs, err := getUserScheduleIDs(ctx, userID)
+		if err != nil {
+			log.Printf("Error checking the history of user %d: %v", userID, err)
+			continue
+		}
+		if len(scheduleIDs) > 0 {
+			continue
+		}
+		ok, err := purgeRow(ctx, "users", userID, purgeUser, fencingToken)
+		if err != nil {
+			return err
+		}
+		if ok {
+			purged++
+		}
+	}
+	return nil
+}
+
+// purgeFunc purges one deleted row while fencingToken is the leader's.
+type purgeFunc func(ctx context.Context, id int, fencingToken int64) error
+
+// purgeExpired purges the rows of table that query, given cutoff, returns.
+// It only fails with errFencedOut.
+func purgeExpired(ctx context.Context, table string, purge purgeFunc, query string, cutoff time.Time, fencingToken int64) error {
+	ids, err := queryIDs(ctx, db, query, cutoff)
+	if err != nil {
+		log.Printf("Error finding %s to purge: %v", table, err)
+		return nil
+	}
+	purged := 0
+	defer func() {
+		if purged > 0 {
+			log.Printf("Purged %d deleted %s", purged, table)
+		}
+	}()
+	for _, id := range ids {
+		ok, err := purgeRow(ctx, table, id, purge, fencingToken)
+		if err != nil {
+			return err
+		}
+		if ok {
+			purged++
+		}
+	}
+	return nil
+}
+
+// purgeRow purges one row and reports whether it went. Rows that are still
+// referenced, or were restored or purged meanwhile, are left alone. The only
+// error returned is errFencedOut; others are logged.
+func purgeRow(ctx context.Context, table string, id int, purge purgeFunc, fencingToken int64) (bool, error) {
+	err := purge(ctx, id, fencingToken)
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, errFencedOut) {
+		return false, err
+	}
+	if !isForeignKeyViolation(err) && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Error purging %s %d: %v", table, id, err)
+	}
+	return false, nil
+}
+
+// deletedRetentionDays reads DELETED_RETENTION_DAYS, defaulting to a month.
+func deletedRetentionDays() int {
+	if v := os.Getenv("DELETED_RETENTION_DAYS"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err == nil && days > 0 {
+			return days
+		}
+		log.Printf("Invalid DELETED_RETENTION_DAYS %q, using %d", v, defaultDeletedRetentionDays)
+	}
+	return defaultDeletedRetentionDays
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Rotation strategies for choosing the next on-caller.
+const (
+	RotationStrategyRoundRobin    = "round_robin"
+	RotationStrategyFairness      = "fairness"
+	RotationStrategyWeighted      = "weighted"
+	RotationStrategyRandom        = "random"
+	RotationStrategyFixedSequence = "fixed_sequence"
+)
+
+// RotationInput is everything a strategy may look at to pick who covers a
+// shift. Strategies must not read the clock or the database themselves:
+// ShiftStart is "now" for the decision and History is the past, which keeps
+// them deterministic so projectSchedule can replay them from the schedule's
+// first shift.
+type RotationInput struct {
+	Schedule     Schedule
+	Participants []int // candidates, in rotation order
+	ShiftStart   time.Time
+	ShiftEnd     time.Time
+	History      []OnCallAssignment // earlier projected shifts of this rotation, oldest first
+	FirstSeen    map[int]time.Time  // when each user first went on call for the schedule
+	Holidays     []Holiday
+	Recorded     []OnCallAssignment // the schedule's recorded tier 1 primary assignments, oldest first
+	Unavailable  map[int]bool
+}
+
+// RotationStrategy picks the user who covers a shift, or 0 when nobody
+// available can.
+type RotationStrategy interface {
+	Next(input RotationInput) int
+}
+
+var rotationStrategies = map[string]RotationStrategy{
+	RotationStrategyRoundRobin:    roundRobinStrategy{},
+	RotationStrategyFairness:      weightedStrategy{},
+	RotationStrategyWeighted:      weightedStrategy{useWeights: true},
+	RotationStrategyRandom:        randomStrategy{},
+	RotationStrategyFixedSequence: fixedSequenceStrategy{},
+}
+
+// strategyFor returns the schedule's rotation strategy, defaulting to
+// round-robin.
+func strategyFor(schedule Schedule) RotationStrategy {
+	if strategy, ok := rotationStrategies[schedule.RotationStrategy]; ok {
+		return strategy
+	}
+	return roundRobinStrategy{}
+}
+
+func isValidRotationStrategy(name string) bool {
+	_, ok := rotationStrategies[name]
+	return ok
+}
+
+// roundRobinStrategy hands the shift to the participant after whoever held
+// the last shift, skipping anyone unavailable.
+type roundRobinStrategy struct{}
+
+func (roundRobinStrategy) Next(input RotationInput) int {
+	userID, _ := pickAvailableParticipant(input.Participants, nextRoundRobinIndex(input), input.Unavailable)
+	return userID
+}
+
+// nextRoundRobinIndex finds the last participant in the history and returns
+// the index of the one after them, or 0 if none of them has been on call.
+func nextRoundRobinIndex(input RotationInput) int {
+	for i := len(input.History) - 1; i >= 0; i-- {
+		for j, participant := range input.Participants {
+			if participant == input.History[i].UserID {
+				return (j + 1) % len(input.Participants)
+			}
+		}
+	}
+	return 0
+}
+
+// pickAvailableParticipant walks the participants from startIndex in
+// rotation order and returns the first one who is not unavailable, along
+// with their index. It returns 0, -1 when everyone is unavailable.
+func pickAvailableParticipant(participants []int, startIndex int, unavailable map[int]bool) (int, int) {
+	for offset := 0; offset < len(participants); offset++ {
+		index := (startIndex + offset) % len(participants)
+		if !unavailable[participants[index]] {
+			return participants[index], index
+		}
+	}
+	return 0, -1
+}
+
+// weightedStrategy picks the available participant with the lowest weighted
+// on-call load (see fairnessLoads). With useWeights, each load is divided by
+// the participant's weight, so a weight of 2 takes twice the share.
+type weightedStrategy struct {
+	useWeights bool
+}
+
+func (s weightedStrategy) Next(input RotationInput) int {
+	loads := fairnessLoads(input)
+	if s.useWeights {
+		for userID, load := range loads {
+			if weight, ok := input.Schedule.ParticipantWeights[userID]; ok && weight > 0 {
+				loads[userID] = load / weight
+			}
+		}
+	}
+	
+	startIndex := nextRoundRobinIndex(input)
+	bestUser, bestIndex := 0, -1
+	for offset := 0; offset < len(input.Participants); offset++ {
+		index := (startIndex + offset) % len(input.Participants)
+		candidate := input.Participants[index]
+		if input.Unavailable[candidate] {
+			continue
+		}
+		// Ties go to whoever comes first in round-robin order
+		if bestIndex == -1 || loads[candidate] < loads[bestUser] {
+			bestUser, bestIndex = candidate, index
+		}
+	}
+	return bestUser
+}
+
+// randomStrategy draws participants at random without repeats: everyone
+// serves once per cycle before anyone serves again, and a new cycle never
+// starts with whoever ended the last one. The draw is seeded from the
+// schedule and shift, so the same inputs always give the same pick.
+type randomStrategy struct{}
+
+func (randomStrategy) Next(input RotationInput) int {
+	if len(input.Participants) == 0 {
+		return 0
+	}
+	
+	isParticipant := make(map[int]bool)
+	for _, participant := range input.Participants {
+		isParticipant[participant] = true
+	}
+	
+	// Replay the history to find who has served in the current cycle
+	served := make(map[int]bool)
+	lastUser := 0
+	for _, assignment := range input.History {
+		if !isParticipant[assignment.UserID] {
+			continue
+		}
+		if len(served) == len(isParticipant) || served[assignment.UserID] {
+			served = make(map[int]bool)
+		}
+		served[assignment.UserID] = true
+		lastUser = assignment.UserID
+	}
+	if len(served) == len(isParticipant) {
+		served = map[int]bool{lastUser: true}
+	}
+	
+	var candidates []int
+	for _, participant := range input.Participants {
+		if !served[participant] && !input.Unavailable[participant] {
+			candidates = append(candidates, participant)
+		}
+	}
+	if len(candidates) == 0 {
+		// Everyone left in the cycle is away, fall back to anyone available
+		for _, participant := range input.Participants {
+			if !input.Unavailable[participant] {
+				candidates = append(candidates, participant)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return 0
+	}
+	
+	seed := int64(input.Schedule.ID)<<32 ^ input.ShiftStart.Unix()
+	return candidates[rand.New(rand.NewSource(seed)).Intn(len(candidates))]
+}
+
+// fixedSequenceStrategy follows the schedule's explicit sequence of user
+// IDs, which may list someone more than once and is required for the
+// strategy (see validateScheduleDefinition). The shift's position since its
+// version took effect picks the entry, so a new version starts its sequence
+// from the top; unavailable entries pass to the next.
+type fixedSequenceStrategy struct{}
+
+func (fixedSequenceStrategy) Next(input RotationInput) int {
+	sequence := input.Schedule.Sequence
+	if len(sequence) == 0 || input.Schedule.RotationPeriod <= 0 {
+		return 0
+	}
+	
+	period := time.Duration(input.Schedule.RotationPeriod) * time.Second
+	shiftIndex := int(input.ShiftStart.Sub(input.Schedule.EffectiveFrom) / period)
+	if shiftIndex < 0 {
+		shiftIndex = 0
+	}
+	
+	userID, _ := pickAvailableParticipant(sequence, shiftIndex%len(sequence), input.Unavailable)
+	return userID
+}
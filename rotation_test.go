@@ -0,0 +1,276 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+// monday is a weekday midnight, so shifts in the tests weigh the same
+// unless they say otherwise.
+var monday = time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+
+// pastShifts returns a day-long assignment for each user in turn, ending at
+// the start of day.
+func pastShifts(day time.Time, users ...int) []OnCallAssignment {
+	history := make([]OnCallAssignment, len(users))
+	start := day.AddDate(0, 0, -len(users))
+	for i, userID := range users {
+		history[i] = OnCallAssignment{ScheduleID: 1, UserID: userID, StartTime: start.AddDate(0, 0, i),
+			EndTime: start.AddDate(0, 0, i+1), Role: AssignmentRolePrimary}
+	}
+	return history
+}
+
+// shiftInput is the input for the day-long shift starting at start.
+func shiftInput(schedule Schedule, start time.Time, history []OnCallAssignment, unavailable ...int) RotationInput {
+	input := RotationInput{
+		Schedule:     schedule,
+		Participants: schedule.Participants,
+		ShiftStart:   start,
+		ShiftEnd:     start.AddDate(0, 0, 1),
+		History:      history,
+		FirstSeen:    make(map[int]time.Time),
+		Unavailable:  make(map[int]bool),
+	}
+	for _, userID := range unavailable {
+		input.Unavailable[userID] = true
+	}
+	return input
+}
+
+func TestRoundRobinStrategy(t *testing.T) {
+	schedule := Schedule{ID: 1, ScheduleDefinition: ScheduleDefinition{RotationPeriod: 86400, Participants: []int{1, 2, 3}}}
+
+	tests := []struct {
+		name        string
+		history     []OnCallAssignment
+		unavailable []int
+		want        int
+	}{
+		{name: "first shift", want: 1},
+		{name: "after the last on call", history: pastShifts(monday, 1, 2), want: 3},
+		{name: "wraps around", history: pastShifts(monday, 2, 3), want: 1},
+		{name: "ignores users no longer rotating", history: pastShifts(monday, 1, 9), want: 2},
+		{name: "skips the unavailable", history: pastShifts(monday, 1), unavailable: []int{2}, want: 3},
+		{name: "skips the unavailable across the wrap", history: pastShifts(monday, 2), unavailable: []int{3, 1}, want: 2},
+		{name: "nobody available", unavailable: []int{1, 2, 3}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := roundRobinStrategy{}.Next(shiftInput(schedule, monday, tt.history, tt.unavailable...))
+			if got != tt.want {
+				t.Errorf("Next() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWeightedStrategy(t *testing.T) {
+	schedule := Schedule{ID: 1, ScheduleDefinition: ScheduleDefinition{RotationPeriod: 86400, Participants: []int{1, 2, 3},
+		ParticipantWeights: map[int]float64{3: 2}}}
+
+	tests := []struct {
+		name        string
+		useWeights  bool
+		history     []OnCallAssignment
+		unavailable []int
+		want        int
+	}{
+		{name: "ties go to the first in rotation order", want: 1},
+		{name: "ties go to whoever follows the last on call", history: pastShifts(monday, 2), want: 3},
+		{name: "lowest load first", history: pastShifts(monday, 1, 3, 1, 2), want: 3},
+		{name: "lowest load unavailable", history: pastShifts(monday, 1, 3, 1, 2), unavailable: []int{3}, want: 2},
+		{name: "weekend hours weigh more", history: pastShifts(monday, 2, 1, 3), want: 2},
+		{name: "weights share the load", useWeights: true, history: pastShifts(monday, 1, 2, 3), want: 3},
+		{name: "weights ignored for fairness", history: pastShifts(monday, 1, 2, 3), want: 1},
+		{name: "nobody available", unavailable: []int{1, 2, 3}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := weightedStrategy{useWeights: tt.useWeights}.Next(shiftInput(schedule, monday, tt.history, tt.unavailable...))
+			if got != tt.want {
+				t.Errorf("Next() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWeightedStrategyHolidays(t *testing.T) {
+	schedule := Schedule{ID: 1, ScheduleDefinition: ScheduleDefinition{RotationPeriod: 86400, Participants: []int{1, 2}}}
+	history := pastShifts(monday, 2, 1)
+
+	// Both worked a weekend day, but user 2's was a holiday
+	input := shiftInput(schedule, monday, history)
+	input.Holidays = []Holiday{{TeamID: 1, Date: monday.AddDate(0, 0, -2), Name: "Founders' Day"}}
+	if got := (weightedStrategy{}).Next(input); got != 1 {
+		t.Errorf("Next() = %d, want 1", got)
+	}
+}
+
+func TestWeightedStrategyRecorded(t *testing.T) {
+	schedule := Schedule{ID: 1, ScheduleDefinition: ScheduleDefinition{RotationPeriod: 86400, Participants: []int{1, 2}}}
+	history := pastShifts(monday, 1, 2)
+	if got := (weightedStrategy{}).Next(shiftInput(schedule, monday, history)); got != 1 {
+		t.Fatalf("Next() = %d, want 1", got)
+	}
+
+	// User 1 was recorded covering the second half of user 2's shift
+	input := shiftInput(schedule, monday, history)
+	input.Recorded = []OnCallAssignment{history[0], history[1], history[1]}
+	input.Recorded[1].EndTime = monday.Add(-12 * time.Hour)
+	input.Recorded[2].UserID = 1
+	input.Recorded[2].StartTime = monday.Add(-12 * time.Hour)
+	if got := (weightedStrategy{}).Next(input); got != 2 {
+		t.Errorf("Next() with the cover recorded = %d, want 2", got)
+	}
+}
+
+func TestWeightedStrategyNewcomer(t *testing.T) {
+	schedule := Schedule{ID: 1, ScheduleDefinition: ScheduleDefinition{RotationPeriod: 86400, Participants: []int{1, 2, 3}}}
+	now := monday.AddDate(0, 0, 2*fairnessWindowDays)
+
+	// Users 1 and 2 have alternated for the whole window; user 3 joins now
+	// and is credited with their average load, so they take their turn
+	// rather than every shift until they catch up
+	var users []int
+	for i := 0; i < fairnessWindowDays; i++ {
+		users = append(users, 1+i%2)
+	}
+	input := shiftInput(schedule, now, pastShifts(now, users...))
+	input.FirstSeen[1] = now.AddDate(0, 0, -fairnessWindowDays-1)
+	input.FirstSeen[2] = now.AddDate(0, 0, -fairnessWindowDays-1)
+
+	loads := fairnessLoads(input)
+	if average := (loads[1] + loads[2]) / 2; math.Abs(loads[3]-average) > 1e-9 {
+		t.Errorf("newcomer load = %.1f, want the veterans' average %.1f", loads[3], average)
+	}
+}
+
+// replayStrategy runs the strategy over shifts day-long shifts from start,
+// feeding each pick back in as history, and returns the picks.
+func replayStrategy(strategy RotationStrategy, schedule Schedule, start time.Time, shifts int) []int {
+	var picks []int
+	var history []OnCallAssignment
+	for i := 0; i < shifts; i++ {
+		shiftStart := start.AddDate(0, 0, i)
+		userID := strategy.Next(shiftInput(schedule, shiftStart, history))
+		picks = append(picks, userID)
+		history = append(history, OnCallAssignment{ScheduleID: schedule.ID, UserID: userID, StartTime: shiftStart,
+			EndTime: shiftStart.AddDate(0, 0, 1), Role: AssignmentRolePrimary})
+	}
+	return picks
+}
+
+func TestRandomStrategyCycles(t *testing.T) {
+	for _, participants := range [][]int{{1, 2}, {1, 2, 3}, {4, 5, 6, 7, 8}} {
+		schedule := Schedule{ID: 7, ScheduleDefinition: ScheduleDefinition{RotationPeriod: 86400, Participants: participants}}
+		picks := replayStrategy(randomStrategy{}, schedule, monday, 20*len(participants))
+
+		for cycle := 0; cycle < len(picks); cycle += len(participants) {
+			got := slices.Clone(picks[cycle : cycle+len(participants)])
+			slices.Sort(got)
+			if !slices.Equal(got, participants) {
+				t.Fatalf("%v: cycle %v is not everyone once: %v", participants, picks[cycle:cycle+len(participants)], picks)
+			}
+			if cycle > 0 && picks[cycle] == picks[cycle-1] {
+				t.Fatalf("%v: user %d ends one cycle and starts the next: %v", participants, picks[cycle], picks)
+			}
+		}
+	}
+}
+
+func TestRandomStrategyIsDeterministic(t *testing.T) {
+	schedule := Schedule{ID: 7, ScheduleDefinition: ScheduleDefinition{RotationPeriod: 86400, Participants: []int{1, 2, 3, 4, 5}}}
+	first := replayStrategy(randomStrategy{}, schedule, monday, 50)
+	if again := replayStrategy(randomStrategy{}, schedule, monday, 50); !slices.Equal(first, again) {
+		t.Errorf("replays differ:\n%v\n%v", first, again)
+	}
+
+	// The seed includes the schedule, so schedules sharing a roster don't
+	// move in lockstep
+	schedule.ID = 8
+	if other := replayStrategy(randomStrategy{}, schedule, monday, 50); slices.Equal(first, other) {
+		t.Errorf("schedules 7 and 8 drew the same picks: %v", first)
+	}
+}
+
+func TestRandomStrategyUnavailable(t *testing.T) {
+	schedule := Schedule{ID: 7, ScheduleDefinition: ScheduleDefinition{RotationPeriod: 86400, Participants: []int{1, 2, 3}}}
+
+	tests := []struct {
+		name        string
+		history     []OnCallAssignment
+		unavailable []int
+		want        []int // any of these
+	}{
+		{name: "skips the unavailable", history: pastShifts(monday, 1), unavailable: []int{2}, want: []int{3}},
+		{name: "last in the cycle away", history: pastShifts(monday, 1, 2), unavailable: []int{3}, want: []int{1, 2}},
+		{name: "nobody available", unavailable: []int{1, 2, 3}, want: []int{0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := randomStrategy{}.Next(shiftInput(schedule, monday, tt.history, tt.unavailable...))
+			if !slices.Contains(tt.want, got) {
+				t.Errorf("Next() = %d, want one of %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFixedSequenceStrategy(t *testing.T) {
+	schedule := Schedule{ID: 1, EffectiveFrom: monday, ScheduleDefinition: ScheduleDefinition{RotationPeriod: 86400,
+		Participants: []int{2, 3}, RotationStrategy: RotationStrategyFixedSequence, Sequence: []int{3, 2, 3}}}
+
+	tests := []struct {
+		name        string
+		day         int // days after the version took effect
+		unavailable []int
+		want        int
+	}{
+		{name: "first entry", day: 0, want: 3},
+		{name: "second entry", day: 1, want: 2},
+		{name: "repeated entry", day: 2, want: 3},
+		{name: "wraps around", day: 4, want: 2},
+		{name: "unavailable entry passes to the next", day: 1, unavailable: []int{2}, want: 3},
+		{name: "nobody available", day: 0, unavailable: []int{2, 3}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fixedSequenceStrategy{}.Next(shiftInput(schedule, monday.AddDate(0, 0, tt.day), nil, tt.unavailable...))
+			if got != tt.want {
+				t.Errorf("Next() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	schedule.Sequence = nil
+	if got := (fixedSequenceStrategy{}).Next(shiftInput(schedule, monday, nil)); got != 0 {
+		t.Errorf("Next() without a sequence = %d, want 0", got)
+	}
+}
+
+func TestFixedSequenceAcrossVersions(t *testing.T) {
+	first := ScheduleDefinition{RotationPeriod: 86400, Participants: []int{1, 2, 3}}
+	second := ScheduleDefinition{RotationPeriod: 12 * 3600, Participants: []int{2, 3},
+		RotationStrategy: RotationStrategyFixedSequence, Sequence: []int{3, 2, 3}}
+	switchAt := monday.Add(2*24*time.Hour + 6*time.Hour)
+	schedule := Schedule{ID: 1, StartTime: monday, EndTime: monday.AddDate(1, 0, 0), ScheduleDefinition: first,
+		Versions: []ScheduleVersion{
+			{ScheduleID: 1, Version: 1, EffectiveFrom: monday, ScheduleDefinition: first},
+			{ScheduleID: 1, Version: 2, EffectiveFrom: switchAt, ScheduleDefinition: second},
+		}}
+
+	var got []int
+	shifts, _ := replaySchedule(schedule, newReplayState(schedule), nil, nil, nil, nil, monday, switchAt.Add(60*time.Hour))
+	for _, shift := range shifts {
+		got = append(got, shift.UserID)
+	}
+	// Round-robin for two days and the shift cut short by the new version,
+	// then the sequence from its first entry
+	want := []int{1, 2, 3, 3, 2, 3, 3, 2}
+	if !slices.Equal(got, want) {
+		t.Errorf("replay = %v, want %v", got, want)
+	}
+}
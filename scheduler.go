@@ -1,155 +1,480 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// scheduleChangesChannel is notified by database triggers whenever
+	// schedules or their overrides (shadows, PTO, holidays) change.
+	scheduleChangesChannel = "schedule_changes"
+	
+	// maxSchedulerSleep bounds how long the scheduler sleeps without a
+	// boundary, in case a notification is lost.
+	maxSchedulerSleep = time.Hour
+	
+	// schedulerRetryDelay is how soon the scheduler retries after a failed pass.
+	schedulerRetryDelay = 30 * time.Second
+	
+	// boundarySlack wakes the scheduler just after a boundary rather than on
+	// it, since assignments rotate once the current one has ended.
+	boundarySlack = 10 * time.Millisecond
 )
 
-func scheduleChecker() {
-	ticker := time.NewTicker(5 * time.Second) // Check every 10 seconds for better granularity
-	defer ticker.Stop()
+// scheduleChecker runs a rotation pass, then sleeps until the next rotation
+// boundary across all schedules. A schedule change notification from
+// Postgres wakes it early. Only the elected leader rotates; followers check
+// back every heartbeat so they can take over. It returns once ctx is done
+// and the pass in progress, if any, has stopped.
+func scheduleChecker(ctx context.Context, dbURL string) {
+	listener := pq.NewListener(dbURL, 10*time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Schedule change listener error: %v", err)
+		}
+	})
+	defer listener.Close()
 	
-	log.Println("Schedule checker started (checking every 10 seconds)")
+	if err := listener.Listen(scheduleChangesChannel); err != nil {
+		log.Printf("Error listening for schedule changes, relying on rotation boundaries only: %v", err)
+	}
+	
+	log.Println("Schedule checker started (waking at rotation boundaries and on schedule changes)")
+	
+	timer := time.NewTimer(0)
+	defer timer.Stop()
 	
 	for {
 		select {
-		case <-ticker.C:
+		case <-ctx.Done():
+			log.Println("Schedule checker stopped")
+			return
+		case <-timer.C:
+		case notification := <-listener.Notify:
+			if notification == nil {
+				// The listener reconnected and may have missed notifications
+				log.Println("Schedule change listener reconnected, re-checking schedules")
+			} else {
+				log.Printf("Change on %s, re-checking schedules", notification.Extra)
+			}
+		}
+		
+		var next time.Time
+		if fencingToken, leader := elector.fencingToken(); leader {
 			log.Println("Checking and Updating schedules")
-			checkAndUpdateSchedules()
+			next = checkAndUpdateSchedules(ctx, fencingToken)
+		} else {
+			next = clock.Now().Add(leaderHeartbeat)
+		}
+		
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
 		}
+		timer.Reset(next.Sub(clock.Now()))
 	}
 }
 
-func checkAndUpdateSchedules() {
-	schedules, err := getSchedules()
+// checkAndUpdateSchedules rotates every schedule whose current assignment has
+// ended and returns when it next needs to run. Each rotation is written in
+// one transaction fenced with the leader's token, and the pass stops as soon
+// as one is rejected. Once ctx is done no further schedule is started.
+func checkAndUpdateSchedules(ctx context.Context, fencingToken int64) time.Time {
+	schedules, err := store.GetSchedules(ctx)
 	if err != nil {
 		log.Printf("Error getting schedules: %v", err)
-		return
+		return clock.Now().Add(schedulerRetryDelay)
 	}
 	
-	now := time.Now()
+	// Load every schedule's current assignment in one query
+	assignments, err := store.GetCurrentOnCallAssignments(ctx)
+	if err != nil {
+		log.Printf("Error getting current assignments: %v", err)
+		return clock.Now().Add(schedulerRetryDelay)
+	}
+	currentAssignments := make(map[int]*OnCallAssignment, len(assignments))
+	for i := range assignments {
+		currentAssignments[assignments[i].ScheduleID] = &assignments[i]
+	}
+	
+	now := clock.Now()
 	
 	for _, schedule := range schedules {
-		if err := validateScheduleParticipants(schedule); err != nil {
+		if ctx.Err() != nil {
+			log.Println("Stopping rotation pass for shutdown")
+			return clock.Now()
+		}
+		
+		// Archived schedules neither rotate nor notify; paused ones hand
+		// their shifts to the fallback user
+		if schedule.Status == ScheduleStatusArchived {
+			continue
+		}
+		
+		if err := validateScheduleParticipants(ctx, schedule); err != nil {
 			log.Printf("Invalid schedule participants: %v", err)
 			continue
 		}
 
-		fmt.Println("is now after schedule start time", now.After(schedule.StartTime))
-		fmt.Println("is now before schedule end time", now.Before(schedule.EndTime))
-
 		if now.After(schedule.StartTime) && now.Before(schedule.EndTime) {
-			fmt.Println("now is between the schedule start and end")
-			currentAssignment := getCurrentAssignmentForSchedule(schedule.ID)
+			currentAssignment := currentAssignments[schedule.ID]
 			
-			if currentAssignment == nil || shouldRotate(currentAssignment, schedule.RotationPeriod, now) {
-				if currentAssignment != nil {
-					deactivateAssignment(currentAssignment.ID)
-				}
-				
-				nextUserID := getNextOnCallUser(schedule)
-				fmt.Println("next user id", nextUserID)
-				if nextUserID != 0 {
-					rotationStart := calculateRotationStart(schedule.StartTime, schedule.RotationPeriod, now)
-					rotationEnd := rotationStart.Add(time.Duration(schedule.RotationPeriod) * time.Second)
-					
-					err := createOnCallAssignment(schedule.ID, nextUserID, rotationStart, rotationEnd)
-					if err != nil {
-						log.Printf("Error creating assignment: %v", err)
-						continue
-					}
-					
-					user, err := getUserByID(nextUserID)
-					if err != nil {
-						log.Printf("Error getting user: %v", err)
-						continue
-					}
-					
-					log.Printf("New on-call assignment: %s (%s) for schedule %s", user.Email, user.SlackHandle, schedule.Name)
-					
-					go sendSlackNotification(user, schedule.Name, rotationStart, rotationEnd)
-				}
+			var err error
+			switch {
+			case currentAssignment == nil || shouldRotate(schedule, currentAssignment, now):
+				err = materializeSchedule(ctx, schedule, currentAssignment, now, fencingToken)
+			case resumedDuringAssignment(schedule, currentAssignment):
+				err = handBackSchedule(ctx, schedule, currentAssignment, now, fencingToken)
+			}
+			if err == errFencedOut {
+				log.Printf("Stopping rotation pass: %v", err)
+				return clock.Now().Add(leaderHeartbeat)
+			}
+			if err != nil {
+				log.Printf("Error rotating schedule %s: %v", schedule.Name, err)
+			}
+		}
+	}
+	
+	return nextScheduleBoundary(schedules, now)
+}
+
+// nextScheduleBoundary returns the earliest upcoming start, rotation or end
+// of any schedule that is not archived after now, no later than
+// maxSchedulerSleep from now. Pausing or resuming a schedule wakes the
+// scheduler through its change notification.
+func nextScheduleBoundary(schedules []Schedule, now time.Time) time.Time {
+	next := now.Add(maxSchedulerSleep)
+	for _, schedule := range schedules {
+		if schedule.Status == ScheduleStatusArchived {
+			continue
+		}
+		
+		var boundary time.Time
+		switch {
+		case !now.After(schedule.StartTime):
+			boundary = schedule.StartTime
+		case now.Before(schedule.EndTime) && schedule.RotationPeriod > 0:
+			_, boundary, _ = shiftAt(schedule, now)
+			if boundary.After(schedule.EndTime) {
+				boundary = schedule.EndTime
 			}
+		default:
+			continue
+		}
+		
+		boundary = boundary.Add(boundarySlack)
+		if boundary.Before(next) {
+			next = boundary
 		}
 	}
+	return next
 }
 
-func getCurrentAssignmentForSchedule(scheduleID int) *OnCallAssignment {
-	assignments, err := getCurrentOnCallAssignments()
+// materializeSchedule records the schedule's projected shifts up to the one
+// in progress at now. Shifts missed while no scheduler was running, e.g.
+// during downtime, are backfilled as inactive assignments without notifying
+// anyone; the current shift is handed over with the usual notifications.
+// While the schedule is paused its shifts go to the fallback user, who is
+// notified once when they take over rather than at every shift. Shutdown
+// stops a long backfill between shifts.
+func materializeSchedule(ctx context.Context, schedule Schedule, latest *OnCallAssignment, now time.Time, fencingToken int64) error {
+	rotationStart, rotationEnd, definition := shiftAt(schedule, now)
+	from := firstUnmaterializedShift(schedule, latest)
+	if from.After(rotationStart) {
+		from = rotationStart
+	}
+	
+	shifts, err := projectSchedule(ctx, schedule, from, rotationEnd)
 	if err != nil {
-		log.Printf("Error getting current assignments: %v", err)
+		return err
+	}
+	
+	// The last shift is the one in progress, unless the schedule has ended.
+	// Those before it were missed, including the part of the current shift
+	// before a resume (see applyPauses).
+	missed := shifts
+	if n := len(shifts); n > 0 && !shifts[n-1].StartTime.Before(rotationStart) {
+		missed = shifts[:n-1]
+	}
+	
+	backfilled := 0
+	for _, shift := range missed {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		created, _, err := rotateSchedule(ctx, schedule, latest, shift, true, fencingToken)
+		if err != nil {
+			return err
+		}
+		latest = nil
+		if created {
+			backfilled++
+		}
+	}
+	if backfilled > 0 {
+		log.Printf("Backfilled %d missed rotations for schedule %s", backfilled, schedule.Name)
+	}
+	
+	if len(shifts) == 0 || shifts[len(shifts)-1].StartTime.Before(rotationStart) {
 		return nil
 	}
-
-	fmt.Printf("Found %d current assignments\n", len(assignments))
-	for _, assignment := range assignments {
-		fmt.Printf("Checking assignment - ID: %d, ScheduleID: %d, UserID: %d, StartTime: %v, EndTime: %v, Active: %v\n",
-			assignment.ID, assignment.ScheduleID, assignment.UserID, assignment.StartTime, assignment.EndTime, assignment.Active)
-		if assignment.ScheduleID == scheduleID {
-			return &assignment
+	shift := shifts[len(shifts)-1]
+	if shift.Skipped {
+		log.Printf("Skipping rotation for holiday %s on schedule %s", shift.HolidayName, schedule.Name)
+	}
+	log.Printf("Next on-call user for schedule %s: %d", schedule.Name, shift.UserID)
+	
+	created, shadows, err := rotateSchedule(ctx, schedule, latest, shift, false, fencingToken)
+	if err != nil {
+		return err
+	}
+	
+	// A paused schedule without a fallback user is reported by the coverage
+	// checker instead
+	if shift.UserID == 0 && !shift.Skipped && !shift.Paused && len(definition.Participants) > 0 {
+		alertUncoveredShift(ctx, schedule, shift.StartTime, shift.EndTime, fencingToken)
+	}
+	if !created {
+		// Nobody to assign, or this rotation was already recorded
+		return nil
+	}
+	
+	if shift.Paused {
+		// The fallback user was told when they took over the pause
+		if latest != nil && latest.UserID == shift.UserID && !latest.StartTime.Before(schedule.StatusChangedAt) {
+			return nil
 		}
+		return announceHandoff(ctx, schedule, shift, shadows)
+	}
+	
+	if err := announceHandoff(ctx, schedule, shift, shadows); err != nil {
+		return err
 	}
+	warnIfNextShiftUncovered(ctx, schedule, shift.EndTime, fencingToken)
 	return nil
 }
 
-func shouldRotate(assignment *OnCallAssignment, rotationPeriod int, now time.Time) bool {
-	shouldRotate := now.After(assignment.EndTime)
-	fmt.Printf("Checking rotation - Now: %v, Assignment End: %v, Should Rotate: %v\n", 
-		now, assignment.EndTime, shouldRotate)
-	return shouldRotate
+// resumedDuringAssignment reports whether the schedule was resumed after the
+// assignment in progress started, which may then belong to the fallback
+// user rather than to whoever the rotation has reached.
+func resumedDuringAssignment(schedule Schedule, assignment *OnCallAssignment) bool {
+	return schedule.Status == ScheduleStatusActive && assignment.Active && assignment.StartTime.Before(schedule.StatusChangedAt)
 }
 
-func validateScheduleParticipants(schedule Schedule) error {
-	for _, userID := range schedule.Participants {
-		user, err := getUserByID(userID)
-		if err != nil {
-			return fmt.Errorf("participant user %d not found: %v", userID, err)
-		}
-		fmt.Printf("Validated participant: ID %d, Email %s\n", user.ID, user.Email)
+// handBackSchedule hands the rest of the shift in progress from the fallback
+// user to whoever the rotation has reached, once the schedule is resumed.
+// It changes nothing if they are the same person, e.g. after a pause that
+// started and ended within the shift.
+func handBackSchedule(ctx context.Context, schedule Schedule, current *OnCallAssignment, now time.Time, fencingToken int64) error {
+	_, rotationEnd, _ := shiftAt(schedule, now)
+	shifts, err := projectSchedule(ctx, schedule, now, rotationEnd)
+	if err != nil {
+		return err
+	}
+	// The projection splits the shift at the resume (see applyPauses)
+	for len(shifts) > 0 && shifts[len(shifts)-1].StartTime.After(now) {
+		shifts = shifts[:len(shifts)-1]
+	}
+	if len(shifts) == 0 || shifts[len(shifts)-1].UserID == current.UserID {
+		return nil
+	}
+	
+	// The fallback user covered the shift until the resume, including when
+	// the pause was not recorded
+	shift := shifts[len(shifts)-1]
+	if shift.StartTime.Before(schedule.StatusChangedAt) {
+		shift.StartTime = schedule.StatusChangedAt
+	}
+	log.Printf("Handing schedule %s back from the fallback user to user %d", schedule.Name, shift.UserID)
+	
+	created, shadows, err := rotateSchedule(ctx, schedule, current, shift, false, fencingToken)
+	if err != nil || !created {
+		return err
+	}
+	return announceHandoff(ctx, schedule, shift, shadows)
+}
+
+// announceHandoff tells the shift's on-caller, and any shadows paired with
+// them, that they are on call, with times in the schedule's time zone.
+func announceHandoff(ctx context.Context, schedule Schedule, shift ProjectedShift, shadows []OnCallAssignment) error {
+	user, err := store.GetUserByID(ctx, shift.UserID)
+	if err != nil {
+		return fmt.Errorf("error getting user: %v", err)
 	}
+	
+	log.Printf("New on-call assignment: %s (%s) for schedule %s", user.Email, user.SlackHandle, schedule.Name)
+	
+	_, _, definition := shiftAt(schedule, shift.StartTime)
+	location := scheduleLocation(definition.ScheduleDefinition)
+	notifier.OnCallHandoff(ctx, user, schedule.Name, shift.StartTime.In(location), shift.EndTime.In(location))
+	
+	notifyShadows(ctx, schedule, user, shadows, location)
 	return nil
 }
 
-func getNextOnCallUser(schedule Schedule) int {
-	if len(schedule.Participants) == 0 {
-		fmt.Println("No participants in schedule")
-		return 0
+// firstUnmaterializedShift returns the start of the first shift the scheduler
+// has not recorded yet: the one after the latest assignment or, for a schedule
+// that has never rotated, the shift in force when it was created. It is never
+// earlier than the shift in force at the last pause or resume, so a resumed
+// schedule does not backfill the pause with the rotation's picks, which the
+// fallback user covered.
+func firstUnmaterializedShift(schedule Schedule, latest *OnCallAssignment) time.Time {
+	since := schedule.StartTime
+	if schedule.CreatedAt.After(since) {
+		since = schedule.CreatedAt
+	}
+	if schedule.StatusChangedAt.After(since) {
+		since = schedule.StatusChangedAt
 	}
+	start, _, _ := shiftAt(schedule, since)
+	
+	if latest != nil {
+		if _, end, _ := shiftAt(schedule, latest.StartTime); end.After(start) {
+			return end
+		}
+	}
+	return start
+}
 
-	fmt.Printf("Schedule participants: %v\n", schedule.Participants)
+// rotateSchedule hands the schedule over to the projected shift's on-caller,
+// or to nobody when it has none: the ended assignment is deactivated and the
+// new primary assignment is recorded together with any shadows scheduled
+// during the shift, all in one go (see Store.RecordRotation). Shadow
+// assignments are recorded so their hours can be reported, but they never
+// become the current on-call person.
+//
+// It reports whether the primary assignment was newly created and returns the
+// shadow assignments, clipped to the rotation window, that were paired with
+// it. When the rotation had already been recorded nothing is created, so
+// callers only notify people on the first successful attempt. Backfilled
+// assignments are recorded as already inactive. Once started, the rotation
+// is written even if ctx is cancelled, so shutdown never cuts one short.
+func rotateSchedule(ctx context.Context, schedule Schedule, current *OnCallAssignment, shift ProjectedShift, backfill bool, fencingToken int64) (bool, []OnCallAssignment, error) {
+	if shift.UserID == 0 {
+		created, _, err := store.RecordRotation(context.WithoutCancel(ctx), current, shift.StartTime, nil, nil, fencingToken)
+		return created, nil, err
+	}
+	
+	primary := &OnCallAssignment{ScheduleID: schedule.ID, UserID: shift.UserID, StartTime: shift.StartTime, EndTime: shift.EndTime,
+		Role: AssignmentRolePrimary, Holiday: shift.Holiday, Active: !backfill, Backfilled: backfill}
+	
+	shadowShifts, err := store.GetShadowShiftsForWindow(ctx, schedule.ID, shift.StartTime, shift.EndTime)
+	if err != nil {
+		return false, nil, fmt.Errorf("error getting shadow shifts: %v", err)
+	}
+	
+	var shadows []OnCallAssignment
+	for _, shadowShift := range shadowShifts {
+		// Clip the shadow's time to the rotation window it is paired with
+		shadow := *primary
+		shadow.UserID = shadowShift.UserID
+		shadow.Role = AssignmentRoleShadow
+		if shadowShift.StartTime.After(shadow.StartTime) {
+			shadow.StartTime = shadowShift.StartTime
+		}
+		if shadowShift.EndTime.Before(shadow.EndTime) {
+			shadow.EndTime = shadowShift.EndTime
+		}
+		shadows = append(shadows, shadow)
+	}
+	
+	return store.RecordRotation(context.WithoutCancel(ctx), current, shift.StartTime, primary, shadows, fencingToken)
+}
 
-	currentAssignment := getCurrentAssignmentForSchedule(schedule.ID)
-	if currentAssignment == nil {
-		fmt.Printf("No current assignment, starting rotation with first user: %d\n", schedule.Participants[0])
-		return schedule.Participants[0]
+// notifyShadows tells each shadow who they are paired with, with shift times
+// in the given location.
+func notifyShadows(ctx context.Context, schedule Schedule, primary *User, shadows []OnCallAssignment, location *time.Location) {
+	for _, shift := range shadows {
+		shadow, err := store.GetUserByID(ctx, shift.UserID)
+		if err != nil {
+			log.Printf("Error getting shadow user: %v", err)
+			continue
+		}
+		
+		log.Printf("New shadow assignment: %s shadowing %s for schedule %s", shadow.Email, primary.Email, schedule.Name)
+		
+		notifier.ShadowPaired(ctx, shadow, primary, schedule.Name, shift.StartTime.In(location), shift.EndTime.In(location))
 	}
+}
 
-	fmt.Printf("Current assignment: ID=%d, UserID=%d, StartTime=%v, EndTime=%v, Active=%v\n",
-		currentAssignment.ID, currentAssignment.UserID, currentAssignment.StartTime,
-		currentAssignment.EndTime, currentAssignment.Active)
+// shouldRotate reports whether a shift has started since the assignment's.
+// A new version taking effect can end a shift before its assignment's
+// recorded end time.
+func shouldRotate(schedule Schedule, assignment *OnCallAssignment, now time.Time) bool {
+	rotationStart, _, _ := shiftAt(schedule, now)
+	return rotationStart.After(assignment.StartTime)
+}
 
-	// Find current user's position
-	currentIndex := -1
-	for i, participant := range schedule.Participants {
-		if participant == currentAssignment.UserID {
-			currentIndex = i
-			break
+func validateScheduleParticipants(ctx context.Context, schedule Schedule) error {
+	for _, userID := range schedule.Participants {
+		if _, err := store.GetUserByID(ctx, userID); err != nil {
+			return fmt.Errorf("participant user %d not found: %v", userID, err)
 		}
 	}
+	return nil
+}
+
+// holidayParticipants returns the participants of the schedule's holiday
+// rotation.
+func holidayParticipants(ctx context.Context, schedule Schedule) ([]int, error) {
+	if schedule.HolidayScheduleID == 0 {
+		return nil, fmt.Errorf("holiday mode is %s but no holiday schedule is set", schedule.HolidayMode)
+	}
+	holidaySchedule, err := store.GetScheduleByID(ctx, schedule.HolidayScheduleID)
+	if err != nil {
+		return nil, err
+	}
+	return holidaySchedule.Participants, nil
+}
 
-	if currentIndex == -1 {
-		fmt.Printf("Current user %d not found in participants list %v, starting with first user\n",
-			currentAssignment.UserID, schedule.Participants)
-		return schedule.Participants[0]
+// warnIfNextShiftUncovered looks one rotation ahead and alerts the team
+// channel when every participant is unavailable for the upcoming shift, so
+// cover can be arranged before the handoff rather than after it.
+func warnIfNextShiftUncovered(ctx context.Context, schedule Schedule, nextStart time.Time, fencingToken int64) {
+	if !nextStart.Before(schedule.EndTime) {
+		return
+	}
+	_, nextEnd, definition := shiftAt(schedule, nextStart)
+	
+	unavailable, err := store.GetUnavailableUserIDs(ctx, nextStart, nextEnd)
+	if err != nil {
+		log.Printf("Error getting unavailable users: %v", err)
+		return
+	}
+	
+	for _, participant := range definition.Participants {
+		if !unavailable[participant] {
+			return
+		}
 	}
+	
+	alertUncoveredShift(ctx, schedule, nextStart, nextEnd, fencingToken)
+}
 
-	// Get next user
-	nextIndex := (currentIndex + 1) % len(schedule.Participants)
-	nextUser := schedule.Participants[nextIndex]
-	fmt.Printf("Current user %d at index %d, rotating to next user %d at index %d\n",
-		currentAssignment.UserID, currentIndex, nextUser, nextIndex)
-	return nextUser
+// alertUncoveredShift warns the team channel that nobody is available for
+// the shift, unless it was already warned about. A shift nobody can take is
+// re-examined on every pass until it ends, and is usually warned about a
+// rotation ahead too, so the alerts sent are recorded (see
+// Store.ClaimCoverageGapAlert).
+func alertUncoveredShift(ctx context.Context, schedule Schedule, start, end time.Time, fencingToken int64) {
+	gap := CoverageGap{ScheduleID: schedule.ID, ScheduleName: schedule.Name, TeamID: schedule.TeamID,
+		StartTime: start, EndTime: end, Reason: GapReasonUnfilledPTO}
+	
+	claimed, err := store.ClaimCoverageGapAlert(ctx, gap, fencingToken)
+	if err != nil {
+		log.Printf("Error recording the coverage gap alert for schedule %s: %v", schedule.Name, err)
+		return
+	}
+	if claimed {
+		notifier.CoverageGap(ctx, gap)
+	}
 }
 
 func calculateRotationStart(scheduleStart time.Time, rotationPeriod int, now time.Time) time.Time {
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// rotationFixture is a daily round-robin schedule of users 1 and 2 starting
+// on monday, with user 3 on the team to shadow them.
+func rotationFixture() SimulationFixture {
+	return SimulationFixture{
+		Users: []User{{ID: 1, TeamIDs: []int{1}}, {ID: 2, TeamIDs: []int{1}}, {ID: 3, TeamIDs: []int{1}}},
+		Schedules: []SimulationSchedule{
+			{Schedule: Schedule{ID: 1, TeamID: 1, Name: "ops", StartTime: monday, EndTime: monday.AddDate(1, 0, 0),
+				ScheduleDefinition: ScheduleDefinition{RotationPeriod: 86400, Participants: []int{1, 2}}}},
+		},
+	}
+}
+
+// assignmentsByRole returns the simulated assignments with the role.
+func assignmentsByRole(result SimulationResult, role string) []OnCallAssignment {
+	var assignments []OnCallAssignment
+	for _, assignment := range result.Assignments {
+		if assignment.Role == role {
+			assignments = append(assignments, assignment)
+		}
+	}
+	return assignments
+}
+
+func TestShadowPairing(t *testing.T) {
+	fixture := rotationFixture()
+	// Half of Tuesday's shift and half of Wednesday's
+	fixture.ShadowShifts = []ShadowShift{{ScheduleID: 1, UserID: 3,
+		StartTime: monday.Add(36 * time.Hour), EndTime: monday.Add(60 * time.Hour)}}
+
+	result, err := simulate(fixture, monday.Add(12*time.Hour), monday.AddDate(0, 0, 4))
+	if err != nil {
+		t.Fatalf("simulate() error = %v", err)
+	}
+
+	for _, primary := range assignmentsByRole(result, AssignmentRolePrimary) {
+		if primary.UserID == 3 {
+			t.Errorf("shadow was put on call at %s", primary.StartTime.Format(time.RFC3339))
+		}
+	}
+
+	want := []struct{ start, end time.Time }{
+		{monday.Add(36 * time.Hour), monday.Add(48 * time.Hour)},
+		{monday.Add(48 * time.Hour), monday.Add(60 * time.Hour)},
+	}
+	shadows := assignmentsByRole(result, AssignmentRoleShadow)
+	if len(shadows) != len(want) {
+		t.Fatalf("got %d shadow assignments, want %d: %+v", len(shadows), len(want), shadows)
+	}
+	for i, shadow := range shadows {
+		if shadow.UserID != 3 || !shadow.StartTime.Equal(want[i].start) || !shadow.EndTime.Equal(want[i].end) {
+			t.Errorf("shadow %d = user %d from %s to %s, want user 3 from %s to %s", i, shadow.UserID,
+				shadow.StartTime.Format(time.RFC3339), shadow.EndTime.Format(time.RFC3339),
+				want[i].start.Format(time.RFC3339), want[i].end.Format(time.RFC3339))
+		}
+	}
+
+	var paired int
+	for _, notification := range result.Notifications {
+		if notification.Kind == "shadow" {
+			paired++
+			if notification.UserID != 3 {
+				t.Errorf("shadow notification went to user %d", notification.UserID)
+			}
+		}
+	}
+	if paired != len(want) {
+		t.Errorf("got %d shadow notifications, want %d", paired, len(want))
+	}
+}
+
+func TestUnavailableParticipantsAreSkipped(t *testing.T) {
+	fixture := rotationFixture()
+	thursday, saturday := monday.AddDate(0, 0, 3), monday.AddDate(0, 0, 5)
+	fixture.Unavailability = []Unavailability{
+		{UserID: 2, StartTime: thursday, EndTime: thursday.AddDate(0, 0, 1)},
+		{UserID: 1, StartTime: saturday, EndTime: saturday.AddDate(0, 0, 1)},
+		{UserID: 2, StartTime: saturday.Add(6 * time.Hour), EndTime: saturday.Add(18 * time.Hour)},
+	}
+
+	result, err := simulate(fixture, monday.Add(12*time.Hour), monday.AddDate(0, 0, 7))
+	if err != nil {
+		t.Fatalf("simulate() error = %v", err)
+	}
+
+	// User 1 covers user 2's Thursday and the rotation carries on; nobody
+	// can take Saturday, which is alerted instead
+	got := make(map[int]int)
+	for _, primary := range assignmentsByRole(result, AssignmentRolePrimary) {
+		got[int(primary.StartTime.Sub(monday)/(24*time.Hour))] = primary.UserID
+	}
+	want := map[int]int{0: 1, 1: 2, 2: 1, 3: 1, 4: 2, 6: 1}
+	for day := 0; day < 7; day++ {
+		if got[day] != want[day] {
+			t.Errorf("day %d: user %d on call, want %d", day, got[day], want[day])
+		}
+	}
+
+	var gaps []SimulatedNotification
+	for _, notification := range result.Notifications {
+		if notification.Kind == "coverage_gap" {
+			gaps = append(gaps, notification)
+		}
+	}
+	if len(gaps) != 1 || !gaps[0].StartTime.Equal(saturday) || gaps[0].Reason != GapReasonUnfilledPTO {
+		t.Errorf("coverage gap alerts = %+v, want one for Saturday", gaps)
+	}
+}
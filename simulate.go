@@ -0,0 +1,431 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"time"
+)
+
+// SimulationFixture is the world a simulation runs in, read from a JSON
+// file. Schedules without versions get their definition as version 1, and
+// assignments already recorded can be included to replay a recovery.
+type SimulationFixture struct {
+	Users          []User               `json:"users"`
+	Schedules      []SimulationSchedule `json:"schedules"`
+	ShadowShifts   []ShadowShift        `json:"shadow_shifts"`
+	Unavailability []Unavailability     `json:"unavailability"`
+	Holidays       []Holiday            `json:"holidays"`
+	Assignments    []OnCallAssignment   `json:"assignments"`
+}
+
+// SimulationSchedule is a schedule in a fixture, whose versions are given
+// inline.
+type SimulationSchedule struct {
+	Schedule
+	Versions []ScheduleVersion `json:"versions"`
+	Pauses   []SchedulePause   `json:"pauses"`
+}
+
+// SimulationResult is everything the scheduler did over the simulated range.
+type SimulationResult struct {
+	Assignments   []OnCallAssignment       `json:"assignments"`
+	Notifications []SimulatedNotification `json:"notifications"`
+}
+
+// SimulatedNotification is a message the scheduler would have sent.
+type SimulatedNotification struct {
+	At         time.Time `json:"at"`   // virtual time it was sent
+	Kind       string    `json:"kind"` // handoff, shadow or coverage_gap
+	ScheduleID int       `json:"schedule_id,omitempty"`
+	Schedule   string    `json:"schedule"`
+	UserID     int       `json:"user_id,omitempty"`
+	StartTime  time.Time `json:"start_time"`
+	EndTime    time.Time `json:"end_time"`
+	Reason     string    `json:"reason,omitempty"`
+}
+
+// simulate runs the scheduler over [from, to) on a virtual clock against an
+// in-memory copy of the fixture, jumping straight from one wake-up to the
+// next, and returns every assignment and notification it produced.
+func simulate(fixture SimulationFixture, from, to time.Time) (SimulationResult, error) {
+	memory, err := newMemoryStore(fixture)
+	if err != nil {
+		return SimulationResult{}, err
+	}
+	virtual := &virtualClock{now: from}
+	recorder := &recordingNotifier{clock: virtual}
+	
+	previousClock, previousStore, previousNotifier, previousCheckpoints := clock, store, notifier, replayCheckpoints
+	clock, store, notifier, replayCheckpoints = virtual, memory, recorder, newReplayCache()
+	defer func() {
+		clock, store, notifier, replayCheckpoints = previousClock, previousStore, previousNotifier, previousCheckpoints
+	}()
+	
+	for virtual.Now().Before(to) {
+		next := checkAndUpdateSchedules(context.Background(), 0)
+		if !next.After(virtual.Now()) {
+			next = virtual.Now().Add(boundarySlack)
+		}
+		virtual.Set(next)
+	}
+	
+	return SimulationResult{Assignments: memory.assignments, Notifications: recorder.notifications}, nil
+}
+
+// runSimulateCommand implements "go-oncall simulate", printing what the
+// scheduler would do over a time range. It returns the exit code.
+func runSimulateCommand(args []string) int {
+	flags := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	fixturePath := flags.String("fixture", "", "JSON file with the users, schedules and overrides to simulate")
+	fromFlag := flags.String("from", "", "start of the simulated range, RFC 3339")
+	toFlag := flags.String("to", "", "end of the simulated range, RFC 3339")
+	asJSON := flags.Bool("json", false, "print the result as JSON")
+	verbose := flags.Bool("v", false, "show the scheduler's log")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *fixturePath == "" || *fromFlag == "" || *toFlag == "" {
+		fmt.Fprintln(os.Stderr, "usage: go-oncall simulate -fixture FILE -from TIME -to TIME [-json] [-v]")
+		return 2
+	}
+	
+	from, err := time.Parse(time.RFC3339, *fromFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid -from: %v\n", err)
+		return 2
+	}
+	to, err := time.Parse(time.RFC3339, *toFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid -to: %v\n", err)
+		return 2
+	}
+	
+	file, err := os.Open(*fixturePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening fixture: %v\n", err)
+		return 1
+	}
+	defer file.Close()
+	
+	var fixture SimulationFixture
+	if err := json.NewDecoder(file).Decode(&fixture); err != nil {
+		fmt.Fprintf(os.Stderr, "Error decoding fixture: %v\n", err)
+		return 1
+	}
+	
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+	
+	result, err := simulate(fixture, from, to)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error simulating: %v\n", err)
+		return 1
+	}
+	
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(result)
+		return 0
+	}
+	
+	// Show each schedule's shifts in its own time zone
+	locations := make(map[int]*time.Location)
+	for _, schedule := range fixture.Schedules {
+		locations[schedule.ID] = scheduleLocation(schedule.ScheduleDefinition)
+	}
+	
+	fmt.Println("Assignments:")
+	for _, assignment := range result.Assignments {
+		location, ok := locations[assignment.ScheduleID]
+		if !ok {
+			location = time.UTC
+		}
+		flags := assignment.Role
+		if assignment.Holiday {
+			flags += ", holiday"
+		}
+		if assignment.Backfilled {
+			flags += ", backfilled"
+		}
+		fmt.Printf("  schedule %d  %s - %s  user %d (%s)\n", assignment.ScheduleID,
+			assignment.StartTime.In(location).Format("2006-01-02 15:04 MST"), assignment.EndTime.In(location).Format("2006-01-02 15:04 MST"), assignment.UserID, flags)
+	}
+	
+	fmt.Println("Notifications:")
+	for _, notification := range result.Notifications {
+		at := notification.At.In(notification.StartTime.Location())
+		fmt.Printf("  at %s  %s  %s  user %d  %s - %s %s\n", at.Format("2006-01-02 15:04 MST"), notification.Kind,
+			notification.Schedule, notification.UserID, notification.StartTime.Format("2006-01-02 15:04 MST"),
+			notification.EndTime.Format("2006-01-02 15:04 MST"), notification.Reason)
+	}
+	return 0
+}
+
+// recordingNotifier is a Notifier that records messages instead of
+// sending them.
+type recordingNotifier struct {
+	clock         Clock
+	notifications []SimulatedNotification
+}
+
+func (n *recordingNotifier) OnCallHandoff(ctx context.Context, user *User, scheduleName string, startTime, endTime time.Time) {
+	n.notifications = append(n.notifications, SimulatedNotification{At: n.clock.Now(), Kind: "handoff",
+		Schedule: scheduleName, UserID: user.ID, StartTime: startTime, EndTime: endTime})
+}
+
+func (n *recordingNotifier) ShadowPaired(ctx context.Context, shadow, primary *User, scheduleName string, startTime, endTime time.Time) {
+	n.notifications = append(n.notifications, SimulatedNotification{At: n.clock.Now(), Kind: "shadow",
+		Schedule: scheduleName, UserID: shadow.ID, StartTime: startTime, EndTime: endTime})
+}
+
+func (n *recordingNotifier) CoverageGap(ctx context.Context, gap CoverageGap) {
+	n.notifications = append(n.notifications, SimulatedNotification{At: n.clock.Now(), Kind: "coverage_gap",
+		ScheduleID: gap.ScheduleID, Schedule: gap.ScheduleName, StartTime: gap.StartTime, EndTime: gap.EndTime, Reason: gap.Reason})
+}
+
+func (n *recordingNotifier) Drain(ctx context.Context) error {
+	return nil
+}
+
+// memoryStore is a Store held in memory, for simulations. It keeps the same
+// rules as Postgres: one primary assignment per schedule and start time, and
+// one shadow assignment per schedule, start time and user.
+type memoryStore struct {
+	users          map[int]User
+	schedules      []Schedule
+	shadowShifts   []ShadowShift
+	unavailability []Unavailability
+	holidays       []Holiday
+	assignments    []OnCallAssignment
+	gapAlerts      map[int]map[time.Time]bool // schedule ID to alerted shift starts
+	nextID         int
+}
+
+// newMemoryStore loads a fixture, validating each schedule definition the
+// same way the API does.
+func newMemoryStore(fixture SimulationFixture) (*memoryStore, error) {
+	m := &memoryStore{
+		users:          make(map[int]User),
+		shadowShifts:   fixture.ShadowShifts,
+		unavailability: fixture.Unavailability,
+		holidays:       fixture.Holidays,
+		gapAlerts:      make(map[int]map[time.Time]bool),
+	}
+	for _, user := range fixture.Users {
+		m.users[user.ID] = user
+	}
+	
+	for _, fixtureSchedule := range fixture.Schedules {
+		schedule := fixtureSchedule.Schedule
+		schedule.Versions = fixtureSchedule.Versions
+		schedule.Pauses = fixtureSchedule.Pauses
+		if schedule.Status == "" {
+			schedule.Status = ScheduleStatusActive
+		}
+		if schedule.Status == ScheduleStatusPaused && len(schedule.Pauses) == 0 {
+			schedule.Pauses = []SchedulePause{{PausedAt: schedule.StatusChangedAt, FallbackUserID: schedule.FallbackUserID}}
+		}
+		if len(schedule.Versions) == 0 {
+			schedule.Versions = []ScheduleVersion{{ScheduleID: schedule.ID, Version: 1, EffectiveFrom: schedule.StartTime,
+				ScheduleDefinition: schedule.ScheduleDefinition}}
+		}
+		for i := range schedule.Versions {
+			version := &schedule.Versions[i]
+			if err := validateScheduleDefinition(&version.ScheduleDefinition); err != nil {
+				return nil, fmt.Errorf("schedule %d version %d: %v", schedule.ID, version.Version, err)
+			}
+			version.ScheduleID = schedule.ID
+		}
+		sort.Slice(schedule.Versions, func(i, j int) bool {
+			return schedule.Versions[i].Version < schedule.Versions[j].Version
+		})
+		m.schedules = append(m.schedules, schedule)
+	}
+	
+	for _, assignment := range fixture.Assignments {
+		m.insertAssignment(assignment)
+	}
+	return m, nil
+}
+
+func (m *memoryStore) GetSchedules(ctx context.Context) ([]Schedule, error) {
+	schedules := make([]Schedule, len(m.schedules))
+	for i, schedule := range m.schedules {
+		schedules[i] = versionAt(schedule, clock.Now())
+	}
+	return schedules, nil
+}
+
+func (m *memoryStore) GetScheduleByID(ctx context.Context, scheduleID int) (*Schedule, error) {
+	for _, schedule := range m.schedules {
+		if schedule.ID == scheduleID {
+			schedule = versionAt(schedule, clock.Now())
+			return &schedule, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *memoryStore) GetUserByID(ctx context.Context, userID int) (*User, error) {
+	user, ok := m.users[userID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &user, nil
+}
+
+func (m *memoryStore) GetCurrentOnCallAssignments(ctx context.Context) ([]OnCallAssignment, error) {
+	latest := make(map[int]OnCallAssignment)
+	for _, assignment := range m.assignments {
+		if assignment.Role != AssignmentRolePrimary || assignment.Tier != 1 {
+			continue
+		}
+		if current, ok := latest[assignment.ScheduleID]; !ok || assignment.StartTime.After(current.StartTime) {
+			latest[assignment.ScheduleID] = assignment
+		}
+	}
+	
+	var assignments []OnCallAssignment
+	for _, assignment := range latest {
+		assignments = append(assignments, assignment)
+	}
+	return assignments, nil
+}
+
+func (m *memoryStore) GetRecordedAssignments(ctx context.Context, scheduleID int, from, to time.Time) ([]OnCallAssignment, error) {
+	var assignments []OnCallAssignment
+	for _, assignment := range m.assignments {
+		if assignment.ScheduleID == scheduleID && assignment.Role == AssignmentRolePrimary && assignment.Tier == 1 &&
+			assignment.StartTime.Before(to) && assignment.EndTime.After(from) {
+			assignments = append(assignments, assignment)
+		}
+	}
+	sort.Slice(assignments, func(i, j int) bool { return assignments[i].StartTime.Before(assignments[j].StartTime) })
+	return assignments, nil
+}
+
+func (m *memoryStore) GetShadowShiftsForWindow(ctx context.Context, scheduleID int, startTime, endTime time.Time) ([]ShadowShift, error) {
+	var shifts []ShadowShift
+	for _, shift := range m.shadowShifts {
+		if shift.ScheduleID == scheduleID && shift.StartTime.Before(endTime) && shift.EndTime.After(startTime) {
+			shifts = append(shifts, shift)
+		}
+	}
+	sort.Slice(shifts, func(i, j int) bool { return shifts[i].StartTime.Before(shifts[j].StartTime) })
+	return shifts, nil
+}
+
+func (m *memoryStore) GetHolidaysBetween(ctx context.Context, teamID int, from, to time.Time) ([]Holiday, error) {
+	// Compare calendar days, as the holiday_date column does, a day either
+	// side to cover every timezone
+	first := from.UTC().AddDate(0, 0, -1).Format("2006-01-02")
+	last := to.UTC().AddDate(0, 0, 1).Format("2006-01-02")
+	
+	var holidays []Holiday
+	for _, holiday := range m.holidays {
+		day := holiday.Date.UTC().Format("2006-01-02")
+		if holiday.TeamID == teamID && day >= first && day <= last {
+			holidays = append(holidays, holiday)
+		}
+	}
+	sort.Slice(holidays, func(i, j int) bool { return holidays[i].Date.Before(holidays[j].Date) })
+	return holidays, nil
+}
+
+func (m *memoryStore) GetUnavailabilityBetween(ctx context.Context, from, to time.Time) ([]Unavailability, error) {
+	var entries []Unavailability
+	for _, entry := range m.unavailability {
+		if entry.StartTime.Before(to) && entry.EndTime.After(from) {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].StartTime.Before(entries[j].StartTime) })
+	return entries, nil
+}
+
+func (m *memoryStore) GetUnavailableUserIDs(ctx context.Context, startTime, endTime time.Time) (map[int]bool, error) {
+	return unavailableDuring(m.unavailability, startTime, endTime), nil
+}
+
+func (m *memoryStore) ClaimCoverageGapAlert(ctx context.Context, gap CoverageGap, fencingToken int64) (bool, error) {
+	alerted := m.gapAlerts[gap.ScheduleID]
+	if alerted == nil {
+		alerted = make(map[time.Time]bool)
+		m.gapAlerts[gap.ScheduleID] = alerted
+	}
+	start := gap.alertStart().UTC()
+	if alerted[start] {
+		return false, nil
+	}
+	alerted[start] = true
+	return true, nil
+}
+
+// GetRotationInputsGeneration never moves on, as nothing edits a fixture
+// while it is simulated.
+func (m *memoryStore) GetRotationInputsGeneration(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (m *memoryStore) RecordRotation(ctx context.Context, current *OnCallAssignment, endedAt time.Time, primary *OnCallAssignment, shadows []OnCallAssignment, fencingToken int64) (bool, []OnCallAssignment, error) {
+	if current != nil {
+		for i := range m.assignments {
+			if m.assignments[i].ID == current.ID && m.assignments[i].Active {
+				m.assignments[i].Active = false
+				if endedAt.Before(m.assignments[i].EndTime) {
+					m.assignments[i].EndTime = endedAt
+				}
+			}
+		}
+	}
+	
+	if primary == nil || !m.insertAssignment(*primary) {
+		return false, nil, nil
+	}
+	
+	var created []OnCallAssignment
+	for _, shadow := range shadows {
+		if m.insertAssignment(shadow) {
+			created = append(created, shadow)
+		}
+	}
+	return true, created, nil
+}
+
+// insertAssignment records the assignment unless it already exists, and
+// reports whether it was recorded.
+func (m *memoryStore) insertAssignment(assignment OnCallAssignment) bool {
+	if assignment.Tier == 0 {
+		assignment.Tier = 1
+	}
+	for _, existing := range m.assignments {
+		if existing.ScheduleID != assignment.ScheduleID || existing.Role != assignment.Role || !existing.StartTime.Equal(assignment.StartTime) {
+			continue
+		}
+		if assignment.Role == AssignmentRolePrimary && existing.Tier == assignment.Tier {
+			return false
+		}
+		if assignment.Role == AssignmentRoleShadow && existing.UserID == assignment.UserID {
+			return false
+		}
+	}
+	
+	// Fixture assignments keep their IDs, which later ones must not reuse
+	if assignment.ID == 0 {
+		m.nextID++
+		assignment.ID = m.nextID
+	} else if assignment.ID > m.nextID {
+		m.nextID = assignment.ID
+	}
+	m.assignments = append(m.assignments, assignment)
+	return true
+}
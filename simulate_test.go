@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSimulateRotations(t *testing.T) {
+	if _, err := time.LoadLocation("America/New_York"); err != nil {
+		t.Skip("no timezone data:", err)
+	}
+	start := time.Date(2024, 3, 25, 0, 0, 0, 0, time.UTC)
+	fixture := SimulationFixture{
+		Users: []User{{ID: 1, TeamIDs: []int{1}}, {ID: 2, TeamIDs: []int{1}}, {ID: 3, TeamIDs: []int{1}}},
+		Schedules: []SimulationSchedule{
+			{Schedule: Schedule{ID: 1, TeamID: 1, Name: "NY", StartTime: start, EndTime: start.AddDate(1, 0, 0),
+				ScheduleDefinition: ScheduleDefinition{RotationPeriod: 86400, Participants: []int{1, 2, 3},
+					HolidayMode: HolidayModeSkip, Timezone: "America/New_York"}}},
+		},
+		// Good Friday in New York runs from 04:00 UTC on the 29th to 04:00
+		// UTC on the 30th, so it takes two UTC-midnight shifts
+		Holidays: []Holiday{{TeamID: 1, Date: time.Date(2024, 3, 29, 0, 0, 0, 0, time.UTC), Name: "Good Friday"}},
+		Assignments: []OnCallAssignment{{ID: 3, ScheduleID: 1, UserID: 1, StartTime: start, EndTime: start.AddDate(0, 0, 1),
+			Role: AssignmentRolePrimary}},
+	}
+
+	result, err := simulate(fixture, start.Add(12*time.Hour), start.AddDate(0, 0, 7))
+	if err != nil {
+		t.Fatalf("simulate() error = %v", err)
+	}
+
+	want := []struct {
+		day    int
+		userID int
+	}{{0, 1}, {1, 2}, {2, 3}, {3, 1}, {6, 2}}
+	if len(result.Assignments) != len(want) {
+		t.Fatalf("got %d assignments, want %d: %+v", len(result.Assignments), len(want), result.Assignments)
+	}
+	ids := make(map[int]bool)
+	for i, assignment := range result.Assignments {
+		wantStart := start.AddDate(0, 0, want[i].day)
+		if !assignment.StartTime.Equal(wantStart) || assignment.UserID != want[i].userID {
+			t.Errorf("assignment %d = user %d at %s, want user %d at %s", i, assignment.UserID,
+				assignment.StartTime.Format(time.RFC3339), want[i].userID, wantStart.Format(time.RFC3339))
+		}
+		if ids[assignment.ID] {
+			t.Errorf("assignment %d reuses ID %d", i, assignment.ID)
+		}
+		ids[assignment.ID] = true
+	}
+
+	var handoffs int
+	for _, notification := range result.Notifications {
+		if notification.Kind == "handoff" {
+			handoffs++
+		}
+	}
+	if handoffs != len(want)-1 {
+		t.Errorf("got %d handoffs, want %d", handoffs, len(want)-1)
+	}
+}
@@ -1,45 +1,204 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/slack-go/slack"
 )
 
-func sendSlackNotification(user *User, scheduleName string, startTime, endTime time.Time) {
+// slackSendTimeout bounds how long one Slack message may take to send.
+const slackSendTimeout = 10 * time.Second
+
+// Notifier delivers the scheduler's messages. Production posts them to
+// Slack; simulations record them instead.
+type Notifier interface {
+	OnCallHandoff(ctx context.Context, user *User, scheduleName string, startTime, endTime time.Time)
+	ShadowPaired(ctx context.Context, shadow, primary *User, scheduleName string, startTime, endTime time.Time)
+	CoverageGap(ctx context.Context, gap CoverageGap)
+	
+	// Drain waits until every message handed over so far has been sent,
+	// or until ctx is done.
+	Drain(ctx context.Context) error
+}
+
+// notifier is the Notifier used by the scheduler and coverage checks.
+var notifier Notifier = &slackNotifier{}
+
+// slackNotifier posts each message from its own goroutine, so a slow Slack
+// API never holds up a rotation. A message is sent even if the context it
+// was handed over with is cancelled afterwards, since the rotation it
+// announces has already been recorded; Drain lets shutdown wait for them.
+type slackNotifier struct {
+	pending sync.WaitGroup
+}
+
+func (n *slackNotifier) OnCallHandoff(ctx context.Context, user *User, scheduleName string, startTime, endTime time.Time) {
+	n.send(ctx, func(ctx context.Context) {
+		sendSlackNotification(ctx, user, scheduleName, startTime, endTime)
+	})
+}
+
+func (n *slackNotifier) ShadowPaired(ctx context.Context, shadow, primary *User, scheduleName string, startTime, endTime time.Time) {
+	n.send(ctx, func(ctx context.Context) {
+		sendShadowSlackNotification(ctx, shadow, primary, scheduleName, startTime, endTime)
+	})
+}
+
+func (n *slackNotifier) CoverageGap(ctx context.Context, gap CoverageGap) {
+	n.send(ctx, func(ctx context.Context) {
+		sendCoverageAlert(ctx, gap)
+	})
+}
+
+func (n *slackNotifier) Drain(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		n.pending.Wait()
+		close(done)
+	}()
+	
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (n *slackNotifier) send(ctx context.Context, deliver func(ctx context.Context)) {
+	n.pending.Add(1)
+	go func() {
+		defer n.pending.Done()
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), slackSendTimeout)
+		defer cancel()
+		deliver(ctx)
+	}()
+}
+
+func sendSlackNotification(ctx context.Context, user *User, scheduleName string, startTime, endTime time.Time) {
+	message := fmt.Sprintf("🚨 *On-Call Rotation Update*\n\n"+
+		"**Schedule:** %s\n"+
+		"**New On-Call Person:** %s (%s)\n"+
+		"**Start Time:** %s\n"+
+		"**End Time:** %s\n\n"+
+		"Please ensure you're available during your on-call period!",
+		scheduleName,
+		user.Email,
+		user.SlackHandle,
+		startTime.Format("2006-01-02 15:04:05 MST"),
+		endTime.Format("2006-01-02 15:04:05 MST"))
+	
+	deliverSlackMessage(ctx, user, scheduleName, message)
+}
+
+// sendShadowSlackNotification tells a shadow who they are paired with. The
+// message is clearly marked so it is never mistaken for a real on-call page.
+func sendShadowSlackNotification(ctx context.Context, shadow, primary *User, scheduleName string, startTime, endTime time.Time) {
+	message := fmt.Sprintf("👀 *[SHADOW] On-Call Rotation Update*\n\n"+
+		"**Schedule:** %s\n"+
+		"**Shadowing:** %s (%s)\n"+
+		"**Start Time:** %s\n"+
+		"**End Time:** %s\n\n"+
+		"You are shadowing this shift. The primary on-call person owns all escalations.",
+		scheduleName,
+		primary.Email,
+		primary.SlackHandle,
+		startTime.Format("2006-01-02 15:04:05 MST"),
+		endTime.Format("2006-01-02 15:04:05 MST"))
+	
+	deliverSlackMessage(ctx, shadow, scheduleName, message)
+}
+
+// sendCoverageAlert warns the schedule's team channel about a window with
+// nobody on call.
+func sendCoverageAlert(ctx context.Context, gap CoverageGap) {
+	message := fmt.Sprintf("⚠️ *On-Call Coverage Gap*\n\n"+
+		"**Schedule:** %s\n"+
+		"**Start Time:** %s\n"+
+		"**End Time:** %s\n"+
+		"**Reason:** %s\n\n"+
+		"Nobody will be on call during this window. Please arrange cover!",
+		gap.ScheduleName,
+		gap.StartTime.Format("2006-01-02 15:04:05 MST"),
+		gap.EndTime.Format("2006-01-02 15:04:05 MST"),
+		describeGapReason(gap.Reason))
+	
+	channel, err := getTeamSlackChannel(ctx, gap.TeamID)
+	if err != nil {
+		log.Printf("Error getting Slack channel for team %d: %v", gap.TeamID, err)
+	}
+	
+	postSlackChannelMessage(ctx, channel, gap.ScheduleName, message)
+}
+
+func describeGapReason(reason string) string {
+	switch reason {
+	case GapReasonScheduleEnds:
+		return "the schedule ends"
+	case GapReasonNoParticipants:
+		return "the schedule has no participants"
+	case GapReasonInvalidParticipants:
+		return "a participant no longer exists, so the schedule is not rotating"
+	case GapReasonParticipantLeft:
+		return "the participant due on call is no longer on the team"
+	case GapReasonUnfilledPTO:
+		return "every participant is unavailable"
+	case GapReasonPaused:
+		return "the schedule is paused and has no fallback user"
+	}
+	return reason
+}
+
+// postSlackChannelMessage posts to the given channel, falling back to
+// SLACK_CHANNEL when it is empty.
+func postSlackChannelMessage(ctx context.Context, slackChannel, scheduleName, message string) {
 	slackToken := os.Getenv("SLACK_TOKEN")
 	if slackToken == "" {
 		log.Println("SLACK_TOKEN not set, skipping Slack notification")
 		return
 	}
 	
-	slackChannel := os.Getenv("SLACK_CHANNEL")
+	if slackChannel == "" {
+		slackChannel = os.Getenv("SLACK_CHANNEL")
+	}
 	if slackChannel == "" {
 		slackChannel = "#oncall"
 	}
 	
 	api := slack.New(slackToken)
 	
-	message := fmt.Sprintf("🚨 *On-Call Rotation Update*\n\n"+
-		"**Schedule:** %s\n"+
-		"**New On-Call Person:** %s (%s)\n"+
-		"**Start Time:** %s\n"+
-		"**End Time:** %s\n\n"+
-		"Please ensure you're available during your on-call period!",
-		scheduleName,
-		user.Email,
-		user.SlackHandle,
-		startTime.Format("2006-01-02 15:04:05"),
-		endTime.Format("2006-01-02 15:04:05"))
+	_, _, err := api.PostMessageContext(ctx, slackChannel, slack.MsgOptionText(message, false))
+	if err != nil {
+		log.Printf("Error sending Slack channel message: %v", err)
+		return
+	}
+	log.Printf("Slack channel message sent to %s for schedule %s", slackChannel, scheduleName)
+}
+
+func deliverSlackMessage(ctx context.Context, user *User, scheduleName, message string) {
+	slackToken := os.Getenv("SLACK_TOKEN")
+	if slackToken == "" {
+		log.Println("SLACK_TOKEN not set, skipping Slack notification")
+		return
+	}
+	
+	slackChannel := os.Getenv("SLACK_CHANNEL")
+	if slackChannel == "" {
+		slackChannel = "#oncall"
+	}
+	
+	api := slack.New(slackToken)
 	
 	// Try to send direct message to user first, fallback to channel
-	_, _, _, err := api.SendMessage(user.SlackHandle, slack.MsgOptionText(message, false))
+	_, _, _, err := api.SendMessageContext(ctx, user.SlackHandle, slack.MsgOptionText(message, false))
 	if err != nil {
 		// If direct message fails, send to channel
-		_, _, err = api.PostMessage(slackChannel, slack.MsgOptionText(message, false))
+		_, _, err = api.PostMessageContext(ctx, slackChannel, slack.MsgOptionText(message, false))
 		if err != nil {
 			log.Printf("Error sending Slack notification: %v", err)
 			return
@@ -48,4 +207,4 @@ func sendSlackNotification(user *User, scheduleName string, startTime, endTime t
 	} else {
 		log.Printf("Direct Slack notification sent to %s (%s) for schedule %s", user.Email, user.SlackHandle, scheduleName)
 	}
-}
\ No newline at end of file
+}
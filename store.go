@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// Store is the data the scheduler reads and the rotations it records.
+// Production uses Postgres; simulations swap in a memoryStore. Every call
+// gives up when its context is cancelled.
+type Store interface {
+	GetSchedules(ctx context.Context) ([]Schedule, error)
+	GetScheduleByID(ctx context.Context, scheduleID int) (*Schedule, error)
+	GetUserByID(ctx context.Context, userID int) (*User, error)
+	GetCurrentOnCallAssignments(ctx context.Context) ([]OnCallAssignment, error)
+	GetShadowShiftsForWindow(ctx context.Context, scheduleID int, startTime, endTime time.Time) ([]ShadowShift, error)
+	GetHolidaysBetween(ctx context.Context, teamID int, from, to time.Time) ([]Holiday, error)
+	GetUnavailabilityBetween(ctx context.Context, from, to time.Time) ([]Unavailability, error)
+	GetUnavailableUserIDs(ctx context.Context, startTime, endTime time.Time) (map[int]bool, error)
+	
+	// GetRecordedAssignments returns the schedule's tier 1 primary
+	// assignments overlapping [from, to), oldest first.
+	GetRecordedAssignments(ctx context.Context, scheduleID int, from, to time.Time) ([]OnCallAssignment, error)
+	
+	// ClaimCoverageGapAlert records that the gap's shift has been alerted
+	// about and reports whether it had not been already, only while
+	// fencingToken is the leader's.
+	ClaimCoverageGapAlert(ctx context.Context, gap CoverageGap, fencingToken int64) (bool, error)
+	
+	// GetRotationInputsGeneration returns a number that moves on whenever
+	// anything a rotation is replayed from changes.
+	GetRotationInputsGeneration(ctx context.Context) (int64, error)
+
+	// RecordRotation ends current, if any, at endedAt and records primary,
+	// if any, together with its shadows, all or nothing and only while
+	// fencingToken is the leader's. Recording an assignment that already
+	// exists is a no-op, and shadows are only recorded with a newly created
+	// primary. It reports whether the primary was created and returns the
+	// shadows that were.
+	RecordRotation(ctx context.Context, current *OnCallAssignment, endedAt time.Time, primary *OnCallAssignment, shadows []OnCallAssignment, fencingToken int64) (bool, []OnCallAssignment, error)
+}
+
+// store is the Store used by the scheduler, projections and coverage checks.
+var store Store = postgresStore{}
+
+// postgresStore is the Store backed by the database functions.
+type postgresStore struct{}
+
+func (postgresStore) GetSchedules(ctx context.Context) ([]Schedule, error) {
+	return getSchedules(ctx)
+}
+
+func (postgresStore) GetScheduleByID(ctx context.Context, scheduleID int) (*Schedule, error) {
+	return getScheduleByID(ctx, scheduleID)
+}
+
+func (postgresStore) GetUserByID(ctx context.Context, userID int) (*User, error) {
+	return getUserByID(ctx, userID)
+}
+
+func (postgresStore) GetCurrentOnCallAssignments(ctx context.Context) ([]OnCallAssignment, error) {
+	return getCurrentOnCallAssignments(ctx)
+}
+
+func (postgresStore) GetShadowShiftsForWindow(ctx context.Context, scheduleID int, startTime, endTime time.Time) ([]ShadowShift, error) {
+	return getShadowShiftsForWindow(ctx, scheduleID, startTime, endTime)
+}
+
+func (postgresStore) GetHolidaysBetween(ctx context.Context, teamID int, from, to time.Time) ([]Holiday, error) {
+	return getHolidaysBetween(ctx, teamID, from, to)
+}
+
+func (postgresStore) GetRecordedAssignments(ctx context.Context, scheduleID int, from, to time.Time) ([]OnCallAssignment, error) {
+	return getRecordedAssignments(ctx, scheduleID, from, to)
+}
+
+func (postgresStore) GetUnavailabilityBetween(ctx context.Context, from, to time.Time) ([]Unavailability, error) {
+	return getUnavailabilityBetween(ctx, from, to)
+}
+
+func (postgresStore) GetUnavailableUserIDs(ctx context.Context, startTime, endTime time.Time) (map[int]bool, error) {
+	return getUnavailableUserIDs(ctx, startTime, endTime)
+}
+
+func (postgresStore) ClaimCoverageGapAlert(ctx context.Context, gap CoverageGap, fencingToken int64) (bool, error) {
+	return claimCoverageGapAlert(ctx, gap, fencingToken)
+}
+
+func (postgresStore) GetRotationInputsGeneration(ctx context.Context) (int64, error) {
+	return getRotationInputsGeneration(ctx)
+}
+
+func (postgresStore) RecordRotation(ctx context.Context, current *OnCallAssignment, endedAt time.Time, primary *OnCallAssignment, shadows []OnCallAssignment, fencingToken int64) (bool, []OnCallAssignment, error) {
+	return recordRotation(ctx, current, endedAt, primary, shadows, fencingToken)
+}
@@ -0,0 +1,1109 @@
+package main
+
+import (
+	"html/template"
+	"net/http"
+)
+
+// uiHandler serves the web UI, a single page that calls the JSON API at
+// /api/v1 like any other client.
+func uiHandler(w http.ResponseWriter, r *http.Request) {
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+    <title>OnCall Scheduler</title>
+    <style>
+        * { margin: 0; padding: 0; box-sizing: border-box; }
+        body { 
+            font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif;
+            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
+            min-height: 100vh;
+            color: #333;
+        }
+        .container { max-width: 1200px; margin: 0 auto; padding: 20px; }
+        
+        .header {
+            text-align: center;
+            color: white;
+            margin-bottom: 40px;
+            padding: 40px 0;
+        }
+        .header h1 { font-size: 3rem; margin-bottom: 10px; }
+        .header p { font-size: 1.2rem; opacity: 0.9; }
+        
+        .nav-grid {
+            display: grid;
+            grid-template-columns: repeat(auto-fit, minmax(300px, 1fr));
+            gap: 20px;
+            margin-bottom: 40px;
+        }
+        
+        .nav-card {
+            background: rgba(255, 255, 255, 0.95);
+            border-radius: 12px;
+            padding: 25px;
+            text-decoration: none;
+            color: #333;
+            transition: all 0.3s ease;
+            border: 2px solid transparent;
+            backdrop-filter: blur(10px);
+        }
+        
+        .nav-card:hover {
+            transform: translateY(-5px);
+            box-shadow: 0 10px 30px rgba(0, 0, 0, 0.2);
+            border-color: #667eea;
+            color: #333;
+            text-decoration: none;
+        }
+        
+        .nav-card h3 {
+            font-size: 1.4rem;
+            margin-bottom: 10px;
+            color: #667eea;
+        }
+        
+        .nav-card p {
+            color: #666;
+            line-height: 1.5;
+        }
+        
+        .section {
+            background: rgba(255, 255, 255, 0.95);
+            border-radius: 12px;
+            padding: 30px;
+            margin-bottom: 30px;
+            backdrop-filter: blur(10px);
+            display: none;
+        }
+        
+        .section.active { display: block; }
+        
+        .form-group { margin-bottom: 20px; }
+        
+        label {
+            display: block;
+            margin-bottom: 8px;
+            font-weight: 600;
+            color: #555;
+        }
+        
+        input, textarea, select {
+            width: 100%;
+            padding: 12px 16px;
+            border: 2px solid #e1e5e9;
+            border-radius: 8px;
+            font-size: 14px;
+            transition: border-color 0.3s ease;
+            background: white;
+        }
+        
+        input:focus, textarea:focus, select:focus {
+            outline: none;
+            border-color: #667eea;
+            box-shadow: 0 0 0 3px rgba(102, 126, 234, 0.1);
+        }
+        
+        button {
+            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
+            color: white;
+            padding: 12px 24px;
+            border: none;
+            border-radius: 8px;
+            font-size: 16px;
+            font-weight: 600;
+            cursor: pointer;
+            transition: all 0.3s ease;
+        }
+        
+        button:hover {
+            transform: translateY(-2px);
+            box-shadow: 0 5px 15px rgba(102, 126, 234, 0.4);
+        }
+        
+        .back-btn {
+            background: #6c757d;
+            margin-bottom: 20px;
+            padding: 8px 16px;
+            font-size: 14px;
+        }
+        
+        .back-btn:hover {
+            background: #5a6268;
+        }
+        
+        .item-card {
+            background: #f8f9fa;
+            border-radius: 8px;
+            padding: 20px;
+            margin-bottom: 15px;
+            border-left: 4px solid #667eea;
+        }
+        
+        .item-card h4 {
+            color: #667eea;
+            margin-bottom: 10px;
+        }
+        
+        .item-card p {
+            margin-bottom: 5px;
+            color: #666;
+        }
+        
+        .emoji { font-size: 1.5rem; margin-right: 10px; }
+        
+        /* Toast notification styles */
+        .toast-container {
+            position: fixed;
+            top: 20px;
+            right: 20px;
+            z-index: 1000;
+            display: flex;
+            flex-direction: column;
+            gap: 10px;
+        }
+        
+        .toast {
+            background: rgba(255, 255, 255, 0.95);
+            border-radius: 8px;
+            padding: 16px 20px;
+            box-shadow: 0 4px 20px rgba(0, 0, 0, 0.15);
+            backdrop-filter: blur(10px);
+            border-left: 4px solid;
+            min-width: 300px;
+            transform: translateX(100%);
+            opacity: 0;
+            transition: all 0.3s ease;
+        }
+        
+        .toast.show {
+            transform: translateX(0);
+            opacity: 1;
+        }
+        
+        .toast.success {
+            border-left-color: #28a745;
+        }
+        
+        .toast.error {
+            border-left-color: #dc3545;
+        }
+        
+        .toast-header {
+            font-weight: 600;
+            margin-bottom: 4px;
+            display: flex;
+            align-items: center;
+            gap: 8px;
+        }
+        
+        .toast-message {
+            color: #666;
+            font-size: 14px;
+        }
+        
+        .toast-close {
+            position: absolute;
+            top: 8px;
+            right: 12px;
+            background: none;
+            border: none;
+            font-size: 18px;
+            cursor: pointer;
+            color: #999;
+            padding: 0;
+            width: 20px;
+            height: 20px;
+            display: flex;
+            align-items: center;
+            justify-content: center;
+        }
+        
+        .toast-close:hover {
+            color: #666;
+        }
+    </style>
+</head>
+<body>
+    <!-- Toast Container -->
+    <div class="toast-container" id="toast-container"></div>
+    
+    <div class="container">
+        <div class="header">
+            <h1>🚨 OnCall Scheduler</h1>
+            <p>Manage your team's on-call rotations with ease</p>
+        </div>
+
+        <!-- Navigation Grid -->
+        <div id="nav-section">
+            <div class="nav-grid">
+                <a href="#" class="nav-card" onclick="showSection('create-user')">
+                    <h3><span class="emoji">👤</span>Create User</h3>
+                    <p>Add new team members with email and Slack details</p>
+                </a>
+                
+                <a href="#" class="nav-card" onclick="showSection('create-team')">
+                    <h3><span class="emoji">👥</span>Create Team</h3>
+                    <p>Set up new teams for organizing your on-call rotations</p>
+                </a>
+                
+                <a href="#" class="nav-card" onclick="showSection('create-schedule')">
+                    <h3><span class="emoji">📅</span>Create Schedule</h3>
+                    <p>Design on-call schedules with rotation periods</p>
+                </a>
+                
+                <a href="#" class="nav-card" onclick="showSection('view-users')">
+                    <h3><span class="emoji">📋</span>View Users</h3>
+                    <p>Browse all registered users and their details</p>
+                </a>
+                
+                <a href="#" class="nav-card" onclick="showSection('view-teams')">
+                    <h3><span class="emoji">🏢</span>View Teams</h3>
+                    <p>See all teams and their members</p>
+                </a>
+                
+                <a href="#" class="nav-card" onclick="showSection('view-schedules')">
+                    <h3><span class="emoji">⏰</span>View Schedules</h3>
+                    <p>Monitor active schedules and rotations</p>
+                </a>
+                
+                <a href="#" class="nav-card" onclick="showSection('time-off')">
+                    <h3><span class="emoji">🌴</span>Time Off</h3>
+                    <p>Record PTO so the rotation skips unavailable people</p>
+                </a>
+                
+                <a href="#" class="nav-card" onclick="showSection('holidays')">
+                    <h3><span class="emoji">🎉</span>Holidays</h3>
+                    <p>Maintain team holiday calendars by hand or from an .ics file</p>
+                </a>
+            </div>
+        </div>
+
+        <!-- Create User Section -->
+        <div id="create-user" class="section">
+            <button class="back-btn" onclick="showNav()">← Back to Menu</button>
+            <h2>👤 Create User</h2>
+            <form id="userForm">
+                <div class="form-group">
+                    <label for="userEmail">Email Address:</label>
+                    <input type="email" id="userEmail" name="userEmail" placeholder="john.doe@company.com" required>
+                </div>
+                <div class="form-group">
+                    <label for="userSlackHandle">Slack Handle:</label>
+                    <input type="text" id="userSlackHandle" name="userSlackHandle" placeholder="@johndoe" required>
+                </div>
+                <div class="form-group">
+                    <label for="userTeamId">Team ID:</label>
+                    <input type="number" id="userTeamId" name="userTeamId" placeholder="1" required>
+                </div>
+                <button type="submit">Create User</button>
+            </form>
+        </div>
+
+        <!-- Create Team Section -->
+        <div id="create-team" class="section">
+            <button class="back-btn" onclick="showNav()">← Back to Menu</button>
+            <h2>👥 Create Team</h2>
+            <form id="teamForm">
+                <div class="form-group">
+                    <label for="teamName">Team Name:</label>
+                    <input type="text" id="teamName" name="teamName" placeholder="Backend Engineering" required>
+                </div>
+                <div class="form-group">
+                    <label for="teamSlackChannel">Slack Channel (for coverage warnings):</label>
+                    <input type="text" id="teamSlackChannel" name="teamSlackChannel" placeholder="#backend-oncall">
+                </div>
+                <button type="submit">Create Team</button>
+            </form>
+        </div>
+
+        <!-- Create Schedule Section -->
+        <div id="create-schedule" class="section">
+            <button class="back-btn" onclick="showNav()">← Back to Menu</button>
+            <h2>📅 Create Schedule</h2>
+            <form id="scheduleForm">
+                <div class="form-group">
+                    <label for="scheduleName">Schedule Name:</label>
+                    <input type="text" id="scheduleName" name="scheduleName" placeholder="Weekend On-Call Rotation" required>
+                </div>
+                <div class="form-group">
+                    <label for="teamId">Team ID:</label>
+                    <input type="number" id="teamId" name="teamId" placeholder="1" required>
+                </div>
+                <div class="form-group">
+                    <label for="startTime">Start Time:</label>
+                    <input type="datetime-local" id="startTime" name="startTime" required>
+                </div>
+                <div class="form-group">
+                    <label for="endTime">End Time:</label>
+                    <input type="datetime-local" id="endTime" name="endTime" required>
+                </div>
+                <div class="form-group">
+                    <label for="rotationPeriod">Rotation Period:</label>
+                    <div style="display: flex; gap: 10px; align-items: center;">
+                        <div style="flex: 1;">
+                            <label for="rotationDays" style="font-size: 12px; margin-bottom: 2px;">Days</label>
+                            <input type="number" id="rotationDays" name="rotationDays" min="0" max="365" placeholder="0" style="width: 100%;">
+                        </div>
+                        <div style="flex: 1;">
+                            <label for="rotationHours" style="font-size: 12px; margin-bottom: 2px;">Hours</label>
+                            <input type="number" id="rotationHours" name="rotationHours" min="0" max="23" placeholder="1" style="width: 100%;">
+                        </div>
+                        <div style="flex: 1;">
+                            <label for="rotationMinutes" style="font-size: 12px; margin-bottom: 2px;">Minutes</label>
+                            <input type="number" id="rotationMinutes" name="rotationMinutes" min="0" max="59" placeholder="0" style="width: 100%;">
+                        </div>
+                        <div style="flex: 1;">
+                            <label for="rotationSeconds" style="font-size: 12px; margin-bottom: 2px;">Seconds</label>
+                            <input type="number" id="rotationSeconds" name="rotationSeconds" min="0" max="59" placeholder="0" style="width: 100%;">
+                        </div>
+                    </div>
+                    <small style="color: #666; font-size: 12px; margin-top: 5px; display: block;">
+                        Minimum rotation period is 1 second. Common examples: 1 day = 1d 0h 0m 0s, 8 hours = 0d 8h 0m 0s
+                    </small>
+                </div>
+                <div class="form-group">
+                    <label for="participants">Participants (comma-separated user IDs):</label>
+                    <textarea id="participants" name="participants" rows="3" placeholder="1,2,3" required></textarea>
+                </div>
+                <div class="form-group">
+                    <label for="rotationStrategy">Rotation Strategy:</label>
+                    <select id="rotationStrategy" name="rotationStrategy">
+                        <option value="round_robin">Round-robin in participant order</option>
+                        <option value="fairness">Fairness: lowest weighted on-call load</option>
+                        <option value="weighted">Weighted: fairness with per-participant shares</option>
+                        <option value="random">Random without repeats</option>
+                        <option value="fixed_sequence">Fixed sequence</option>
+                    </select>
+                </div>
+                <div class="form-group">
+                    <label for="participantWeights">Participant Weights (weighted only, e.g. 1:2,3:0.5):</label>
+                    <input type="text" id="participantWeights" name="participantWeights" placeholder="1:2,3:0.5">
+                </div>
+                <div class="form-group">
+                    <label for="sequence">Sequence (fixed sequence only, comma-separated user IDs, repeats allowed):</label>
+                    <input type="text" id="sequence" name="sequence" placeholder="1,2,1,3">
+                </div>
+                <div class="form-group">
+                    <label for="holidayMode">Holiday Handling:</label>
+                    <select id="holidayMode" name="holidayMode">
+                        <option value="none">Ignore holidays</option>
+                        <option value="skip">Skip the rotation on holidays</option>
+                        <option value="rotation">Route holidays to a separate rotation</option>
+                        <option value="flag">Flag holiday shifts for compensation</option>
+                    </select>
+                </div>
+                <div class="form-group">
+                    <label for="holidayScheduleId">Holiday Rotation Schedule ID (only for separate rotation):</label>
+                    <input type="number" id="holidayScheduleId" name="holidayScheduleId" placeholder="2">
+                </div>
+                <div class="form-group">
+                    <label for="timezone">Time Zone (for shift times in notifications):</label>
+                    <input type="text" id="timezone" name="timezone" placeholder="UTC">
+                </div>
+                <button type="submit">Create Schedule</button>
+            </form>
+        </div>
+
+        <!-- View Users Section -->
+        <div id="view-users" class="section">
+            <button class="back-btn" onclick="showNav()">← Back to Menu</button>
+            <h2>📋 All Users</h2>
+            <div id="usersList"></div>
+        </div>
+
+        <!-- View Teams Section -->
+        <div id="view-teams" class="section">
+            <button class="back-btn" onclick="showNav()">← Back to Menu</button>
+            <h2>🏢 All Teams</h2>
+            <form id="teamMemberForm">
+                <div class="form-group">
+                    <label for="memberTeamId">Team ID:</label>
+                    <input type="number" id="memberTeamId" name="memberTeamId" placeholder="1" required>
+                </div>
+                <div class="form-group">
+                    <label for="memberUserId">User ID:</label>
+                    <input type="number" id="memberUserId" name="memberUserId" placeholder="1" required>
+                </div>
+                <div class="form-group">
+                    <label for="memberRole">Role:</label>
+                    <select id="memberRole" name="memberRole">
+                        <option value="member">Member</option>
+                        <option value="manager">Manager</option>
+                    </select>
+                </div>
+                <button type="submit">Add Member</button>
+            </form>
+            <div id="teamsList" style="margin-top: 30px;"></div>
+        </div>
+
+        <!-- View Schedules Section -->
+        <div id="view-schedules" class="section">
+            <button class="back-btn" onclick="showNav()">← Back to Menu</button>
+            <h2>⏰ All Schedules</h2>
+            <label><input type="checkbox" id="showArchived" onchange="loadSchedules()"> Show archived schedules</label>
+            <div id="schedulesList"></div>
+        </div>
+
+        <!-- Time Off Section -->
+        <div id="time-off" class="section">
+            <button class="back-btn" onclick="showNav()">← Back to Menu</button>
+            <h2>🌴 Time Off</h2>
+            <form id="unavailabilityForm">
+                <div class="form-group">
+                    <label for="unavailableUserId">User ID:</label>
+                    <input type="number" id="unavailableUserId" name="unavailableUserId" placeholder="1" required>
+                </div>
+                <div class="form-group">
+                    <label for="unavailableStart">Unavailable From:</label>
+                    <input type="datetime-local" id="unavailableStart" name="unavailableStart" required>
+                </div>
+                <div class="form-group">
+                    <label for="unavailableEnd">Unavailable Until:</label>
+                    <input type="datetime-local" id="unavailableEnd" name="unavailableEnd" required>
+                </div>
+                <div class="form-group">
+                    <label for="unavailableReason">Reason:</label>
+                    <input type="text" id="unavailableReason" name="unavailableReason" placeholder="Vacation">
+                </div>
+                <button type="submit">Add Time Off</button>
+            </form>
+            <div id="unavailabilityList" style="margin-top: 30px;"></div>
+        </div>
+
+        <!-- Holidays Section -->
+        <div id="holidays" class="section">
+            <button class="back-btn" onclick="showNav()">← Back to Menu</button>
+            <h2>🎉 Holidays</h2>
+            <form id="holidayForm">
+                <div class="form-group">
+                    <label for="holidayTeamId">Team ID:</label>
+                    <input type="number" id="holidayTeamId" name="holidayTeamId" placeholder="1" required onchange="loadHolidays()">
+                </div>
+                <div class="form-group">
+                    <label for="holidayDate">Date:</label>
+                    <input type="date" id="holidayDate" name="holidayDate">
+                </div>
+                <div class="form-group">
+                    <label for="holidayName">Name:</label>
+                    <input type="text" id="holidayName" name="holidayName" placeholder="New Year's Day">
+                </div>
+                <div class="form-group">
+                    <label for="holidayFile">Or import an .ics file:</label>
+                    <input type="file" id="holidayFile" name="holidayFile" accept=".ics,text/calendar">
+                </div>
+                <button type="submit">Save Holidays</button>
+            </form>
+            <div id="holidaysList" style="margin-top: 30px;"></div>
+        </div>
+    </div>
+
+    <script>
+        // Toast notification system
+        function showToast(type, title, message, duration = 4000) {
+            const container = document.getElementById('toast-container');
+            const toast = document.createElement('div');
+            toast.className = 'toast ' + type;
+            
+            toast.innerHTML = 
+                '<button class="toast-close" onclick="removeToast(this.parentElement)">&times;</button>' +
+                '<div class="toast-header">' +
+                    (type === 'success' ? '✅' : '❌') + ' ' + title +
+                '</div>' +
+                '<div class="toast-message">' + message + '</div>';
+            
+            container.appendChild(toast);
+            
+            // Trigger animation
+            setTimeout(function() { toast.classList.add('show'); }, 100);
+            
+            // Auto remove
+            setTimeout(function() { removeToast(toast); }, duration);
+        }
+        
+        function removeToast(toast) {
+            toast.classList.remove('show');
+            setTimeout(function() {
+                if (toast.parentElement) {
+                    toast.parentElement.removeChild(toast);
+                }
+            }, 300);
+        }
+        
+        // Utility function to format duration from seconds
+        function formatDuration(totalSeconds) {
+            const days = Math.floor(totalSeconds / (24 * 60 * 60));
+            const hours = Math.floor((totalSeconds % (24 * 60 * 60)) / (60 * 60));
+            const minutes = Math.floor((totalSeconds % (60 * 60)) / 60);
+            const seconds = totalSeconds % 60;
+            
+            const parts = [];
+            if (days > 0) parts.push(days + 'd');
+            if (hours > 0) parts.push(hours + 'h');
+            if (minutes > 0) parts.push(minutes + 'm');
+            if (seconds > 0 || parts.length === 0) parts.push(seconds + 's');
+            
+            return parts.join(' ');
+        }
+        
+        // Navigation functions
+        function showSection(sectionId) {
+            // Hide navigation
+            document.getElementById('nav-section').style.display = 'none';
+            
+            // Hide all sections
+            const sections = document.querySelectorAll('.section');
+            sections.forEach(section => section.classList.remove('active'));
+            
+            // Show selected section
+            const targetSection = document.getElementById(sectionId);
+            if (targetSection) {
+                targetSection.classList.add('active');
+                
+                // Load data for view sections
+                if (sectionId === 'view-users') loadUsers();
+                if (sectionId === 'view-teams') loadTeams();
+                if (sectionId === 'view-schedules') loadSchedules();
+                if (sectionId === 'time-off') loadUnavailability();
+                if (sectionId === 'holidays') loadHolidays();
+            }
+        }
+        
+        function showNav() {
+            // Show navigation
+            document.getElementById('nav-section').style.display = 'block';
+            
+            // Hide all sections
+            const sections = document.querySelectorAll('.section');
+            sections.forEach(section => section.classList.remove('active'));
+        }
+        
+        // The UI is a client of the JSON API like any other
+        const API = '/api/v1';
+        
+        // Returns the body of a successful response, and otherwise throws the
+        // API error's message, listing the problem with each field
+        function readResponse(response) {
+            if (response.ok) {
+                return response.json();
+            }
+            return response.json()
+                .catch(() => ({message: 'HTTP ' + response.status + ': ' + response.statusText}))
+                .then(error => {
+                    const fields = (error.fields || []).map(field => field.field + ': ' + field.message);
+                    throw new Error(fields.length > 0 ? fields.join('; ') : error.message);
+                });
+        }
+        
+        // Form handlers
+        document.getElementById('userForm').addEventListener('submit', function(e) {
+            e.preventDefault();
+            const formData = new FormData(this);
+            
+            fetch(API + '/users', {
+                method: 'POST',
+                headers: {'Content-Type': 'application/json'},
+                body: JSON.stringify({
+                    email: formData.get('userEmail'),
+                    slack_handle: formData.get('userSlackHandle'),
+                    team_id: parseInt(formData.get('userTeamId'))
+                })
+            })
+            .then(readResponse)
+            .then(data => {
+                showToast('success', 'User Created', 'User has been successfully added to the system!');
+                this.reset();
+            })
+            .catch(error => {
+                console.error('Error:', error);
+                showToast('error', 'Creation Failed', error.message);
+            });
+        });
+
+        document.getElementById('teamForm').addEventListener('submit', function(e) {
+            e.preventDefault();
+            const formData = new FormData(this);
+            
+            fetch(API + '/teams', {
+                method: 'POST',
+                headers: {'Content-Type': 'application/json'},
+                body: JSON.stringify({
+                    name: formData.get('teamName'),
+                    slack_channel: formData.get('teamSlackChannel')
+                })
+            })
+            .then(readResponse)
+            .then(data => {
+                showToast('success', 'Team Created', 'Team has been successfully created!');
+                this.reset();
+            })
+            .catch(error => {
+                console.error('Error:', error);
+                showToast('error', 'Creation Failed', error.message);
+            });
+        });
+
+        document.getElementById('scheduleForm').addEventListener('submit', function(e) {
+            e.preventDefault();
+            const formData = new FormData(this);
+            const participants = formData.get('participants').split(',').map(p => parseInt(p.trim()));
+            const sequence = (formData.get('sequence') || '').split(',').filter(p => p.trim() !== '').map(p => parseInt(p.trim()));
+            const participantWeights = {};
+            (formData.get('participantWeights') || '').split(',').filter(p => p.includes(':')).forEach(p => {
+                const parts = p.split(':');
+                participantWeights[parts[0].trim()] = parseFloat(parts[1]);
+            });
+            
+            // Calculate total rotation period in seconds
+            const days = parseInt(formData.get('rotationDays') || 0);
+            const hours = parseInt(formData.get('rotationHours') || 0);
+            const minutes = parseInt(formData.get('rotationMinutes') || 0);
+            const seconds = parseInt(formData.get('rotationSeconds') || 0);
+            
+            const totalSeconds = (days * 24 * 60 * 60) + (hours * 60 * 60) + (minutes * 60) + seconds;
+            
+            if (totalSeconds < 1) {
+                showToast('error', 'Invalid Rotation Period', 'Rotation period must be at least 1 second.');
+                return;
+            }
+            
+            fetch(API + '/schedules', {
+                method: 'POST',
+                headers: {'Content-Type': 'application/json'},
+                body: JSON.stringify({
+                    team_id: parseInt(formData.get('teamId')),
+                    name: formData.get('scheduleName'),
+                    start_time: formData.get('startTime'),
+                    end_time: formData.get('endTime'),
+                    rotation_period: totalSeconds,
+                    participants: participants,
+                    rotation_strategy: formData.get('rotationStrategy'),
+                    participant_weights: participantWeights,
+                    sequence: sequence,
+                    holiday_mode: formData.get('holidayMode'),
+                    holiday_schedule_id: parseInt(formData.get('holidayScheduleId') || 0),
+                    timezone: formData.get('timezone')
+                })
+            })
+            .then(readResponse)
+            .then(data => {
+                showToast('success', 'Schedule Created', 'On-call schedule has been successfully created!');
+                this.reset();
+            })
+            .catch(error => {
+                console.error('Error:', error);
+                showToast('error', 'Creation Failed', error.message);
+            });
+        });
+
+        // Lists show a page at a time; cursor is set when loading a later page
+        function showPage(list, html, cursor, next, loadMore) {
+            const previous = list.querySelector('.load-more');
+            if (previous) {
+                previous.remove();
+            }
+            if (cursor) {
+                list.insertAdjacentHTML('beforeend', html);
+            } else {
+                list.innerHTML = html;
+            }
+            if (next) {
+                const button = document.createElement('button');
+                button.className = 'load-more';
+                button.textContent = 'Load more';
+                button.onclick = () => loadMore(next);
+                list.appendChild(button);
+            }
+        }
+
+        function fetchPage(url, cursor) {
+            if (cursor) {
+                url += (url.includes('?') ? '&' : '?') + 'cursor=' + encodeURIComponent(cursor);
+            }
+            return fetch(API + url).then(response => readResponse(response)
+                .then(items => ({items: items, next: response.headers.get('X-Next-Cursor')})));
+        }
+
+        function loadUsers(cursor) {
+            fetchPage('/users', cursor)
+                .then(page => {
+                    const users = page.items;
+                    const usersList = document.getElementById('usersList');
+                    if (!cursor && users.length === 0) {
+                        usersList.innerHTML = '<p>No users found. <a href="#" onclick="showSection(\'create-user\')">Create your first user</a></p>';
+                    } else {
+                        showPage(usersList, users.map(user => 
+                            '<div class="item-card">' +
+                            '<h4>👤 ' + user.email + ' (ID: ' + user.id + ')</h4>' +
+                            '<p><strong>Slack:</strong> ' + user.slack_handle + '</p>' +
+                            '<p><strong>Team IDs:</strong> ' + (user.team_ids && user.team_ids.length > 0 ? user.team_ids.join(', ') : 'None') + '</p>' +
+                            '<p><strong>Created:</strong> ' + new Date(user.created_at).toLocaleString() + '</p>' +
+                            '<button onclick="editUser(' + user.id + ')">Edit</button> ' +
+                            '<button onclick="deleteResource(\'/users/' + user.id + '\', loadUsers)">Delete</button>' +
+                            '</div>'
+                        ).join(''), cursor, page.next, loadUsers);
+                    }
+                });
+        }
+
+        function loadTeams(cursor) {
+            fetchPage('/teams', cursor)
+                .then(page => {
+                    const teams = page.items;
+                    console.log('Teams loaded:', teams);
+                    const teamsList = document.getElementById('teamsList');
+                    if (!cursor && teams.length === 0) {
+                        teamsList.innerHTML = '<p>No teams found. <a href="#" onclick="showSection(\'create-team\')">Create your first team</a></p>';
+                    } else {
+                        showPage(teamsList, teams.map(team => 
+                            '<div class="item-card">' +
+                            '<h4>👥 ' + team.name + ' (ID: ' + team.id + ')</h4>' +
+                            '<p><strong>Slack Channel:</strong> ' + (team.slack_channel || 'Default') + '</p>' +
+                            '<p><strong>Members:</strong> ' + (team.members && team.members.length > 0 ? team.members.map(m => 
+                                m.email + ' (' + m.slack_handle + (m.role === 'manager' ? ', manager' : '') + ') ' +
+                                '<button onclick="removeTeamMember(' + team.id + ', ' + m.id + ')">Remove</button>').join(', ') : 'No members yet') + '</p>' +
+                            '<p><strong>Created:</strong> ' + new Date(team.created_at).toLocaleString() + '</p>' +
+                            '<button onclick="editTeam(' + team.id + ')">Edit</button> ' +
+                            '<button onclick="deleteResource(\'/teams/' + team.id + '\', loadTeams)">Delete</button>' +
+                            '</div>'
+                        ).join(''), cursor, page.next, loadTeams);
+                    }
+                })
+                .catch(error => {
+                    console.error('Error loading teams:', error);
+                    const teamsList = document.getElementById('teamsList');
+                    teamsList.innerHTML = '<p>Error loading teams: ' + error.message + '</p>';
+                });
+        }
+
+        document.getElementById('teamMemberForm').addEventListener('submit', function(e) {
+            e.preventDefault();
+            const formData = new FormData(this);
+            
+            fetch(API + '/teams/' + parseInt(formData.get('memberTeamId')) + '/members', {
+                method: 'POST',
+                headers: {'Content-Type': 'application/json'},
+                body: JSON.stringify({
+                    user_id: parseInt(formData.get('memberUserId')),
+                    role: formData.get('memberRole')
+                })
+            })
+            .then(readResponse)
+            .then(data => {
+                showToast('success', 'Member Saved', data.message);
+                this.reset();
+                loadTeams();
+            })
+            .catch(error => {
+                console.error('Error:', error);
+                showToast('error', 'Update Failed', error.message);
+            });
+        });
+
+        function removeTeamMember(teamId, userId) {
+            fetch(API + '/teams/' + teamId + '/members/' + userId, { method: 'DELETE' })
+                .then(readResponse)
+                .then(data => {
+                    showToast('success', 'Member Removed', data.message);
+                    loadTeams();
+                })
+                .catch(error => {
+                    console.error('Error:', error);
+                    showToast('error', 'Removal Failed', error.message);
+                });
+        }
+
+        function editUser(id) {
+            fetch(API + '/users/' + id)
+                .then(readResponse)
+                .then(user => {
+                    const email = prompt('Email address:', user.email);
+                    if (email === null) {
+                        return;
+                    }
+                    const slackHandle = prompt('Slack handle:', user.slack_handle);
+                    if (slackHandle === null) {
+                        return;
+                    }
+                    saveResource('/users/' + id, user.revision, {email: email, slack_handle: slackHandle}, loadUsers);
+                });
+        }
+
+        function editTeam(id) {
+            fetch(API + '/teams/' + id)
+                .then(readResponse)
+                .then(team => {
+                    const name = prompt('Team name:', team.name);
+                    if (name === null) {
+                        return;
+                    }
+                    const slackChannel = prompt('Slack channel (leave empty for the default):', team.slack_channel);
+                    if (slackChannel === null) {
+                        return;
+                    }
+                    saveResource('/teams/' + id, team.revision, {name: name, slack_channel: slackChannel}, loadTeams);
+                });
+        }
+
+        // Saves an edit of the revision that was read, so it fails instead of
+        // overwriting a change someone made in the meantime
+        function saveResource(path, revision, body, reload) {
+            fetch(API + path, {
+                method: 'PATCH',
+                headers: {'Content-Type': 'application/json', 'If-Match': '"' + revision + '"'},
+                body: JSON.stringify(body)
+            })
+            .then(readResponse)
+            .then(data => {
+                showToast('success', 'Saved', 'Your changes have been saved.');
+                reload();
+            })
+            .catch(error => {
+                console.error('Error:', error);
+                showToast('error', 'Update Failed', error.message);
+            });
+        }
+
+        function deleteResource(path, reload) {
+            if (!confirm('Delete this? It can be restored through the API until it is purged.')) {
+                return;
+            }
+            fetch(API + path, { method: 'DELETE' })
+                .then(readResponse)
+                .then(data => {
+                    showToast('success', 'Deleted', data.message);
+                    reload();
+                })
+                .catch(error => {
+                    console.error('Error:', error);
+                    showToast('error', 'Deletion Failed', error.message);
+                });
+        }
+
+        function loadSchedules(cursor) {
+            const includeArchived = document.getElementById('showArchived').checked;
+            fetchPage('/schedules' + (includeArchived ? '?include_archived=true' : ''), cursor)
+                .then(page => {
+                    const schedules = page.items;
+                    const schedulesList = document.getElementById('schedulesList');
+                    if (!cursor && schedules.length === 0) {
+                        schedulesList.innerHTML = '<p>No schedules found. <a href="#" onclick="showSection(\'create-schedule\')">Create your first schedule</a></p>';
+                    } else {
+                        showPage(schedulesList, schedules.map(schedule => 
+                            '<div class="item-card">' +
+                            '<h4>📅 ' + schedule.name + ' (Team ID: ' + schedule.team_id + ')</h4>' +
+                            '<p><strong>Start:</strong> ' + new Date(schedule.start_time).toLocaleString() + '</p>' +
+                            '<p><strong>End:</strong> ' + new Date(schedule.end_time).toLocaleString() + '</p>' +
+                            '<p><strong>Rotation:</strong> ' + formatDuration(schedule.rotation_period) + '</p>' +
+                            '<p><strong>Participants (User IDs, in order):</strong> ' + formatRoster(schedule.roster) + '</p>' +
+                            '<p><strong>Strategy:</strong> ' + (schedule.rotation_strategy || 'round_robin') + '</p>' +
+                            '<p><strong>Version:</strong> ' + schedule.version + ' (' + schedule.timezone + ')</p>' +
+                            '<p><strong>Holidays:</strong> ' + (schedule.holiday_mode || 'none') + (schedule.holiday_schedule_id ? ' (Schedule ID: ' + schedule.holiday_schedule_id + ')' : '') + '</p>' +
+                            '<p><strong>Status:</strong> ' + schedule.status + (schedule.fallback_user_id ? ' (Fallback User ID: ' + schedule.fallback_user_id + ')' : '') + '</p>' +
+                            scheduleStatusButtons(schedule) +
+                            ' <button onclick="deleteResource(\'/schedules/' + schedule.id + '\', loadSchedules)">Delete</button>' +
+                            '</div>'
+                        ).join(''), cursor, page.next, loadSchedules);
+                    }
+                });
+        }
+
+        function formatRoster(roster) {
+            if (!roster || roster.length === 0) {
+                return 'None';
+            }
+            return roster.map(p => {
+                const details = [];
+                if (p.tier > 1) details.push('tier ' + p.tier);
+                if (p.weight !== 1) details.push('weight ' + p.weight);
+                if (!p.active) details.push('inactive');
+                return p.user_id + (details.length > 0 ? ' (' + details.join(', ') + ')' : '');
+            }).join(', ');
+        }
+
+        function scheduleStatusButtons(schedule) {
+            if (schedule.status === 'archived') {
+                return '';
+            }
+            let buttons = '';
+            const args = schedule.id + ', ' + schedule.revision;
+            if (schedule.status === 'paused') {
+                buttons += '<button onclick="changeScheduleStatus(' + args + ', \'resume\')">Resume</button> ';
+            } else {
+                buttons += '<button onclick="changeScheduleStatus(' + args + ', \'pause\')">Pause</button> ';
+            }
+            return buttons + '<button onclick="changeScheduleStatus(' + args + ', \'archive\')">Archive</button>';
+        }
+
+        function changeScheduleStatus(id, revision, action) {
+            let body = {};
+            if (action === 'pause') {
+                const fallback = prompt('Fallback user ID to cover while paused (leave empty for none):');
+                if (fallback === null) {
+                    return;
+                }
+                body.fallback_user_id = parseInt(fallback || 0);
+            }
+            if (action === 'archive' && !confirm('Archive this schedule? It will stop rotating and be hidden.')) {
+                return;
+            }
+            
+            fetch(API + '/schedules/' + id + '/' + action, {
+                method: 'POST',
+                headers: {'Content-Type': 'application/json', 'If-Match': '"' + revision + '"'},
+                body: JSON.stringify(body)
+            })
+            .then(readResponse)
+            .then(data => {
+                showToast('success', 'Schedule Updated', data.message);
+                loadSchedules();
+            })
+            .catch(error => {
+                console.error('Error:', error);
+                showToast('error', 'Update Failed', error.message);
+            });
+        }
+
+        document.getElementById('unavailabilityForm').addEventListener('submit', function(e) {
+            e.preventDefault();
+            const formData = new FormData(this);
+            
+            fetch(API + '/unavailability', {
+                method: 'POST',
+                headers: {'Content-Type': 'application/json'},
+                body: JSON.stringify({
+                    user_id: parseInt(formData.get('unavailableUserId')),
+                    start_time: formData.get('unavailableStart'),
+                    end_time: formData.get('unavailableEnd'),
+                    reason: formData.get('unavailableReason')
+                })
+            })
+            .then(readResponse)
+            .then(data => {
+                showToast('success', 'Time Off Added', 'The rotation will skip this user while they are away.');
+                this.reset();
+                loadUnavailability();
+            })
+            .catch(error => {
+                console.error('Error:', error);
+                showToast('error', 'Creation Failed', error.message);
+            });
+        });
+
+        function loadUnavailability() {
+            fetch(API + '/unavailability')
+                .then(readResponse)
+                .then(entries => {
+                    const list = document.getElementById('unavailabilityList');
+                    if (!entries || entries.length === 0) {
+                        list.innerHTML = '<p>No time off recorded.</p>';
+                    } else {
+                        list.innerHTML = entries.map(entry => 
+                            '<div class="item-card">' +
+                            '<h4>🌴 User ID: ' + entry.user_id + (entry.reason ? ' - ' + entry.reason : '') + '</h4>' +
+                            '<p><strong>From:</strong> ' + new Date(entry.start_time).toLocaleString() + '</p>' +
+                            '<p><strong>Until:</strong> ' + new Date(entry.end_time).toLocaleString() + '</p>' +
+                            '<button onclick="deleteUnavailability(' + entry.id + ')">Remove</button>' +
+                            '</div>'
+                        ).join('');
+                    }
+                });
+        }
+
+        function deleteUnavailability(id) {
+            fetch(API + '/unavailability/' + id, { method: 'DELETE' })
+                .then(readResponse)
+                .then(data => {
+                    showToast('success', 'Time Off Removed', 'The user is back in the rotation for that period.');
+                    loadUnavailability();
+                })
+                .catch(error => {
+                    console.error('Error:', error);
+                    showToast('error', 'Removal Failed', error.message);
+                });
+        }
+
+        document.getElementById('holidayForm').addEventListener('submit', function(e) {
+            e.preventDefault();
+            const formData = new FormData(this);
+            const teamId = parseInt(formData.get('holidayTeamId'));
+            const file = formData.get('holidayFile');
+            
+            let request;
+            if (file && file.size > 0) {
+                request = fetch(API + '/teams/' + teamId + '/holidays/import', {
+                    method: 'POST',
+                    headers: {'Content-Type': 'text/calendar'},
+                    body: file
+                });
+            } else {
+                request = fetch(API + '/teams/' + teamId + '/holidays', {
+                    method: 'POST',
+                    headers: {'Content-Type': 'application/json'},
+                    body: JSON.stringify({
+                        date: formData.get('holidayDate'),
+                        name: formData.get('holidayName')
+                    })
+                });
+            }
+            
+            request
+            .then(readResponse)
+            .then(data => {
+                showToast('success', 'Holidays Saved', data.imported !== undefined ? data.imported + ' holidays imported.' : 'Holiday has been added to the calendar.');
+                loadHolidays();
+            })
+            .catch(error => {
+                console.error('Error:', error);
+                showToast('error', 'Save Failed', error.message);
+            });
+        });
+
+        function loadHolidays() {
+            const teamId = parseInt(document.getElementById('holidayTeamId').value);
+            const list = document.getElementById('holidaysList');
+            if (!teamId) {
+                list.innerHTML = '<p>Enter a team ID to see its holidays.</p>';
+                return;
+            }
+            fetch(API + '/teams/' + teamId + '/holidays')
+                .then(readResponse)
+                .then(holidays => {
+                    if (!holidays || holidays.length === 0) {
+                        list.innerHTML = '<p>No holidays found for this team.</p>';
+                    } else {
+                        list.innerHTML = holidays.map(holiday => 
+                            '<div class="item-card">' +
+                            '<h4>🎉 ' + holiday.name + '</h4>' +
+                            '<p><strong>Date:</strong> ' + holiday.date.substring(0, 10) + '</p>' +
+                            '<button onclick="deleteHoliday(' + teamId + ', ' + holiday.id + ')">Remove</button>' +
+                            '</div>'
+                        ).join('');
+                    }
+                });
+        }
+
+        function deleteHoliday(teamId, id) {
+            fetch(API + '/teams/' + teamId + '/holidays/' + id, { method: 'DELETE' })
+                .then(readResponse)
+                .then(data => {
+                    showToast('success', 'Holiday Removed', 'The holiday has been removed from the calendar.');
+                    loadHolidays();
+                })
+                .catch(error => {
+                    console.error('Error:', error);
+                    showToast('error', 'Removal Failed', error.message);
+                });
+        }
+
+        // Initialize the page
+        showNav();
+    </script>
+</body>
+</html>
+	`
+
+	t := template.Must(template.New("ui").Parse(tmpl))
+	t.Execute(w, nil)
+}
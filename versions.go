@@ -0,0 +1,67 @@
+package main
+
+import "time"
+
+// versionAt returns the schedule with the definition in effect at t: the
+// latest version that took effect at or before t, or the first version for
+// instants before the schedule started.
+func versionAt(schedule Schedule, t time.Time) Schedule {
+	if len(schedule.Versions) == 0 {
+		if schedule.EffectiveFrom.IsZero() {
+			schedule.EffectiveFrom = schedule.StartTime
+		}
+		return schedule
+	}
+	
+	version := schedule.Versions[0]
+	for _, candidate := range schedule.Versions[1:] {
+		if candidate.EffectiveFrom.After(t) {
+			break
+		}
+		version = candidate
+	}
+	
+	schedule.ScheduleDefinition = version.ScheduleDefinition
+	schedule.Version = version.Version
+	schedule.EffectiveFrom = version.EffectiveFrom
+	return schedule
+}
+
+// latestVersion returns the schedule's newest version, which may not have
+// taken effect yet.
+func latestVersion(schedule Schedule) (ScheduleVersion, bool) {
+	if len(schedule.Versions) == 0 {
+		return ScheduleVersion{}, false
+	}
+	return schedule.Versions[len(schedule.Versions)-1], true
+}
+
+// shiftAt returns the start and end of the schedule's shift in progress at
+// t, along with the schedule as defined for that shift. Each version's shifts
+// follow its rotation period from the instant it took effect, and the last
+// shift before a new version takes effect is cut short.
+func shiftAt(schedule Schedule, t time.Time) (time.Time, time.Time, Schedule) {
+	definition := versionAt(schedule, t)
+	start := calculateRotationStart(definition.EffectiveFrom, definition.RotationPeriod, t)
+	end := start.Add(time.Duration(definition.RotationPeriod) * time.Second)
+	
+	for _, version := range schedule.Versions {
+		if version.EffectiveFrom.After(start) {
+			if version.EffectiveFrom.Before(end) {
+				end = version.EffectiveFrom
+			}
+			break
+		}
+	}
+	return start, end, definition
+}
+
+// scheduleLocation returns the time zone the definition's shift times are
+// shown in, falling back to UTC.
+func scheduleLocation(definition ScheduleDefinition) *time.Location {
+	location, err := time.LoadLocation(definition.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}